@@ -16,13 +16,21 @@ package redisadapter
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"crypto/tls"
-	"encoding/json"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
@@ -30,6 +38,13 @@ import (
 )
 
 // CasbinRule is used to determine which policy line to load.
+//
+// Trailing empty fields of a rule are not significant: []string{"alice", ""},
+// []string{"alice"} and []string{"alice", "", ""} are stored as the same entry,
+// so adding any of them and removing another one is symmetric. Rules are loaded
+// with as many fields as the tokens of their ptype in the model, e.g.
+// []string{"bob", "data2", ""} for "sub, obj, act", and empty fields followed by
+// a non-empty one are kept, e.g. []string{"alice", "", "read"} is loaded as is.
 type CasbinRule struct {
 	PType string
 	V0    string
@@ -46,54 +61,318 @@ type Config struct {
 	Network string
 	// Address is the Redis server address, e.g., "127.0.0.1:6379"
 	Address string
+	// DB is the number of the database to select (default: 0)
+	DB int
 	// Key is the Redis key to store Casbin rules (default: "casbin_rules")
 	Key string
+	// KeyPrefix is prepended to Key and every auxiliary key, e.g. "prod:" (optional)
+	KeyPrefix string
+	// Keys are further keys whose rules are loaded along with Key, which alone is written (optional)
+	Keys []string
+	// LoadConcurrency is the maximum number of Keys loaded at once (default: 1)
+	LoadConcurrency int
 	// Username for Redis authentication (optional)
 	Username string
 	// Password for Redis authentication (optional)
 	Password string
+	// CredentialsProvider returns the username and password whenever a connection is dialed (optional)
+	CredentialsProvider func() (username, password string, err error)
 	// TLSConfig for secure connections (optional)
 	TLSConfig *tls.Config
-	// Pool is an existing Redis connection pool (optional)
-	// If provided, Network, Address, Username, Password, and TLSConfig are ignored
+	// TLSCertFile is the PEM file of a client certificate, read at every dial (optional)
+	TLSCertFile string
+	// TLSKeyFile is the PEM file of the key of TLSCertFile (optional)
+	TLSKeyFile string
+	// TLSCAFile is the PEM file of the certificates authenticating the server (optional)
+	TLSCAFile string
+	// TLSServerName is the name the server certificate is verified against (default: the host of Address)
+	TLSServerName string
+	// TLSInsecureSkipVerify skips verifying the server certificate, for development only (optional)
+	TLSInsecureSkipVerify bool
+	// ClientName is set with CLIENT SETNAME on every connection (optional)
+	ClientName string
+	// StrictClientName fails the dial when the server refuses ClientName (optional)
+	StrictClientName bool
+	// SentinelAddrs are the Redis Sentinels resolving the master instead of Address (optional)
+	SentinelAddrs []string
+	// SentinelMasterName is the name of the master known to SentinelAddrs
+	SentinelMasterName string
+	// SentinelPassword authenticates with SentinelAddrs (optional)
+	SentinelPassword string
+	// DialFunc dials the connections of the adapter in place of the dial options (optional)
+	DialFunc func() (redis.Conn, error)
+	// NetDialer opens the raw network connections in place of net.Dialer, e.g. through a tunnel (optional)
+	NetDialer func(network, addr string) (net.Conn, error)
+	// Addresses are dialed in turn instead of Address until one is reachable (optional)
+	Addresses []string
+	// Pool is an existing Redis connection pool, which the caller keeps owning (optional)
+	// If provided, the options dialing the server, e.g. Network, Address, Username, Password and TLSConfig, are rejected
 	Pool *redis.Pool
+	// ClosePoolOnShutdown makes Close and the finalizer close Pool as well (optional)
+	ClosePoolOnShutdown bool
+	// MaxIdle is the maximum number of idle connections of the pool (default: 10)
+	MaxIdle int
+	// MaxActive is the maximum number of connections of the pool (default: 0, no limit)
+	MaxActive int
+	// IdleTimeout closes the connections idle for this long (default: 0, no limit)
+	IdleTimeout time.Duration
+	// PoolWait makes operations wait for a free connection instead of failing (optional)
+	PoolWait bool
+	// OperationTimeout bounds each operation, failing it with ErrOperationTimeout (optional)
+	OperationTimeout time.Duration
+	// PoolWaitTimeout bounds how long operations wait for a free connection (optional)
+	PoolWaitTimeout time.Duration
+	// ReadPool is a pool of connections to a replica that loads read from (optional)
+	ReadPool *redis.Pool
+	// RestURL is the URL of the REST API of an Upstash or compatible Redis, used instead of RESP (optional)
+	RestURL string
+	// RestToken is the bearer token of the REST API
+	RestToken string
+	// HTTPClient sends the requests of RestURL (default: a client with a 30s timeout)
+	HTTPClient *http.Client
+	// ConnectTimeout bounds dialing the server (default: 0, no limit)
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds waiting for a reply (default: 0, no limit)
+	ReadTimeout time.Duration
+	// WriteTimeout bounds sending a command (default: 0, no limit)
+	WriteTimeout time.Duration
+	// KeepAlive is the TCP keep-alive period, and pings connections idle for longer (default: the redigo period)
+	KeepAlive time.Duration
+	// MaxConnLifetime closes the connections older than this duration (default: 0, no limit)
+	MaxConnLifetime time.Duration
+	// FilterRegexLimit is the maximum number of values per field RawPatternMatching compiles (default: 64)
+	FilterRegexLimit int
+	// FilterAllSections makes a Filter without PType match every section, not only "p" (optional)
+	FilterAllSections bool
+	// SoftDelete keeps removed rules marked as deleted until PurgeDeleted (optional)
+	SoftDelete bool
+	// WriteRateLimit is the maximum rate of mutating operations per second (optional)
+	WriteRateLimit float64
+	// WriteRateBurst is the number of mutating operations allowed at once (default: 1)
+	WriteRateBurst int
+	// WriteRateLimitNoWait fails rate-limited operations with ErrWriteRateLimited instead of waiting
+	WriteRateLimitNoWait bool
+	// WriteLimiter limits mutating operations in place of WriteRateLimit, e.g. a *rate.Limiter (optional)
+	WriteLimiter RateLimiter
+	// Encoding is the serialization of stored rules (default: JSONEncoding)
+	Encoding Encoding
+	// Compression compresses the stored rules, e.g. GzipCompression for long object paths (default: NoCompression)
+	Compression Compression
+	// EncryptionKey encrypts the stored rules with AES-GCM, see MigrateToEncrypted (optional)
+	EncryptionKey []byte
+	// JSONKeys are the keys of the fields of JSON-encoded rules (default: DefaultJSONKeys)
+	JSONKeys JSONKeys
+	// Codec serializes the stored rules in place of Encoding and JSONKeys (optional)
+	Codec Codec
+	// SaveBatchSize is the number of rules SavePolicy sends at a time (default: 1000)
+	SaveBatchSize int
+	// UpdateBatchSize is the number of rules UpdatePolicies replaces per script (default: 1000)
+	UpdateBatchSize int
+	// SingleScanRemoval makes RemovePolicies remove all the rules in one scan of the list (optional)
+	SingleScanRemoval bool
+	// Layout is how rules are laid out in Redis keys (default: ListLayout)
+	Layout Layout
+	// SplitSections stores the rules of each ptype in a list of its own, see MigrateToSplitSections (optional)
+	SplitSections bool
+	// CompatOfficialAdapter keeps the policy readable and writable by the official redis-adapter (optional)
+	CompatOfficialAdapter bool
+	// Backend stores the rules in place of the StorageBackend of Layout (optional)
+	Backend StorageBackend
+	// StreamCompactThreshold is the number of events StreamLayout keeps before compacting (default: 1000)
+	StreamCompactThreshold int
+	// SnapshotPath is a file LoadPolicy falls back to when Redis cannot be read (optional)
+	SnapshotPath string
+	// LoadErrorPosition makes LoadPolicy return a *LoadError for undecodable rules (optional)
+	LoadErrorPosition bool
+	// NegativeCacheTTL is how long LoadFilteredPolicy remembers filters that loaded no rule (optional)
+	NegativeCacheTTL time.Duration
+	// FieldNames names the fields of the rules of each ptype, for FilterByName (optional)
+	FieldNames map[string][]string
+	// BaseAdapter holds base rules that LoadPolicy merges with the rules in Redis (optional)
+	BaseAdapter persist.Adapter
+	// AuditStream is a stream recording every committed change, see ReadAudit (optional)
+	AuditStream string
+	// CloseTimeout is how long Close waits for operations in flight (default: 10s)
+	CloseTimeout time.Duration
+	// RepairVersionOnStart makes NewAdapter bump the version if the policy changed without it (optional)
+	RepairVersionOnStart bool
+	// CheckServerVersion makes NewAdapter fail with ErrUnsupportedServer on too old servers (optional)
+	CheckServerVersion bool
+	// InternStrings makes equal field values of loaded rules share memory (optional)
+	InternStrings bool
+	// ConnBudget caps the connections in use by the adapters sharing it, see NewConnBudget (optional)
+	ConnBudget *ConnBudget
+	// TrackCreationOrder makes GetAllPolicies return the rules in creation order (optional)
+	TrackCreationOrder bool
+	// CJSONMatching made filtered operations match the decoded rules.
+	//
+	// Deprecated: Filtered operations match the decoded rules by default, see
+	// RawPatternMatching.
+	CJSONMatching bool
+	// RawPatternMatching matches filters against the bytes of JSON rules instead of decoding them (optional)
+	RawPatternMatching bool
+	// StrictFieldValidation rejects field values that JSON escapes with ErrUnsafeFieldValue (optional)
+	StrictFieldValidation bool
+	// DisableLua rewrites the policy list in transactions instead of running Lua scripts (optional)
+	DisableLua bool
+	// Fencing makes writes fail with ErrFenced once another adapter took over leadership (optional)
+	Fencing bool
+	// Observer is notified of every policy operation, e.g. to record metrics (optional)
+	Observer Observer
+	// Logger reports problems the adapter recovers from (default: the standard logger)
+	Logger Logger
+	// CommandHook is called with every command before it is sent to Redis, for debugging (optional)
+	CommandHook func(cmd string, args []interface{})
+
+	// client is the Client of NewAdapterWithClient, used like Pool.
+	client Client
 }
 
+// defaultMaxIdle is the default value of Config.MaxIdle.
+const defaultMaxIdle = 10
+
+// defaultSaveBatchSize is the default value of Config.SaveBatchSize.
+const defaultSaveBatchSize = 1000
+
+// defaultFilterRegexLimit is the default value of Config.FilterRegexLimit.
+const defaultFilterRegexLimit = 64
+
 // Adapter represents the Redis adapter for policy storage.
 type Adapter struct {
-	network    string
-	address    string
-	key        string
-	username   string
-	password   string
-	tlsConfig  *tls.Config
-	_conn      redis.Conn
-	_pool      *redis.Pool
-	isFiltered bool
-}
-
-func (a *Adapter) getConn() redis.Conn {
-	if a._pool != nil {
-		return a._pool.Get()
+	network                string
+	address                string
+	db                     int
+	key                    string
+	keyMu                  *sync.RWMutex // held for reading by the operations in flight, see SetKey and LoadPolicyAsync
+	keyPrefix              string
+	mergedKeys             []string
+	loadConcurrency        int
+	username               string
+	password               string
+	credentials            func() (username, password string, err error)
+	tlsConfig              *tls.Config
+	tlsFiles               tlsFiles
+	tlsServerName          string
+	tlsInsecure            bool
+	connectTimeout         time.Duration
+	readTimeout            time.Duration
+	writeTimeout           time.Duration
+	keepAlive              time.Duration
+	client                 Client
+	ownsPool               bool     // whether close closes client, false for Config.Pool
+	origin                 *Adapter // the adapter a clone shares client with, see CloneWithKey
+	singleConn             bool     // set by NewAdapterWithConn
+	_pool                  *redis.Pool
+	isFiltered             int32 // set atomically, as loads may run concurrently
+	filterRegexLimit       int
+	filterAll              bool
+	softDelete             bool
+	writeLimiter           RateLimiter
+	writeNoWait            bool
+	encoding               Encoding
+	compression            Compression
+	cipher                 *ruleCipher
+	jsonKeys               JSONKeys
+	codec                  Codec
+	customCodec            bool
+	saveBatchSize          int
+	updateBatchSize        int
+	updateBatchBytes       int
+	layout                 Layout
+	splitSections          bool
+	backend                StorageBackend
+	compatOfficial         bool
+	snapshotPath           string
+	loadErrorPos           bool
+	negativeCache          *negativeCache
+	streamCompactThreshold int
+	cjsonMatching          bool
+	rawMatching            bool
+	strictFields           bool
+	auditStream            string
+	closeTimeout           time.Duration
+	state                  *lifecycle
+	logger                 Logger
+	commandHook            func(cmd string, args []interface{})
+	internStrings          bool
+	observer               Observer
+	disableLua             bool
+	trackOrder             bool
+	connBudget             *ConnBudget
+	regexCache             *regexCache
+	fieldLimitWarned       *sync.Map // ptypes warned about by checkStoredRules
+	baseAdapter            persist.Adapter
+	poolWaitTimeout        time.Duration
+	readPool               *redis.Pool
+	fieldNames             map[string][]string
+	clientName             string
+	strictClientName       bool
+	sentinel               *sentinel
+	addresses              *addressList
+	dialFunc               func() (redis.Conn, error)
+	netDialer              func(network, addr string) (net.Conn, error)
+	operationTimeout       time.Duration
+	fenceToken             *int64 // set with Config.Fencing, shared by the copies of SelfTest
+}
+
+// maxConnAttempts bounds how many pooled connections getConn tries before giving up.
+const maxConnAttempts = 3
+
+// getConn returns a connection for a single operation. A pool may hand out a
+// connection that is already broken, e.g. one dialed while the server was down,
+// so those are discarded and another one is fetched.
+func (a *Adapter) getConn() (redis.Conn, error) {
+	return a.getConnCtx(context.Background())
+}
+
+// getConnCtx is getConn that stops waiting for a pooled connection when ctx is done.
+func (a *Adapter) getConnCtx(ctx context.Context) (redis.Conn, error) {
+	a.connBudget.acquire()
+	conn, err := a.budgetedConn(ctx)
+	if err != nil || a.operationTimeout <= 0 {
+		return conn, err
+	}
+	return timeoutConn{Conn: conn, ctx: ctx, deadline: time.Now().Add(a.operationTimeout), timeout: a.operationTimeout}, nil
+}
+
+// budgetedConn returns a connection for a slot already taken from the budget,
+// freeing the slot if there is none.
+func (a *Adapter) budgetedConn(ctx context.Context) (redis.Conn, error) {
+	conn, err := a.conn(ctx)
+	if err != nil {
+		a.connBudget.release()
+		return nil, err
+	}
+	if a.fenceToken != nil {
+		conn = fencedConn{Conn: conn, metaKey: a.metaKey(), token: a.fenceToken}
 	}
-	return a._conn
+	if a.commandHook == nil {
+		return conn, nil
+	}
+	return hookConn{Conn: conn, hook: a.commandHook}, nil
+}
+
+func (a *Adapter) conn(ctx context.Context) (redis.Conn, error) {
+	return a.client.Get(ctx)
 }
 
 func (a *Adapter) release(conn redis.Conn) {
-	if a._pool != nil {
-		if conn != nil {
-			conn.Close()
-		}
+	if replica, ok := conn.(replicaConn); ok {
+		replica.Close()
+	} else if conn != nil {
+		a.client.Put(conn)
 	}
+	a.connBudget.release()
 }
 
-// finalizer is the destructor for Adapter.
+// finalizer is the destructor for Adapter, a safety net closing the adapter
+// unless Close did.
 func finalizer(a *Adapter) {
-	if a._conn != nil {
-		a._conn.Close()
-	}
-	if a._pool != nil {
-		a._pool.Close()
+	a.state.mu.Lock()
+	closed := a.state.closed
+	a.state.mu.Unlock()
+	if !closed {
+		a.close()
 	}
 }
 
@@ -103,7 +382,16 @@ func NewAdapter(config *Config) (*Adapter, error) {
 		return nil, errors.New("config cannot be nil")
 	}
 
-	a := &Adapter{}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	jsonKeys := config.JSONKeys
+	if jsonKeys == (JSONKeys{}) {
+		jsonKeys = DefaultJSONKeys
+	}
+
+	a := &Adapter{encoding: config.Encoding, jsonKeys: jsonKeys, layout: config.Layout, state: newLifecycle(), keyMu: &sync.RWMutex{}, regexCache: newRegexCache(regexCacheSize), fieldLimitWarned: &sync.Map{}}
 
 	// Set default key if not provided
 	if config.Key == "" {
@@ -111,28 +399,196 @@ func NewAdapter(config *Config) (*Adapter, error) {
 	} else {
 		a.key = config.Key
 	}
+	// Auxiliary keys are derived from the key, so they are prefixed as well.
+	a.keyPrefix = config.KeyPrefix
+	a.key = a.prefixed(a.key)
+	for _, key := range config.Keys {
+		if key = a.prefixed(key); key != a.key {
+			a.mergedKeys = append(a.mergedKeys, key)
+		}
+	}
+	a.loadConcurrency = config.LoadConcurrency
+	a.operationTimeout = config.OperationTimeout
+	a.baseAdapter = config.BaseAdapter
+	if config.AuditStream != "" {
+		a.auditStream = a.prefixed(config.AuditStream)
+	}
 
-	// If a pool is provided, use it
-	if config.Pool != nil {
-		a._pool = config.Pool
+	if config.FilterRegexLimit > 0 {
+		a.filterRegexLimit = config.FilterRegexLimit
 	} else {
-		// Otherwise, create a new connection
-		if config.Network == "" {
-			return nil, errors.New("network is required when not using a pool")
-		}
-		if config.Address == "" {
-			return nil, errors.New("address is required when not using a pool")
+		a.filterRegexLimit = defaultFilterRegexLimit
+	}
+	a.filterAll = config.FilterAllSections
+	a.softDelete = config.SoftDelete
+	a.backend = config.Backend
+	if a.backend == nil {
+		a.backend = layoutBackend(config)
+	}
+	a.snapshotPath = config.SnapshotPath
+	a.loadErrorPos = config.LoadErrorPosition
+	a.readPool = config.ReadPool
+	a.fieldNames = config.FieldNames
+	a.compatOfficial = config.CompatOfficialAdapter
+	a.codec = config.Codec
+	a.customCodec = config.Codec != nil
+	if a.codec == nil {
+		a.codec = newCodec(config.Encoding, jsonKeys)
+	}
+	a.compression = config.Compression
+	a.splitSections = config.SplitSections && config.Layout == ListLayout
+	if len(config.EncryptionKey) > 0 {
+		cipher, err := newRuleCipher(config.EncryptionKey)
+		if err != nil {
+			return nil, err
 		}
+		a.cipher = cipher
+	}
+	a.rawMatching = config.RawPatternMatching && config.Encoding == JSONEncoding && !a.customCodec && !config.CompatOfficialAdapter
+	// The official rows may differ in key casing, which cjson does not fold, so
+	// compat decodes them in Go, as do the backends other than ListBackend.
+	// Scripts can neither decompress nor decrypt rules.
+	_, list := a.backend.(ListBackend)
+	a.cjsonMatching = config.Encoding == JSONEncoding && !a.customCodec && !a.rawMatching && !a.compatOfficial && list &&
+		config.Compression == NoCompression && a.cipher == nil
+	a.strictFields = config.StrictFieldValidation && a.rawMatching
+	if config.StreamCompactThreshold > 0 {
+		a.streamCompactThreshold = config.StreamCompactThreshold
+	} else {
+		a.streamCompactThreshold = defaultStreamCompactThreshold
+	}
+	if config.NegativeCacheTTL > 0 {
+		a.negativeCache = newNegativeCache(config.NegativeCacheTTL)
+	}
+
+	if config.Logger != nil {
+		a.logger = config.Logger
+	} else {
+		a.logger = stdLogger{}
+	}
+	a.commandHook = config.CommandHook
+	a.internStrings = config.InternStrings
+	a.observer = config.Observer
+	a.disableLua = config.DisableLua
+	a.trackOrder = config.TrackCreationOrder
+	if config.Fencing {
+		a.fenceToken = new(int64)
+	}
+	a.connBudget = config.ConnBudget
+
+	if config.CloseTimeout > 0 {
+		a.closeTimeout = config.CloseTimeout
+	} else {
+		a.closeTimeout = defaultCloseTimeout
+	}
 
+	if config.SaveBatchSize > 0 {
+		a.saveBatchSize = config.SaveBatchSize
+	} else {
+		a.saveBatchSize = defaultSaveBatchSize
+	}
+	if config.UpdateBatchSize > 0 {
+		a.updateBatchSize = config.UpdateBatchSize
+	} else {
+		a.updateBatchSize = defaultUpdateBatchSize
+	}
+	a.updateBatchBytes = defaultUpdateBatchBytes
+
+	if config.WriteLimiter != nil {
+		a.writeLimiter = config.WriteLimiter
+	} else if config.WriteRateLimit > 0 {
+		a.writeLimiter = newWriteLimiter(config.WriteRateLimit, config.WriteRateBurst)
+	}
+	a.writeNoWait = config.WriteRateLimitNoWait
+
+	// If a pool is provided, use it
+	var internalPool *redis.Pool
+	if config.client != nil {
+		a.client = config.client
+	} else if config.Pool != nil {
+		a._pool = config.Pool
+		a.poolWaitTimeout = config.PoolWaitTimeout
+	} else if config.RestURL != "" {
+		a.client = newRestClient(config.RestURL, config.RestToken, config.HTTPClient)
+	} else {
+		// Otherwise, connect through a pool of our own
 		a.network = config.Network
 		a.address = config.Address
+		a.db = config.DB
 		a.username = config.Username
 		a.password = config.Password
+		a.credentials = config.CredentialsProvider
 		a.tlsConfig = config.TLSConfig
+		if a.tlsConfig == nil {
+			a.tlsFiles = tlsFiles{cert: config.TLSCertFile, key: config.TLSKeyFile, ca: config.TLSCAFile}
+		}
+		a.tlsServerName = config.TLSServerName
+		a.tlsInsecure = config.TLSInsecureSkipVerify
+		a.connectTimeout = config.ConnectTimeout
+		a.readTimeout = config.ReadTimeout
+		a.writeTimeout = config.WriteTimeout
+		a.keepAlive = config.KeepAlive
+		a.clientName = config.ClientName
+		a.strictClientName = config.StrictClientName
+		a.dialFunc = config.DialFunc
+		a.netDialer = config.NetDialer
+		if len(config.Addresses) > 0 {
+			a.addresses = newAddressList(config.Addresses)
+		}
+		if len(config.SentinelAddrs) > 0 {
+			a.sentinel = newSentinel(config.SentinelAddrs, config.SentinelMasterName, config.SentinelPassword)
+		}
 
-		// Open the DB connection
-		err := a.open()
+		maxIdle := config.MaxIdle
+		if maxIdle <= 0 {
+			maxIdle = defaultMaxIdle
+		}
+		internalPool = &redis.Pool{
+			Dial:            a.dial,
+			MaxIdle:         maxIdle,
+			MaxActive:       config.MaxActive,
+			IdleTimeout:     config.IdleTimeout,
+			MaxConnLifetime: config.MaxConnLifetime,
+			Wait:            config.PoolWait,
+		}
+		if a.keepAlive > 0 {
+			internalPool.TestOnBorrow = a.testIdleConn
+		}
+		a._pool = internalPool
+		a.poolWaitTimeout = config.PoolWaitTimeout
+
+		// Dial the first connection now, so that an unreachable server fails
+		// NewAdapter rather than the first operation.
+		conn := a._pool.Get()
+		err := conn.Err()
+		conn.Close()
 		if err != nil {
+			internalPool.Close()
+			return nil, err
+		}
+	}
+	a.ownsPool = config.Pool == nil || config.ClosePoolOnShutdown
+	if a._pool != nil {
+		a.client = poolClient{pool: a._pool, waitTimeout: a.poolWaitTimeout}
+	}
+
+	// closeOnError closes the connections opened by NewAdapter.
+	closeOnError := func() {
+		if internalPool != nil {
+			internalPool.Close()
+		}
+	}
+
+	if config.CheckServerVersion {
+		if err := a.checkServer(config); err != nil {
+			closeOnError()
+			return nil, err
+		}
+	}
+
+	if config.RepairVersionOnStart {
+		if err := a.repairVersion(false); err != nil {
+			closeOnError()
 			return nil, err
 		}
 	}
@@ -230,13 +686,23 @@ func NewAdapterWithOption(options ...Option) (*Adapter, error) {
 
 	// Convert to new config-based approach
 	config := &Config{
-		Network:   a.network,
-		Address:   a.address,
-		Key:       a.key,
-		Username:  a.username,
-		Password:  a.password,
-		TLSConfig: a.tlsConfig,
-	}
+		Network:               a.network,
+		Address:               a.address,
+		Key:                   a.key,
+		Layout:                a.layout,
+		Username:              a.username,
+		Password:              a.password,
+		TLSConfig:             a.tlsConfig,
+		TLSServerName:         a.tlsServerName,
+		TLSInsecureSkipVerify: a.tlsInsecure,
+		ConnectTimeout:        a.connectTimeout,
+		ReadTimeout:           a.readTimeout,
+		WriteTimeout:          a.writeTimeout,
+		ClientName:            a.clientName,
+		Pool:                  a._pool,
+		client:                a.client,
+	}
+	config.clearDialOptions()
 
 	return NewAdapter(config)
 }
@@ -277,40 +743,142 @@ func WithTls(tlsConfig *tls.Config) Option {
 	}
 }
 
-func (a *Adapter) open() error {
+// WithTLSServerName sets Config.TLSServerName.
+func WithTLSServerName(serverName string) Option {
+	return func(a *Adapter) {
+		a.tlsServerName = serverName
+	}
+}
+
+// WithTLSInsecureSkipVerify sets Config.TLSInsecureSkipVerify.
+func WithTLSInsecureSkipVerify(skip bool) Option {
+	return func(a *Adapter) {
+		a.tlsInsecure = skip
+	}
+}
+
+// WithClientName sets Config.ClientName.
+func WithClientName(name string) Option {
+	return func(a *Adapter) {
+		a.clientName = name
+	}
+}
+
+// WithPool sets Config.Pool, which makes WithNetwork and WithAddress optional.
+func WithPool(pool *redis.Pool) Option {
+	return func(a *Adapter) {
+		a._pool = pool
+	}
+}
+
+// WithTimeouts sets Config.ConnectTimeout, Config.ReadTimeout and
+// Config.WriteTimeout.
+func WithTimeouts(connect, read, write time.Duration) Option {
+	return func(a *Adapter) {
+		a.connectTimeout = connect
+		a.readTimeout = read
+		a.writeTimeout = write
+	}
+}
+
+// WithLayout sets Config.Layout.
+func WithLayout(layout Layout) Option {
+	return func(a *Adapter) {
+		a.layout = layout
+	}
+}
+
+// testIdleConn pings a pooled connection that has been idle for longer than
+// Config.KeepAlive, so that the pool discards and redials it when the server
+// or a middlebox dropped it in the meantime.
+func (a *Adapter) testIdleConn(conn redis.Conn, lastUsed time.Time) error {
+	if time.Since(lastUsed) < a.keepAlive {
+		return nil
+	}
+	_, err := conn.Do("PING")
+	return err
+}
+
+// dial opens a new connection with the configured address and credentials.
+func (a *Adapter) dial() (redis.Conn, error) {
+	if a.dialFunc != nil {
+		return a.dialFunc()
+	}
 	//redis.Dial("tcp", "127.0.0.1:6379")
-	useTls := a.tlsConfig != nil
-	if a.username != "" {
-		conn, err := redis.Dial(a.network, a.address, redis.DialUsername(a.username), redis.DialPassword(a.password), redis.DialTLSConfig(a.tlsConfig), redis.DialUseTLS(useTls))
-		if err != nil {
-			return err
+	tlsConfig, err := a.effectiveTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	useTls := tlsConfig != nil
+	options := []redis.DialOption{redis.DialTLSConfig(tlsConfig), redis.DialUseTLS(useTls)}
+	if a.netDialer != nil {
+		options = append(options, redis.DialNetDial(a.netDialer))
+	}
+	if a.connectTimeout > 0 {
+		options = append(options, redis.DialConnectTimeout(a.connectTimeout))
+	} else if a.addresses != nil {
+		options = append(options, redis.DialConnectTimeout(defaultAddressTimeout))
+	}
+	if a.keepAlive > 0 {
+		options = append(options, redis.DialKeepAlive(a.keepAlive))
+	}
+	if a.readTimeout > 0 {
+		options = append(options, redis.DialReadTimeout(a.readTimeout))
+	}
+	if a.writeTimeout > 0 {
+		options = append(options, redis.DialWriteTimeout(a.writeTimeout))
+	}
+	address := a.address
+	if a.sentinel != nil {
+		// The sentinels are dialed with the same TLS and timeouts.
+		if address, err = a.sentinel.master(a.network, options); err != nil {
+			return nil, err
 		}
+	}
 
-		a._conn = conn
-	} else if a.password == "" {
-		conn, err := redis.Dial(a.network, a.address, redis.DialTLSConfig(a.tlsConfig), redis.DialUseTLS(useTls))
-		if err != nil {
-			return err
+	username, password := a.username, a.password
+	if a.credentials != nil {
+		if username, password, err = a.credentials(); err != nil {
+			return nil, fmt.Errorf("credentials provider: %w", err)
 		}
-
-		a._conn = conn
+	}
+	if username != "" {
+		options = append(options, redis.DialUsername(username))
+	}
+	if password != "" {
+		options = append(options, redis.DialPassword(password))
+	}
+	if a.db != 0 {
+		options = append(options, redis.DialDatabase(a.db))
+	}
+	var conn redis.Conn
+	if a.addresses != nil {
+		conn, err = a.addresses.dial(a.network, options)
 	} else {
-		conn, err := redis.Dial(a.network, a.address, redis.DialPassword(a.password), redis.DialTLSConfig(a.tlsConfig), redis.DialUseTLS(useTls))
-		if err != nil {
-			return err
+		conn, err = redis.Dial(a.network, address, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.sentinel != nil {
+		conn = &masterConn{Conn: conn}
+	}
+	if a.clientName == "" {
+		return conn, nil
+	}
+	if _, err := conn.Do("CLIENT", "SETNAME", a.clientName); err != nil {
+		if a.strictClientName {
+			conn.Close()
+			return nil, fmt.Errorf("setting client name: %w", err)
 		}
-
-		a._conn = conn
+		a.logger.Printf("redis-adapter: cannot set client name %q: %v", a.clientName, err)
 	}
-	return nil
+	return conn, nil
 }
 
 func (a *Adapter) close() {
-	if a._conn != nil {
-		a._conn.Close()
-	}
-	if a._pool != nil {
-		a._pool.Close()
+	if a.ownsPool {
+		a.client.Close()
 	}
 }
 
@@ -318,220 +886,809 @@ func (a *Adapter) createTable() {
 }
 
 func (a *Adapter) dropTable() {
-	conn := a.getConn()
+	conn, err := a.getConn()
+	if err != nil {
+		return
+	}
 	defer a.release(conn)
 
-	_, _ = conn.Do("DEL", a.key)
+	keys := a.policyKeys()
+	if a.perPType() {
+		if keys, err = a.setPolicyKeys(conn); err != nil {
+			return
+		}
+	}
+	_, _ = conn.Do("DEL", append(keys, a.versionKey(), a.metaKey(), a.seqKey(), a.seqCounterKey())...)
+}
+
+// policyKeys returns the keys holding the policy, which SavePolicy replaces.
+func (a *Adapter) policyKeys() []interface{} {
+	if a.layout == StreamLayout {
+		return []interface{}{a.key, a.streamKey()}
+	}
+	if a.softDelete {
+		return []interface{}{a.key, a.deletedKey()}
+	}
+	return []interface{}{a.key}
 }
 
+// toStringPolicy returns the ptype followed by the fields of the rule, without
+// the trailing empty fields.
 func (c *CasbinRule) toStringPolicy() []string {
-	policy := make([]string, 0)
+	policy := make([]string, 0, 7)
 	if c.PType != "" {
 		policy = append(policy, c.PType)
 	}
-	if c.V0 != "" {
-		policy = append(policy, c.V0)
+	fields := []string{c.V0, c.V1, c.V2, c.V3, c.V4, c.V5}
+	n := len(fields)
+	for n > 0 && fields[n-1] == "" {
+		n--
 	}
-	if c.V1 != "" {
-		policy = append(policy, c.V1)
+	return append(policy, fields[:n]...)
+}
+
+// loadPolicyLine loads the rule into the model, with the trailing empty fields
+// its ptype has tokens for.
+func loadPolicyLine(line CasbinRule, model model.Model) error {
+	text := line.toStringPolicy()
+	if len(text) > 0 {
+		if assertion, ok := model[text[0][:1]][text[0]]; ok {
+			for len(text)-1 < len(assertion.Tokens) {
+				text = append(text, "")
+			}
+		}
 	}
-	if c.V2 != "" {
-		policy = append(policy, c.V2)
+
+	return persist.LoadPolicyArray(text, model)
+}
+
+// LoadPolicy loads policy from database. With a SnapshotPath, the loaded policy
+// is saved to the snapshot, and the snapshot is loaded if the database cannot be read.
+func (a *Adapter) LoadPolicy(model model.Model) error {
+	return a.LoadPolicyCtx(context.Background(), model)
+}
+
+// LoadPolicyCtx is LoadPolicy with a context selecting the consistency of the
+// read with Config.ReadPool, see WithConsistency.
+func (a *Adapter) LoadPolicyCtx(ctx context.Context, model model.Model) (err error) {
+	defer a.observe("LoadPolicy", time.Now(), &err)
+	defer a.observeRules(model, &err)
+	if err := a.begin(); err != nil {
+		return err
 	}
-	if c.V3 != "" {
-		policy = append(policy, c.V3)
+	defer a.end()
+
+	// Not a failure of Redis, which the snapshot would cover for.
+	if err := a.checkOrdered(model); err != nil {
+		return err
 	}
-	if c.V4 != "" {
-		policy = append(policy, c.V4)
+	err = a.loadPolicy(ctx, model)
+	if err == nil {
+		err = a.loadBase(model)
 	}
-	if c.V5 != "" {
-		policy = append(policy, c.V5)
+	if a.snapshotPath == "" {
+		return err
 	}
-	return policy
-}
 
-func loadPolicyLine(line CasbinRule, model model.Model) {
-	text := line.toStringPolicy()
+	if err != nil {
+		if snapErr := a.loadSnapshot(model); snapErr != nil {
+			a.logger.Printf("redis-adapter: cannot load snapshot %s: %v", a.snapshotPath, snapErr)
+			return err
+		}
+		a.logger.Printf("redis-adapter: LoadPolicy failed, loaded possibly stale snapshot %s: %v", a.snapshotPath, err)
+		a.setFiltered(false)
+		return nil
+	}
 
-	persist.LoadPolicyArray(text, model)
+	if err = a.saveSnapshot(model); err != nil {
+		a.logger.Printf("redis-adapter: cannot save snapshot %s: %v", a.snapshotPath, err)
+	}
+	return nil
 }
 
-// LoadPolicy loads policy from database.
-func (a *Adapter) LoadPolicy(model model.Model) error {
-	conn := a.getConn()
-	defer a.release(conn)
-
-	num, err := redis.Int(conn.Do("LLEN", a.key))
-	if err == redis.ErrNil {
+func (a *Adapter) loadPolicy(ctx context.Context, model model.Model) error {
+	if a.perPType() {
+		if err := a.setLoadPolicy(ctx, model, nil); err != nil {
+			return err
+		}
+		a.setFiltered(false)
 		return nil
 	}
-	if err != nil {
-		return err
+	if a.layout == StreamLayout {
+		if err := a.streamLoadPolicy(ctx, model, nil); err != nil {
+			return err
+		}
+		a.setFiltered(false)
+		return nil
 	}
-	values, err := redis.Values(conn.Do("LRANGE", a.key, 0, num))
+
+	var texts [][]byte
+	err := a.read(ctx, func(conn redis.Conn) (err error) {
+		texts, err = a.loadMergedValues(conn)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
+	in := a.newInterner()
 	var line CasbinRule
-	for _, value := range values {
-		text, ok := value.([]byte)
-		if !ok {
-			// Amazon MemoryDB for Redis returns string instead of []byte
-			if textStr, ok := value.(string); ok {
-				text = []byte(textStr)
-			} else {
-				return errors.New("the type is wrong")
+	for i, text := range texts {
+		err = a.unmarshal(text, &line)
+		if err != nil {
+			if a.loadErrorPos {
+				return &LoadError{Index: i, Loaded: i, Err: err}
 			}
+			return err
 		}
-		err = json.Unmarshal(text, &line)
-		if err != nil {
+		in.line(&line)
+		if err = loadPolicyLine(line, model); err != nil {
+			if a.loadErrorPos {
+				return &LoadError{Index: i, Loaded: i, Err: err}
+			}
 			return err
 		}
-		loadPolicyLine(line, model)
 	}
 
-	a.isFiltered = false
+	a.setFiltered(false)
 	return nil
 }
 
-func savePolicyLine(ptype string, rule []string) CasbinRule {
-	line := CasbinRule{}
+// LoadError is returned by LoadPolicy with Config.LoadErrorPosition when a
+// stored rule cannot be decoded or loaded into the model, to help find a
+// corrupted entry.
+type LoadError struct {
+	// Index is the position of the rule among the stored rules, those of the key
+	// followed by those of Config.Keys. Removed rules left in the list, e.g.
+	// soft-deleted ones, are not counted.
+	Index int
+	// Loaded is the number of rules loaded into the model before the failure.
+	Loaded int
+	// Err is the decoding or loading error.
+	Err error
+}
 
-	line.PType = ptype
-	if len(rule) > 0 {
-		line.V0 = rule[0]
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("cannot load rule %d, %d rules loaded: %v", e.Index, e.Loaded, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// GetAllGrouped returns the stored rules grouped by ptype, e.g.
+// {"p": {{"alice", "data1", "read"}}, "g": {{"alice", "data2_admin"}}}.
+// The rules do not include the ptype. Rules stored under Config.Keys are included.
+func (a *Adapter) GetAllGrouped() (map[string][][]string, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
 	}
-	if len(rule) > 1 {
-		line.V1 = rule[1]
+	defer a.end()
+
+	if a.perPType() {
+		return a.setGetAllGrouped()
 	}
-	if len(rule) > 2 {
-		line.V2 = rule[2]
+	if a.layout == StreamLayout {
+		return a.streamGetAllGrouped()
 	}
-	if len(rule) > 3 {
-		line.V3 = rule[3]
+
+	conn, err := a.getConn()
+	if err != nil {
+		return nil, err
 	}
-	if len(rule) > 4 {
-		line.V4 = rule[4]
+	defer a.release(conn)
+
+	texts, err := a.loadMergedValues(conn)
+	if err != nil {
+		return nil, err
 	}
-	if len(rule) > 5 {
-		line.V5 = rule[5]
+
+	grouped := make(map[string][][]string)
+	var line CasbinRule
+	for _, text := range texts {
+		if err = a.unmarshal(text, &line); err != nil {
+			return nil, err
+		}
+		grouped[line.PType] = append(grouped[line.PType], line.toStringPolicy()[1:])
 	}
+	return grouped, nil
+}
 
-	return line
+// loadValues returns the serialized rules stored under the key, leaving out
+// soft-deleted ones.
+func (a *Adapter) loadValues(conn redis.Conn) ([][]byte, error) {
+	return a.loadKeyValues(conn, a.key)
 }
 
-// SavePolicy saves policy to database.
-func (a *Adapter) SavePolicy(model model.Model) error {
-	a.dropTable()
-	a.createTable()
+// loadMergedValues returns the serialized rules stored under the key followed by
+// those stored under Config.Keys, in the order of Config.Keys.
+func (a *Adapter) loadMergedValues(conn redis.Conn) ([][]byte, error) {
+	texts, err := a.loadValues(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.mergedKeys) == 0 {
+		return texts, nil
+	}
 
-	var texts [][]byte
+	results := make([][][]byte, len(a.mergedKeys))
+	errs := make([]error, len(a.mergedKeys))
+	pending := make(chan int, len(a.mergedKeys))
+	for i := range a.mergedKeys {
+		pending <- i
+	}
+	close(pending)
+	load := func(conn redis.Conn) {
+		for i := range pending {
+			results[i], errs[i] = a.loadKeyValues(conn, a.mergedKeys[i])
+		}
+	}
 
-	for ptype, ast := range model["p"] {
-		for _, rule := range ast.Policy {
-			line := savePolicyLine(ptype, rule)
-			text, err := json.Marshal(line)
+	// conn loads keys too, the other workers only start if they get a
+	// connection without waiting, so a used up ConnBudget cannot deadlock.
+	var wg sync.WaitGroup
+	if a._pool != nil {
+		// The workers read from the same server as conn.
+		budgetedConn := a.budgetedConn
+		if _, ok := conn.(replicaConn); ok {
+			budgetedConn = a.budgetedReplicaConn
+		}
+		for w := 1; w < a.loadConcurrency && w < len(a.mergedKeys); w++ {
+			if !a.connBudget.tryAcquire() {
+				break
+			}
+			worker, err := budgetedConn(context.Background())
 			if err != nil {
-				return err
+				break
 			}
-			texts = append(texts, text)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer a.release(worker)
+				load(worker)
+			}()
 		}
 	}
+	load(conn)
+	wg.Wait()
 
-	for ptype, ast := range model["g"] {
-		for _, rule := range ast.Policy {
-			line := savePolicyLine(ptype, rule)
-			text, err := json.Marshal(line)
-			if err != nil {
-				return err
-			}
-			texts = append(texts, text)
+	for i, key := range a.mergedKeys {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("key %s: %w", key, errs[i])
 		}
+		texts = append(texts, results[i]...)
 	}
+	return texts, nil
+}
 
-	conn := a.getConn()
-	defer a.release(conn)
+func (a *Adapter) loadKeyValues(conn redis.Conn, key string) ([][]byte, error) {
+	texts, err := a.backend.LoadAll(conn, key)
+	if err != nil || !a.softDelete {
+		return texts, err
+	}
 
-	_, err := conn.Do("RPUSH", redis.Args{}.Add(a.key).AddFlat(texts)...)
-	return err
+	deleted, err := a.loadDeleted(conn, deletedKeyOf(key))
+	if err != nil {
+		return nil, err
+	}
+	kept := texts[:0]
+	for _, text := range texts {
+		if _, ok := deleted[string(text)]; !ok {
+			kept = append(kept, text)
+		}
+	}
+	return kept, nil
 }
 
-// AddPolicy adds a policy rule to the storage.
-func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
-	line := savePolicyLine(ptype, rule)
-	text, err := json.Marshal(line)
-	if err != nil {
-		return err
+// valueBytes converts a value of a multi-bulk reply to bytes.
+func valueBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		// Amazon MemoryDB for Redis returns string instead of []byte
+		return []byte(v), nil
+	default:
+		return nil, errors.New("the type is wrong")
 	}
+}
 
-	conn := a.getConn()
+// ErrTooManyFields is returned for rules with more fields than CasbinRule holds,
+// V0 to V5, as they could not be stored without dropping fields.
+var ErrTooManyFields = errors.New("rule has more than 6 fields")
+
+// maxRuleFields is the number of fields of a rule CasbinRule holds.
+const maxRuleFields = 6
+
+// checkRules rejects rules with more than maxRuleFields fields.
+func checkRules(rules [][]string) error {
+	for _, rule := range rules {
+		if len(rule) > maxRuleFields {
+			return fmt.Errorf("%w: %v", ErrTooManyFields, rule)
+		}
+	}
+	return nil
+}
+
+// checkStoredRules is checkRules for rules about to be stored, which also checks
+// their values with Config.StrictFieldValidation. It also warns, once per ptype,
+// when rules use all fields, since the policy is then one field away from rules
+// the adapter rejects.
+func (a *Adapter) checkStoredRules(ptype string, rules [][]string) error {
+	if err := checkRules(rules); err != nil {
+		return err
+	}
+	if err := a.checkFieldValues(ptype); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := a.checkFieldValues(rule...); err != nil {
+			return err
+		}
+	}
+	for _, rule := range rules {
+		if len(rule) == maxRuleFields {
+			if _, warned := a.fieldLimitWarned.LoadOrStore(ptype, struct{}{}); !warned {
+				a.logger.Printf("redis-adapter: rules of ptype %s use all %d fields V0 to V5, rules with more fields are rejected", ptype, maxRuleFields)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// checkUpdateRules checks the rules replaced by an update and their replacements.
+func (a *Adapter) checkUpdateRules(ptype string, oldRules, newRules [][]string) error {
+	if err := checkRules(oldRules); err != nil {
+		return err
+	}
+	return a.checkStoredRules(ptype, newRules)
+}
+
+func savePolicyLine(ptype string, rule []string) CasbinRule {
+	line := CasbinRule{}
+
+	line.PType = ptype
+	if len(rule) > 0 {
+		line.V0 = rule[0]
+	}
+	if len(rule) > 1 {
+		line.V1 = rule[1]
+	}
+	if len(rule) > 2 {
+		line.V2 = rule[2]
+	}
+	if len(rule) > 3 {
+		line.V3 = rule[3]
+	}
+	if len(rule) > 4 {
+		line.V4 = rule[4]
+	}
+	if len(rule) > 5 {
+		line.V5 = rule[5]
+	}
+
+	return line
+}
+
+// SavePolicy saves policy to database.
+func (a *Adapter) SavePolicy(model model.Model) error {
+	return a.SavePolicyCtx(context.Background(), model)
+}
+
+// SavePolicyCtx is SavePolicy with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) SavePolicyCtx(ctx context.Context, model model.Model) (err error) {
+	defer a.observe("SavePolicy", time.Now(), &err)
+	defer a.observeRules(model, &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	defer func() { a.changed(ctx, &err, AuditEntry{Op: AuditSave, Count: countRules(model)}) }()
+
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
 	defer a.release(conn)
 
-	_, err = conn.Do("RPUSH", a.key, text)
-	return err
+	tmps, err := a.sendSavePolicy(conn, model)
+	if err != nil {
+		return err
+	}
+	reply, err := conn.Do("EXEC")
+	if err == nil {
+		err = execError(reply)
+	}
+	if err != nil {
+		a.dropTempKeys(conn, tmps)
+		return err
+	}
+	return a.syncCreated(conn)
+}
+
+// saveTempTTL is how long the temporary keys of SavePolicy outlive a save
+// abandoned before renaming them, e.g. by a crash.
+const saveTempTTL = time.Hour
+
+// sendSavePolicy writes model to temporary keys, then starts a transaction on
+// conn and queues the replacement of the stored policy with them. The caller
+// executes the transaction, and removes the returned temporary keys with
+// dropTempKeys if it fails.
+func (a *Adapter) sendSavePolicy(conn redis.Conn, model model.Model) (tmps []string, err error) {
+	if err := a.checkOrdered(model); err != nil {
+		return nil, err
+	}
+	if err := a.checkDistinct(model); err != nil {
+		return nil, err
+	}
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			if err := a.checkStoredRules(ptype, ast.Policy); err != nil {
+				return nil, err
+			}
+		}
+	}
+	oldKeys := a.policyKeys()
+	if a.perPType() {
+		if oldKeys, err = a.setPolicyKeys(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	// The rules are marshalled and written in batches, so memory use is bounded by
+	// the batch size rather than the whole policy. Until the transaction renames
+	// the temporary keys over the keys, a failure leaves the stored policy intact.
+	suffix := make([]byte, 8)
+	if _, err = rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	var keys, temps []string
+	defer func() {
+		if err != nil {
+			a.dropTempKeys(conn, temps)
+		}
+	}()
+
+	batch := make([][]byte, 0, a.saveBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		temp := temps[len(temps)-1]
+		if err := a.backend.Append(conn, temp, batch); err != nil {
+			return err
+		}
+		if _, err := conn.Do("PEXPIRE", temp, saveTempTTL.Milliseconds()); err != nil {
+			return err
+		}
+		for i := range batch {
+			batch[i] = nil
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	// PTypeSetLayout and Config.SplitSections write the rules of each ptype to a
+	// key of their own, and record the ptypes.
+	ptypes := redis.Args{}.Add(a.ptypesKey())
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			if len(ast.Policy) == 0 {
+				continue
+			}
+			key := a.key
+			if a.perPType() {
+				key = a.ptypeKey(ptype)
+				ptypes = ptypes.Add(ptype)
+			}
+			if len(keys) == 0 || keys[len(keys)-1] != key {
+				if err = flush(); err != nil {
+					return nil, err
+				}
+				keys = append(keys, key)
+				temps = append(temps, key+":saving:"+hex.EncodeToString(suffix))
+			}
+			for _, rule := range ast.Policy {
+				text, err := a.marshalStored(savePolicyLine(ptype, rule))
+				if err != nil {
+					return nil, err
+				}
+				batch = append(batch, text)
+				if len(batch) >= a.saveBatchSize {
+					if err = flush(); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	if err = flush(); err != nil {
+		return nil, err
+	}
+
+	if err = conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if err = conn.Send("DEL", oldKeys...); err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		// RENAME keeps the expiry of the temporary key.
+		if err = conn.Send("RENAME", temps[i], key); err != nil {
+			return nil, err
+		}
+		if err = conn.Send("PERSIST", key); err != nil {
+			return nil, err
+		}
+	}
+	if len(ptypes) > 1 {
+		if err = conn.Send("SADD", ptypes...); err != nil {
+			return nil, err
+		}
+	}
+	return temps, nil
+}
+
+// dropTempKeys removes the temporary keys of a failed save. The keys expire
+// anyway, so errors are ignored.
+func (a *Adapter) dropTempKeys(conn redis.Conn, tmps []string) {
+	for _, tmp := range tmps {
+		_ = a.backend.Clear(conn, tmp)
+	}
+}
+
+// execError returns the first error reply of the commands of a transaction,
+// which EXEC returns among the other replies.
+func execError(reply interface{}) error {
+	values, _ := reply.([]interface{})
+	for _, value := range values {
+		if err, ok := value.(redis.Error); ok {
+			return fenceError(err)
+		}
+	}
+	return nil
+}
+
+// AddPolicy adds a policy rule to the storage.
+func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.AddPolicyCtx(context.Background(), sec, ptype, rule)
+}
+
+// AddPolicyCtx is AddPolicy with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) AddPolicyCtx(ctx context.Context, sec string, ptype string, rule []string) (err error) {
+	defer a.observe("AddPolicy", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	defer a.changed(ctx, &err, AuditEntry{Op: AuditAdd, Sec: sec, PType: ptype, Rules: [][]string{rule}})
+
+	if err := a.checkStoredRules(ptype, [][]string{rule}); err != nil {
+		return err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
+	if a.perPType() {
+		return a.setAddPolicies(ptype, [][]string{rule})
+	}
+	if a.layout == StreamLayout {
+		return a.streamAddPolicies(ptype, [][]string{rule})
+	}
+
+	line := savePolicyLine(ptype, rule)
+	text, err := a.marshal(line)
+	if err != nil {
+		return err
+	}
+
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	if a.softDelete {
+		return a.softAdd(conn, [][]byte{text})
+	}
+	if err = a.backend.Append(conn, a.key, [][]byte{text}); err != nil {
+		return err
+	}
+	return a.recordCreated(conn, [][]byte{text})
 }
 
 // RemovePolicy removes a policy rule from the storage.
 func (a *Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.RemovePolicyCtx(context.Background(), sec, ptype, rule)
+}
+
+// RemovePolicyCtx is RemovePolicy with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) RemovePolicyCtx(ctx context.Context, sec string, ptype string, rule []string) (err error) {
+	defer a.observe("RemovePolicy", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	defer a.changed(ctx, &err, AuditEntry{Op: AuditRemove, Sec: sec, PType: ptype, Rules: [][]string{rule}})
+
+	if err := checkRules([][]string{rule}); err != nil {
+		return err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
+	if a.perPType() {
+		return a.setRemovePolicies(ptype, [][]string{rule})
+	}
+	if a.layout == StreamLayout {
+		return a.streamRemovePolicies(ptype, [][]string{rule})
+	}
+
 	line := savePolicyLine(ptype, rule)
-	text, err := json.Marshal(line)
+	text, err := a.marshal(line)
 	if err != nil {
 		return err
 	}
 
-	conn := a.getConn()
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
 	defer a.release(conn)
 
-	_, err = conn.Do("LREM", a.key, 1, text)
-	return err
+	if a.softDelete {
+		return a.markDeleted(conn, [][]byte{text})
+	}
+	texts := [][]byte{text}
+	if a.lookupStoredForms() {
+		if texts, err = a.storedForms(conn, texts); err != nil {
+			return err
+		}
+	}
+	if err = a.backend.Remove(conn, a.key, texts); err != nil {
+		return err
+	}
+	return a.forgetCreated(conn, texts)
 }
 
 // AddPolicies adds policy rules to the storage.
 func (a *Adapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	return a.AddPoliciesCtx(context.Background(), sec, ptype, rules)
+}
+
+// AddPoliciesCtx is AddPolicies with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) AddPoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) (err error) {
+	defer a.observe("AddPolicies", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	defer a.changed(ctx, &err, AuditEntry{Op: AuditAdd, Sec: sec, PType: ptype, Rules: rules})
+
+	if err := a.checkStoredRules(ptype, rules); err != nil {
+		return err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
+	if a.perPType() {
+		return a.setAddPolicies(ptype, rules)
+	}
+	if a.layout == StreamLayout {
+		return a.streamAddPolicies(ptype, rules)
+	}
+
 	var texts [][]byte
 	for _, rule := range rules {
 		line := savePolicyLine(ptype, rule)
-		text, err := json.Marshal(line)
+		text, err := a.marshal(line)
 		if err != nil {
 			return err
 		}
 		texts = append(texts, text)
 	}
 
-	conn := a.getConn()
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
 	defer a.release(conn)
 
-	_, err := conn.Do("RPUSH", redis.Args{}.Add(a.key).AddFlat(texts)...)
-	return err
+	if a.softDelete {
+		return a.softAdd(conn, texts)
+	}
+	if err = a.backend.Append(conn, a.key, texts); err != nil {
+		return err
+	}
+	return a.recordCreated(conn, texts)
 }
 
-// RemovePolicies removes policy rules from the storage.
+// RemovePolicies removes policy rules from the storage. Each LREM scans the list,
+// so removing many rules from a long list blocks Redis for one scan per rule; with
+// SingleScanRemoval they are removed in a single scan instead.
 func (a *Adapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
-	conn := a.getConn()
-	defer a.release(conn)
+	return a.RemovePoliciesCtx(context.Background(), sec, ptype, rules)
+}
+
+// RemovePoliciesCtx is RemovePolicies with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) RemovePoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) (err error) {
+	defer a.observe("RemovePolicies", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	defer a.changed(ctx, &err, AuditEntry{Op: AuditRemove, Sec: sec, PType: ptype, Rules: rules})
+
+	if err := checkRules(rules); err != nil {
+		return err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
+	if a.perPType() {
+		return a.setRemovePolicies(ptype, rules)
+	}
+	if a.layout == StreamLayout {
+		return a.streamRemovePolicies(ptype, rules)
+	}
 
+	texts := make([][]byte, 0, len(rules))
 	for _, rule := range rules {
-		line := savePolicyLine(ptype, rule)
-		text, err := json.Marshal(line)
+		text, err := a.marshal(savePolicyLine(ptype, rule))
 		if err != nil {
 			return err
 		}
-		_, err = conn.Do("LREM", a.key, 1, text)
-		if err != nil {
+		texts = append(texts, text)
+	}
+
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	if a.softDelete {
+		return a.markDeleted(conn, texts)
+	}
+	if a.lookupStoredForms() {
+		if texts, err = a.storedForms(conn, texts); err != nil {
 			return err
 		}
 	}
-	return nil
+	if err = a.backend.Remove(conn, a.key, texts); err != nil {
+		return err
+	}
+	return a.forgetCreated(conn, texts)
 }
 
 //FilteredAdapter
 
 // IsFiltered returns true if the loaded policy has been filtered.
 func (a *Adapter) IsFiltered() bool {
-	return a.isFiltered
+	return atomic.LoadInt32(&a.isFiltered) == 1
 }
 
+func (a *Adapter) setFiltered(filtered bool) {
+	var v int32
+	if filtered {
+		v = 1
+	}
+	atomic.StoreInt32(&a.isFiltered, v)
+}
+
+// Filter selects the rules loaded by LoadFilteredPolicy. A rule matches if each
+// of its fields is one of the values listed for it; a field without values
+// matches anything. Without PType only policy rules match, unless
+// Config.FilterAllSections is set.
 type Filter struct {
 	PType []string
 	V0    []string
@@ -542,7 +1699,81 @@ type Filter struct {
 	V5    []string
 }
 
-func filterToRegexPattern(filter *Filter) string {
+// ErrNotFieldValues is returned by Filter.CheckFieldValues for filters that
+// cannot be expressed as field values.
+var ErrNotFieldValues = errors.New("filter cannot be expressed as field values")
+
+// ErrNonContiguousFields is returned by Filter.CheckFieldValues for filters with
+// unset fields between set ones.
+var ErrNonContiguousFields = errors.New("filter fields are not contiguous")
+
+// FilterFromFieldValues converts the (fieldIndex, fieldValues...) arguments used by
+// RemoveFilteredPolicy into a Filter. fieldIndex 0 refers to V0. An empty value is a
+// wildcard and leaves the corresponding field unset, and values before V0 or past V5
+// are left out. PType is never set, callers filter on it separately.
+func FilterFromFieldValues(fieldIndex int, fieldValues ...string) Filter {
+	var filter Filter
+	fields := filter.fields()
+	for i, v := range fieldValues {
+		if j := fieldIndex + i; j >= 0 && j < len(fields) && v != "" {
+			*fields[j] = []string{v}
+		}
+	}
+	return filter
+}
+
+// ToFieldValues converts the filter into the (fieldIndex, fieldValues...) arguments
+// used by RemoveFilteredPolicy. PType is ignored, and a filter without any field set
+// returns (0, nil). A filter that CheckFieldValues rejects, e.g. one with unset
+// fields between set ones, returns (-1, nil).
+func (filter Filter) ToFieldValues() (int, []string) {
+	if filter.CheckFieldValues() != nil {
+		return -1, nil
+	}
+	fields := filter.fields()
+	for i, f := range fields {
+		if len(*f) == 0 {
+			continue
+		}
+		var fieldValues []string
+		for _, f := range fields[i:] {
+			if len(*f) == 0 {
+				break
+			}
+			fieldValues = append(fieldValues, (*f)[0])
+		}
+		return i, fieldValues
+	}
+	return 0, nil
+}
+
+// CheckFieldValues returns ErrNonContiguousFields if unset fields of the filter
+// are between set ones, which the field values would turn into wildcards, and
+// ErrNotFieldValues if a field has more than one value or a single empty one.
+func (filter Filter) CheckFieldValues() error {
+	fields := filter.fields()
+	last := -1
+	for i, f := range fields {
+		if len(*f) == 0 {
+			continue
+		}
+		if len(*f) > 1 || (*f)[0] == "" {
+			return fmt.Errorf("%w: V%d is %q", ErrNotFieldValues, i, *f)
+		}
+		if last >= 0 && last != i-1 {
+			return fmt.Errorf("%w: V%d to V%d are unset", ErrNonContiguousFields, last+1, i-1)
+		}
+		last = i
+	}
+	return nil
+}
+
+// fields returns pointers to V0 to V5 of the filter.
+func (filter *Filter) fields() [6]*[]string {
+	return [6]*[]string{&filter.V0, &filter.V1, &filter.V2, &filter.V3, &filter.V4, &filter.V5}
+}
+
+func filterToRegexPattern(filter *Filter, keys JSONKeys) string {
 	// example data in redis: {"PType":"p","V0":"data2_admin","V1":"data2","V2":"write","V3":"","V4":"","V5":""}
 
 	var f = [][]string{filter.PType,
@@ -564,12 +1795,78 @@ func filterToRegexPattern(filter *Filter) string {
 
 	// example pattern:
 	//^\{"PType":".*","V0":"(?:data2_admin|data1_admin)","V1":".*","V2":".*","V3":".*","V4":".*","V5":".*"\}$
-	pattern := fmt.Sprintf(
-		`^\{"PType":"%s","V0":"%s","V1":"%s","V2":"%s","V3":"%s","V4":"%s","V5":"%s"\}$`, args...,
-	)
+	pattern := fmt.Sprintf(`^\{`+keys.patternFormat(regexp.QuoteMeta)+`\}$`, args...)
 	return pattern
 }
 
+// defaultPType returns filter restricted to the ptypes of the policy section of
+// model if it does not list any ptype, unless Config.FilterAllSections is set.
+func (a *Adapter) defaultPType(model model.Model, filter *Filter) *Filter {
+	if a.filterAll || len(filter.PType) > 0 {
+		return filter
+	}
+	f := *filter
+	for ptype := range model["p"] {
+		f.PType = append(f.PType, ptype)
+	}
+	// No ptype matches nothing rather than everything.
+	if len(f.PType) == 0 {
+		f.PType = []string{""}
+	}
+	sort.Strings(f.PType)
+	return &f
+}
+
+// filterSet matches rules against a Filter by set membership. It replaces the
+// regular expression built by filterToRegexPattern when a field has so many
+// values that the alternation would be slow to compile and match.
+type filterSet [7]map[string]struct{}
+
+func newFilterSet(filter *Filter) filterSet {
+	var f = [][]string{filter.PType,
+		filter.V0, filter.V1, filter.V2,
+		filter.V3, filter.V4, filter.V5}
+
+	var s filterSet
+	for i, v := range f {
+		if len(v) == 0 {
+			continue
+		}
+		s[i] = make(map[string]struct{}, len(v))
+		for _, value := range v {
+			s[i][value] = struct{}{}
+		}
+	}
+	return s
+}
+
+func (s filterSet) match(line *CasbinRule) bool {
+	fields := [7]string{line.PType, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	for i, field := range fields {
+		if s[i] == nil {
+			continue
+		}
+		if _, ok := s[i][field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// exceedsRegexLimit reports whether any field of the filter has more than limit values.
+func (filter *Filter) exceedsRegexLimit(limit int) bool {
+	var f = [][]string{filter.PType,
+		filter.V0, filter.V1, filter.V2,
+		filter.V3, filter.V4, filter.V5}
+
+	for _, v := range f {
+		if len(v) > limit {
+			return true
+		}
+	}
+	return false
+}
+
 func escapeLuaPattern(s string) string {
 	var buf bytes.Buffer
 	for _, char := range s {
@@ -582,7 +1879,7 @@ func escapeLuaPattern(s string) string {
 	return buf.String()
 }
 
-func filterFieldToLuaPattern(sec string, ptype string, fieldIndex int, fieldValues ...string) string {
+func filterFieldToLuaPattern(keys JSONKeys, sec string, ptype string, fieldIndex int, fieldValues ...string) string {
 	args := []interface{}{ptype}
 
 	idx := fieldIndex + len(fieldValues)
@@ -596,67 +1893,88 @@ func filterFieldToLuaPattern(sec string, ptype string, fieldIndex int, fieldValu
 
 	// example pattern:
 	// ^{"PType":"p","V0":"data2_admin","V1":".*","V2":".*","V3":".*","V4":".*","V5":".*"}$
-	pattern := fmt.Sprintf(
-		`^{"PType":"%s","V0":"%s","V1":"%s","V2":"%s","V3":"%s","V4":"%s","V5":"%s"}$`, args...,
-	)
+	pattern := fmt.Sprintf(`^{`+keys.patternFormat(escapeLuaPattern)+`}$`, args...)
 	return pattern
 }
 
-func (a *Adapter) loadFilteredPolicy(model model.Model, filter *Filter) error {
-	conn := a.getConn()
-	defer a.release(conn)
-
-	num, err := redis.Int(conn.Do("LLEN", a.key))
-	if err == redis.ErrNil {
-		return nil
-	}
-	if err != nil {
+func (a *Adapter) loadFilteredPolicy(ctx context.Context, model model.Model, filter *Filter) error {
+	if err := a.checkOrdered(model); err != nil {
 		return err
 	}
-	values, err := redis.Values(conn.Do("LRANGE", a.key, 0, num))
+	filter = a.defaultPType(model, filter)
+	if a.perPType() {
+		return a.setLoadPolicy(ctx, model, filter)
+	}
+	if a.layout == StreamLayout {
+		return a.streamLoadPolicy(ctx, model, filter)
+	}
+
+	var texts [][]byte
+	err := a.read(ctx, func(conn redis.Conn) (err error) {
+		texts, err = a.loadMergedValues(conn)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	re := regexp.MustCompile(filterToRegexPattern(filter))
+	// Large filters are matched client-side, a huge alternation is slow to compile and match.
+	// The regular expression only matches the JSON bytes of RawPatternMatching,
+	// other rules are matched decoded.
+	var re *regexp.Regexp
+	var set filterSet
+	useSet := !a.rawMatching || filter.exceedsRegexLimit(a.filterRegexLimit)
+	if useSet {
+		set = newFilterSet(filter)
+	} else {
+		re = a.regexCache.compile(filterToRegexPattern(filter, a.jsonKeys))
+	}
 
+	in := a.newInterner()
 	var line CasbinRule
-	for _, value := range values {
-		text, ok := value.([]byte)
-		if !ok {
-			// Amazon MemoryDB for Redis returns string instead of []byte
-			if textStr, ok := value.(string); ok {
-				text = []byte(textStr)
-			} else {
-				return errors.New("the type is wrong")
-			}
-		}
-
-		if !re.Match(text) {
+	for _, text := range texts {
+		if !useSet && !re.Match(text) {
 			continue
 		}
 
-		err = json.Unmarshal(text, &line)
+		err = a.unmarshal(text, &line)
 		if err != nil {
 			return err
 		}
-		loadPolicyLine(line, model)
+		if useSet && !set.match(&line) {
+			continue
+		}
+		in.line(&line)
+		if err = loadPolicyLine(line, model); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 // LoadFilteredPolicy loads only policy rules that match the filter.
 func (a *Adapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
+	return a.LoadFilteredPolicyCtx(context.Background(), model, filter)
+}
+
+// LoadFilteredPolicyCtx is LoadFilteredPolicy with a context selecting the
+// consistency of the read with Config.ReadPool, see WithConsistency.
+func (a *Adapter) LoadFilteredPolicyCtx(ctx context.Context, model model.Model, filter interface{}) (err error) {
 	if filter == nil {
-		return a.LoadPolicy(model)
+		return a.LoadPolicyCtx(ctx, model)
 	}
 
-	var err error
+	defer a.observe("LoadFilteredPolicy", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
 	switch f := filter.(type) {
 	case *Filter:
-		err = a.loadFilteredPolicy(model, f)
+		err = a.loadFilteredCached(ctx, model, f)
 	case Filter:
-		err = a.loadFilteredPolicy(model, &f)
+		err = a.loadFilteredCached(ctx, model, &f)
 	default:
 		err = fmt.Errorf("invalid filter type")
 	}
@@ -664,182 +1982,292 @@ func (a *Adapter) LoadFilteredPolicy(model model.Model, filter interface{}) erro
 	if err != nil {
 		return err
 	}
-	a.isFiltered = true
+	a.setFiltered(true)
 	return nil
 }
 
 // RemoveFilteredPolicy removes policy rules that match the filter from the storage.
 func (a *Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return a.RemoveFilteredPolicyCtx(context.Background(), sec, ptype, fieldIndex, fieldValues...)
+}
 
-	pattern := filterFieldToLuaPattern(sec, ptype, fieldIndex, fieldValues...)
-
-	var getScript = redis.NewScript(1, `
-		local key = KEYS[1]
-		local pattern = ARGV[1]
-		
-		local r = redis.call('lrange', key, 0, -1)
-		for i=1, #r do 
-			if  string.find(r[i], pattern) then
-				redis.call('lset', key, i-1, '__CASBIN_DELETED__')
-			end
-		end
-		redis.call('lrem', key, 0, '__CASBIN_DELETED__')
-		return 
-	`)
-
-	conn := a.getConn()
+// RemoveFilteredPolicyCtx is RemoveFilteredPolicy with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) RemoveFilteredPolicyCtx(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) (err error) {
+	defer a.observe("RemoveFilteredPolicy", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	defer a.changed(ctx, &err, AuditEntry{Op: AuditRemoveFiltered, Sec: sec, PType: ptype, FieldIndex: fieldIndex, FieldValues: append([]string{}, fieldValues...)})
+
+	if err := a.checkFieldValues(fieldValues...); err != nil {
+		return err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
+	if a.perPType() {
+		return a.setRemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+	}
+	if a.layout == StreamLayout {
+		return a.streamRemoveFilteredPolicy(ptype, fieldIndex, fieldValues...)
+	}
+
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
 	defer a.release(conn)
 
-	_, err := getScript.Do(conn, a.key, pattern)
-	return err
+	if a.disableLua || a.trackOrder || (a.softDelete && !a.rawMatching) {
+		return a.removeFilteredDecoded(conn, ptype, fieldIndex, fieldValues...)
+	}
+	if a.cjsonMatching {
+		cond, err := filterFieldConditions(a.jsonKeys, ptype, fieldIndex, fieldValues...)
+		if err != nil {
+			return err
+		}
+		_, err = a.doScript(removeFilteredCJSONScript, conn, a.key, cond)
+		return err
+	}
+	if a.softDelete {
+		return a.markDeletedFiltered(conn, filterFieldToLuaPattern(a.jsonKeys, sec, ptype, fieldIndex, fieldValues...))
+	}
+	return a.backend.RemoveMatching(conn, a.key, a.ruleMatch(sec, ptype, fieldIndex, fieldValues...))
 }
 
 // UpdatableAdapter
 
 // UpdatePolicy updates a new policy rule to DB.
 func (a *Adapter) UpdatePolicy(sec string, ptype string, oldRule, newPolicy []string) error {
+	return a.UpdatePolicyCtx(context.Background(), sec, ptype, oldRule, newPolicy)
+}
+
+// UpdatePolicyCtx is UpdatePolicy with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) UpdatePolicyCtx(ctx context.Context, sec string, ptype string, oldRule, newPolicy []string) (err error) {
+	defer a.observe("UpdatePolicy", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	defer a.changed(ctx, &err, AuditEntry{Op: AuditUpdate, Sec: sec, PType: ptype, Rules: [][]string{newPolicy}, OldRules: [][]string{oldRule}})
+
+	if err := a.checkUpdateRules(ptype, [][]string{oldRule}, [][]string{newPolicy}); err != nil {
+		return err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
+	if a.perPType() {
+		return a.setUpdatePolicies(ptype, [][]string{oldRule}, [][]string{newPolicy})
+	}
+	if a.layout == StreamLayout {
+		return a.streamUpdatePolicies(ptype, [][]string{oldRule}, [][]string{newPolicy})
+	}
+
 	oldLine := savePolicyLine(ptype, oldRule)
-	textOld, err := json.Marshal(oldLine)
+	textOld, err := a.marshal(oldLine)
 	if err != nil {
 		return err
 	}
 	newLine := savePolicyLine(ptype, newPolicy)
-	textNew, err := json.Marshal(newLine)
+	textNew, err := a.marshal(newLine)
 	if err != nil {
 		return err
 	}
 
-	var getScript = redis.NewScript(1, `
-		local key = KEYS[1]
-		local old = ARGV[1]
-		local newRule = ARGV[2]
-	
-		local r = redis.call('lrange', key, 0, -1)
-		for i=1,#r do
-			if r[i] == old then
-				redis.call('lset', key, i-1, newRule)
-				return true
-			end
-		end
-		return false
-	`)
-
-	conn := a.getConn()
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
 	defer a.release(conn)
 
-	_, err = getScript.Do(conn, a.key, textOld, textNew)
-	return err
+	if a.lookupStoredForms() {
+		forms, err := a.storedForms(conn, [][]byte{textOld})
+		if err != nil {
+			return err
+		}
+		textOld = forms[0]
+	}
+	if a.disableLua {
+		err = a.rewriteUpdate(conn, []string{string(textOld)}, []string{string(textNew)}, true)
+	} else {
+		err = a.backend.Replace(conn, a.key, [][]byte{textOld}, [][]byte{textNew})
+	}
+	if err != nil {
+		return err
+	}
+	return a.carryCreated(conn, [][]byte{textOld}, [][]byte{textNew})
 }
 
+// UpdatePolicies updates policy rules in the storage.
 func (a *Adapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return a.UpdatePoliciesCtx(context.Background(), sec, ptype, oldRules, newRules)
+}
+
+// UpdatePoliciesCtx is UpdatePolicies with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) UpdatePoliciesCtx(ctx context.Context, sec string, ptype string, oldRules, newRules [][]string) (err error) {
+	defer a.observe("UpdatePolicies", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	defer a.changed(ctx, &err, AuditEntry{Op: AuditUpdate, Sec: sec, PType: ptype, Rules: newRules, OldRules: oldRules})
+
+	if err := a.checkUpdateRules(ptype, oldRules, newRules); err != nil {
+		return err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
 
 	if len(oldRules) != len(newRules) {
 		return errors.New("oldRules and newRules should have the same length")
 	}
+	if a.perPType() {
+		return a.setUpdatePolicies(ptype, oldRules, newRules)
+	}
+	if a.layout == StreamLayout {
+		return a.streamUpdatePolicies(ptype, oldRules, newRules)
+	}
 
 	oldPolicies := make([]string, 0, len(oldRules))
 	newPolicies := make([]string, 0, len(newRules))
 	for _, oldRule := range oldRules {
-		textOld, err := json.Marshal(savePolicyLine(ptype, oldRule))
+		textOld, err := a.marshal(savePolicyLine(ptype, oldRule))
 		if err != nil {
 			return err
 		}
 		oldPolicies = append(oldPolicies, string(textOld))
 	}
 	for _, newRule := range newRules {
-		textNew, err := json.Marshal(savePolicyLine(ptype, newRule))
+		textNew, err := a.marshal(savePolicyLine(ptype, newRule))
 		if err != nil {
 			return err
 		}
 		newPolicies = append(newPolicies, string(textNew))
 	}
 
-	// Initialize a package-level variable with a script.
-	var getScript = redis.NewScript(1, `
-		local key = KEYS[1]
-		local len = #ARGV/2
-		
-		local map = {}
-		for i = 1, len, 1 do
-			map[ARGV[i]] = ARGV[i + len] -- map[oldRule] = newRule
-		end
-		
-		local r = redis.call('lrange', key, 0, -1)
-		for i=1,#r do
-			if map[r[i]] ~= nil then
-				redis.call('lset', key, i-1, map[r[i]])
-				-- return true
-			end
-		end
-		
-		return false
-	`)
-	args := redis.Args{}.Add(a.key).AddFlat(oldPolicies).AddFlat(newPolicies)
-
-	conn := a.getConn()
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
 	defer a.release(conn)
 
-	_, err := getScript.Do(conn, args...)
-	return err
+	if a.lookupStoredForms() {
+		forms, err := a.storedForms(conn, stringsToBytes(oldPolicies))
+		if err != nil {
+			return err
+		}
+		for i, form := range forms {
+			oldPolicies[i] = string(form)
+		}
+	}
+	batches, err := a.updateBatches(oldPolicies, newPolicies)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case a.disableLua:
+		err = a.rewriteUpdate(conn, oldPolicies, newPolicies, false)
+	case len(batches) > 1 && a.plainList():
+		err = a.updateInBatches(conn, updateAllScript, batches, oldPolicies, newPolicies)
+	default:
+		err = a.backend.Replace(conn, a.key, stringsToBytes(oldPolicies), stringsToBytes(newPolicies))
+	}
+	if err != nil {
+		return err
+	}
+	return a.carryCreated(conn, stringsToBytes(oldPolicies), stringsToBytes(newPolicies))
 }
 
+// UpdateFilteredPolicies replaces the policy rules that match the filter with new rules
+// in the storage, and returns the replaced rules.
 func (a *Adapter) UpdateFilteredPolicies(sec string, ptype string, newPolicies [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	return a.UpdateFilteredPoliciesCtx(context.Background(), sec, ptype, newPolicies, fieldIndex, fieldValues...)
+}
+
+// UpdateFilteredPoliciesCtx is UpdateFilteredPolicies with a context carrying the actor of the change, see
+// WithActor. The context also bounds waiting for the write rate limit.
+func (a *Adapter) UpdateFilteredPoliciesCtx(ctx context.Context, sec string, ptype string, newPolicies [][]string, fieldIndex int, fieldValues ...string) (oldRules [][]string, err error) {
+	defer a.observe("UpdateFilteredPolicies", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+	defer func() {
+		a.changed(ctx, &err, AuditEntry{Op: AuditUpdateFiltered, Sec: sec, PType: ptype, Rules: newPolicies, OldRules: oldRules, FieldIndex: fieldIndex, FieldValues: append([]string{}, fieldValues...)})
+	}()
+
+	if err := a.checkStoredRules(ptype, newPolicies); err != nil {
+		return nil, err
+	}
+	if err := a.checkFieldValues(fieldValues...); err != nil {
+		return nil, err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return nil, err
+	}
+	if a.perPType() {
+		return a.setUpdateFilteredPolicies(sec, ptype, newPolicies, fieldIndex, fieldValues...)
+	}
+	if a.layout == StreamLayout {
+		return a.streamUpdateFilteredPolicies(ptype, newPolicies, fieldIndex, fieldValues...)
+	}
+
 	// UpdateFilteredPolicies deletes old rules and adds new rules.
 
-	oldP := make([]string, 0)
 	newP := make([]string, 0, len(newPolicies))
 	for _, newRule := range newPolicies {
-		textNew, err := json.Marshal(savePolicyLine(ptype, newRule))
+		textNew, err := a.marshal(savePolicyLine(ptype, newRule))
 		if err != nil {
 			return nil, err
 		}
 		newP = append(newP, string(textNew))
 	}
 
-	pattern := filterFieldToLuaPattern(sec, ptype, fieldIndex, fieldValues...)
-
-	// Initialize a package-level variable with a script.
-	var getScript = redis.NewScript(1, `
-		local key = KEYS[1]
-		local pattern = ARGV[1]
-		
-		local ret = {}
-		local r = redis.call('lrange', key, 0, -1)
-		for i=1, #r do 
-			if  string.find(r[i], pattern) then
-        		table.insert(ret, r[i])
-				redis.call('lset', key, i-1, '__CASBIN_DELETED__')
-			end
-		end
-		redis.call('lrem', key, 0, '__CASBIN_DELETED__')
-		
-		local r = redis.call('lrange', key, 0, -1)
-		for i=2,#r do
-			redis.call('rpush', key, ARGV[i])
-		end
-		
-		return ret
-	`)
-	args := redis.Args{}.Add(a.key).Add(pattern).AddFlat(newP)
-	//r, err := getScript.Do(a.conn, args...)
-	//reply, err := redis.Values(r, err)
-
-	conn := a.getConn()
-	defer a.release(conn)
-
-	reply, err := redis.Values(getScript.Do(conn, args...))
+	conn, err := a.getConnCtx(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer a.release(conn)
 
-	if err = redis.ScanSlice(reply, &oldP); err != nil {
+	if a.disableLua || a.trackOrder {
+		lines, err := a.updateFilteredDecoded(conn, ptype, newP, fieldIndex, fieldValues...)
+		if err != nil {
+			return nil, err
+		}
+		ret := make([][]string, 0, len(lines))
+		for _, line := range lines {
+			ret = append(ret, line.toStringPolicy())
+		}
+		return ret, nil
+	}
+
+	var oldP [][]byte
+	if a.cjsonMatching {
+		cond, err := filterFieldConditions(a.jsonKeys, ptype, fieldIndex, fieldValues...)
+		if err != nil {
+			return nil, err
+		}
+		args := redis.Args{}.Add(a.key).Add(cond).AddFlat(newP)
+		oldP, err = redis.ByteSlices(a.doScript(updateFilteredCJSONScript, conn, args...))
+	} else {
+		match := a.ruleMatch(sec, ptype, fieldIndex, fieldValues...)
+		oldP, err = a.backend.ReplaceMatching(conn, a.key, match, stringsToBytes(newP))
+	}
+	if err != nil {
 		return nil, err
 	}
 
 	ret := make([][]string, 0, len(oldP))
 	for _, oldRule := range oldP {
 		var line CasbinRule
-		if err := json.Unmarshal([]byte(oldRule), &line); err != nil {
+		if err := a.unmarshal(oldRule, &line); err != nil {
 			return nil, err
 		}
 
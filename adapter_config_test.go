@@ -15,56 +15,20 @@
 package redisadapter
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/gomodule/redigo/redis"
 )
 
-func TestNewAdapterWithConfig(t *testing.T) {
-	// Test basic configuration
-	config := &Config{
-		Network: "tcp",
-		Address: "127.0.0.1:6379",
-	}
-	a, _ := NewAdapter(config)
-
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
-}
-
-func TestNewAdapterWithPool(t *testing.T) {
-	// Test with connection pool
-	pool := &redis.Pool{
-		MaxIdle:   3,
-		MaxActive: 5,
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", "127.0.0.1:6379")
-		},
-	}
-	config := &Config{
-		Pool: pool,
-		Key:  "pool_test_rules",
-	}
-	a, err := NewAdapter(config)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
-}
-
 func TestNewAdapterErrorCases(t *testing.T) {
 	// Test error cases
 	_, err := NewAdapter(nil)
@@ -91,52 +55,248 @@ func TestNewAdapterErrorCases(t *testing.T) {
 	}
 }
 
-func TestNewAdapterWithPassword(t *testing.T) {
-	// Test with password authentication
-	a, err := NewAdapterWithPassword("tcp", "127.0.0.1:6379", "testpass")
+func TestNewAdapterWithKeyPrefix(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_prefixed", KeyPrefix: "test-env:", SoftDelete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.key != "test-env:casbin_rules_prefixed" {
+		t.Fatalf("key = %q, supposed to be prefixed", a.key)
+	}
+
+	conn, err := a.getConn()
 	if err != nil {
-		t.Skipf("Password authentication test skipped (Redis may not have auth configured): %v", err)
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	if _, err = conn.Do("DEL", "casbin_rules_prefixed", "casbin_rules_prefixed:deleted"); err != nil {
+		t.Fatal(err)
 	}
 
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
+	initPolicy(t, a)
+	if err = a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	isLeader, _, release, err := a.AcquireLeadership(context.Background(), time.Second)
+	if err != nil || !isLeader {
+		t.Fatalf("AcquireLeadership() = %v, %v", isLeader, err)
+	}
+	defer release()
+
+	for key, want := range map[string]bool{
+		"test-env:casbin_rules_prefixed":         true,
+		"test-env:casbin_rules_prefixed:deleted": true,
+		"test-env:casbin_rules_prefixed:leader":  true,
+		"casbin_rules_prefixed":                  false,
+		"casbin_rules_prefixed:deleted":          false,
+		"casbin_rules_prefixed:leader":           false,
+	} {
+		exists, err := redis.Bool(conn.Do("EXISTS", key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != want {
+			t.Errorf("EXISTS %s = %v, supposed to be %v", key, exists, want)
+		}
+	}
 }
 
-func TestNewAdapterWithUser(t *testing.T) {
-	// Test with username and password authentication
-	a, err := NewAdapterWithUser("tcp", "127.0.0.1:6379", "testuser", "testpass")
+func TestNewAdapterWithKeys(t *testing.T) {
+	domains := map[string][][]string{
+		"casbin_rules_domain1": {{"alice", "data1", "read"}},
+		"casbin_rules_domain2": {{"bob", "data2", "write"}},
+		"casbin_rules_domain3": {{"carol", "data3", "read"}},
+	}
+	for key, rules := range domains {
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+		e.ClearPolicy()
+		e.AddPolicies(rules)
+		if err = a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := NewAdapter(&Config{
+		Network: "tcp",
+		Address: "127.0.0.1:6379",
+		Key:     "casbin_rules_domain1",
+		Keys:    []string{"casbin_rules_domain1", "casbin_rules_domain2", "casbin_rules_domain3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
 	if err != nil {
-		t.Skipf("User authentication test skipped (Redis may not have auth configured): %v", err)
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}})
+
+	if err = e.LoadFilteredPolicy(&Filter{V2: []string{"read"}}); err != nil {
+		t.Fatal(err)
 	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"carol", "data3", "read"}})
 
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
+	// Writes go to the primary key only.
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.AddPolicy("dave", "data4", "write"); err != nil {
+		t.Fatal(err)
+	}
+	primary, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_domain1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, _ = casbin.NewEnforcer("examples/rbac_model.conf", primary)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"dave", "data4", "write"}})
+
+	if _, err = NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Keys: []string{"a", "b"}, Layout: PTypeSetLayout}); err == nil {
+		t.Error("NewAdapter() with Keys and PTypeSetLayout succeeded, supposed to fail")
+	}
 }
 
-func TestNewAdapterWithKey(t *testing.T) {
-	// Test with custom key
-	a, err := NewAdapterWithKey("tcp", "127.0.0.1:6379", "custom_casbin_rules")
+func TestLoadConcurrency(t *testing.T) {
+	var keys []string
+	var want [][]string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("casbin_rules_concurrency%d", i)
+		rule := []string{fmt.Sprintf("user%d", i), "data", "read"}
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+		e.ClearPolicy()
+		e.AddPolicy(rule)
+		if err = a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		want = append(want, rule)
+	}
+
+	pool := &redis.Pool{
+		MaxIdle: 10,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", "127.0.0.1:6379")
+		},
+	}
+	defer pool.Close()
+	// Every connection is taken from the budget, so its use bounds the
+	// connections loading keys at once.
+	budget := NewConnBudget(100)
+	var mu sync.Mutex
+	maxInUse := 0
+	a, err := NewAdapter(&Config{
+		Pool:            pool,
+		Key:             keys[0],
+		Keys:            keys[1:],
+		LoadConcurrency: 4,
+		ConnBudget:      budget,
+		CommandHook: func(cmd string, args []interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			if n := budget.InUse(); n > maxInUse {
+				maxInUse = n
+			}
+		},
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rules are merged in the order of the keys.
+	testGetPolicy(t, e, want)
+	if maxInUse < 2 || maxInUse > 4 {
+		t.Errorf("%d connections were in use at once, supposed to be 2 to 4", maxInUse)
+	}
+}
+
+func TestPoolWait(t *testing.T) {
+	newSaturated := func(config *Config, wait bool) (*Adapter, redis.Conn) {
+		config.Pool = &redis.Pool{
+			MaxActive: 1,
+			Wait:      wait,
+			Dial:      func() (redis.Conn, error) { return noScriptConn{}, nil },
+		}
+		a, err := NewAdapter(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		held, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a, held
+	}
+
+	// Without PoolWait, operations fail fast.
+	a, held := newSaturated(&Config{}, false)
+	if _, err := a.Version(); !errors.Is(err, redis.ErrPoolExhausted) {
+		t.Errorf("Version() with a saturated pool = %v, supposed to be redis.ErrPoolExhausted", err)
+	}
+	a.release(held)
+
+	// With Pool.Wait, they wait up to PoolWaitTimeout.
+	a, held = newSaturated(&Config{PoolWaitTimeout: 50 * time.Millisecond}, true)
+	start := time.Now()
+	if _, err := a.Version(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Version() with a saturated pool = %v, supposed to be context.DeadlineExceeded", err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("Version() failed after %v, supposed to wait 50ms", waited)
+	}
 
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
+	// The context of Ctx methods bounds the wait too.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := a.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AddPolicyCtx() with a saturated pool = %v, supposed to be context.DeadlineExceeded", err)
+	}
+
+	// A connection released while waiting is handed over.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		a.release(held)
+	}()
+	if _, err := a.Version(); err != nil {
+		t.Errorf("Version() after a connection was released = %v", err)
+	}
+}
+
+func TestMaxConnLifetime(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", MaxConnLifetime: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientID := func() int64 {
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer a.release(conn)
+		id, err := redis.Int64(conn.Do("CLIENT", "ID"))
+		if err != nil {
+			t.Skipf("CLIENT ID is not supported: %v", err)
+		}
+		return id
+	}
+
+	first := clientID()
+	if id := clientID(); id != first {
+		t.Errorf("connection was recycled before its lifetime: client %d, then %d", first, id)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if id := clientID(); id == first {
+		t.Error("connection was not recycled after its lifetime")
+	}
 }
 
 func TestFilterFunctionality(t *testing.T) {
@@ -175,3 +335,286 @@ func TestFilterFunctionality(t *testing.T) {
 	policies = e.GetPolicy()
 	t.Logf("Found %d policies for data1", len(policies))
 }
+
+func TestWarmPool(t *testing.T) {
+	pool := &redis.Pool{
+		MaxIdle:   10,
+		MaxActive: 4,
+		Dial:      func() (redis.Conn, error) { return noScriptConn{}, nil },
+	}
+	a, err := NewAdapter(&Config{Pool: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.WarmPool(3); err != nil {
+		t.Fatal(err)
+	}
+	if idle := pool.IdleCount(); idle != 3 {
+		t.Errorf("IdleCount() after WarmPool(3) = %d, supposed to be 3", idle)
+	}
+
+	// MaxActive bounds the connections opened.
+	if err := a.WarmPool(10); err != nil {
+		t.Fatal(err)
+	}
+	if idle := pool.IdleCount(); idle != 4 {
+		t.Errorf("IdleCount() after WarmPool(10) = %d, supposed to be MaxActive 4", idle)
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	// 10.255.255.1 is not routable, so dialing it hangs until the connect
+	// timeout. Networks whose proxy accepts any connection hang on the first
+	// reply instead, until the read timeout.
+	unreachable := func(newAdapter func() (*Adapter, error)) {
+		t.Helper()
+		start := time.Now()
+		a, err := newAdapter()
+		if err == nil {
+			_, err = a.Version()
+		}
+		if err == nil {
+			t.Fatal("the adapter reached an unreachable address")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("the adapter failed after %v, supposed to give up after the 200ms timeouts", elapsed)
+		}
+	}
+	unreachable(func() (*Adapter, error) {
+		return NewAdapter(&Config{Network: "tcp", Address: "10.255.255.1:6379", ConnectTimeout: 200 * time.Millisecond, ReadTimeout: 200 * time.Millisecond})
+	})
+	unreachable(func() (*Adapter, error) {
+		return NewAdapterWithOption(WithNetwork("tcp"), WithAddress("10.255.255.1:6379"), WithTimeouts(200*time.Millisecond, 200*time.Millisecond, time.Second))
+	})
+}
+
+func TestReadTimeout(t *testing.T) {
+	// The listener accepts connections but never replies.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	a, err := NewAdapter(&Config{Network: "tcp", Address: l.Addr().String(), ReadTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err = a.Version(); err == nil {
+		t.Fatal("Version() without a reply succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Version() failed after %v, supposed to give up after the 100ms read timeout", elapsed)
+	}
+}
+
+func TestInternalPool(t *testing.T) {
+	// The listener accepts the first connection NewAdapter dials.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	a, err := NewAdapter(&Config{Network: "tcp", Address: l.Addr().String(), MaxIdle: 3, MaxActive: 5, IdleTimeout: time.Minute, PoolWait: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a._pool == nil {
+		t.Fatal("NewAdapter() without Pool did not create a pool")
+	}
+	if p := a._pool; p.MaxIdle != 3 || p.MaxActive != 5 || p.IdleTimeout != time.Minute || !p.Wait {
+		t.Errorf("pool = MaxIdle %d, MaxActive %d, IdleTimeout %v, Wait %v, supposed to follow the config", p.MaxIdle, p.MaxActive, p.IdleTimeout, p.Wait)
+	}
+	if idle := a._pool.IdleCount(); idle != 1 {
+		t.Errorf("IdleCount() = %d, supposed to keep the connection dialed by NewAdapter", idle)
+	}
+
+	a, err = NewAdapter(&Config{Network: "tcp", Address: l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := a._pool; p.MaxIdle != defaultMaxIdle || p.MaxActive != 0 || p.Wait {
+		t.Errorf("pool = MaxIdle %d, MaxActive %d, Wait %v, supposed to be the defaults", p.MaxIdle, p.MaxActive, p.Wait)
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_concurrent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+
+	const writers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := a.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data", "read"}); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent AddPolicy(): %v", err)
+	}
+
+	grouped, err := a.GetAllGrouped()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(grouped["p"]); n != writers {
+		t.Errorf("%d rules stored by %d concurrent writers", n, writers)
+	}
+}
+
+func TestCredentialsProvider(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var mu sync.Mutex
+	var auths [][]string
+	go serveNil(l, func(args []string) {
+		if args[0] == "AUTH" {
+			mu.Lock()
+			auths = append(auths, args[1:])
+			mu.Unlock()
+		}
+	})
+
+	calls := 0
+	a, err := NewAdapter(&Config{
+		Network: "tcp",
+		Address: l.Addr().String(),
+		// Connections are redialed after 50ms, when the token rotated.
+		MaxConnLifetime: 50 * time.Millisecond,
+		CredentialsProvider: func() (string, string, error) {
+			calls++
+			return "casbin", fmt.Sprintf("token-%d", calls), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err = a.Version(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	want := [][]string{{"casbin", "token-1"}, {"casbin", "token-2"}}
+	if !reflect.DeepEqual(auths, want) {
+		t.Errorf("AUTH sent %v, supposed to be %v", auths, want)
+	}
+	mu.Unlock()
+
+	errExpired := errors.New("token expired")
+	_, err = NewAdapter(&Config{
+		Network:             "tcp",
+		Address:             l.Addr().String(),
+		CredentialsProvider: func() (string, string, error) { return "", "", errExpired },
+	})
+	if !errors.Is(err, errExpired) {
+		t.Errorf("NewAdapter() with a failing provider = %v, supposed to wrap its error", err)
+	}
+}
+
+func TestClientName(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", ClientName: "casbin-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	conn := a._pool.Get()
+	defer conn.Close()
+	name, err := redis.String(conn.Do("CLIENT", "GETNAME"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "casbin-test" {
+		t.Errorf("CLIENT GETNAME = %q, supposed to be %q", name, "casbin-test")
+	}
+}
+
+func TestClientNameRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// Like a managed server that disables CLIENT.
+	go serve(l, func(args []string) string {
+		if args[0] == "CLIENT" {
+			return "-ERR unknown command 'CLIENT'\r\n"
+		}
+		return "$-1\r\n"
+	})
+
+	logger := &recordingLogger{}
+	a, err := NewAdapter(&Config{Network: "tcp", Address: l.Addr().String(), ClientName: "casbin", Logger: logger})
+	if err != nil {
+		t.Fatalf("NewAdapter() = %v, supposed to ignore the refused client name", err)
+	}
+	a.Close()
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "unknown command") {
+		t.Errorf("logged %q, supposed to log the refused client name", logger.messages)
+	}
+
+	_, err = NewAdapter(&Config{Network: "tcp", Address: l.Addr().String(), ClientName: "casbin", StrictClientName: true})
+	if err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("NewAdapter() with StrictClientName = %v, supposed to fail", err)
+	}
+}
+
+func TestDialFunc(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveNil(l, nil)
+
+	var mu sync.Mutex
+	dials := 0
+	a, err := NewAdapter(&Config{
+		DialFunc: func() (redis.Conn, error) {
+			mu.Lock()
+			dials++
+			mu.Unlock()
+			return redis.Dial("tcp", l.Addr().String())
+		},
+		MaxIdle: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	// Two connections at once, one more than the idle ones kept.
+	conn1, conn2 := a._pool.Get(), a._pool.Get()
+	for _, conn := range []redis.Conn{conn1, conn2} {
+		if _, err = conn.Do("PING"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn1.Close()
+	conn2.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if dials != 2 {
+		t.Errorf("DialFunc called %d times, supposed to be called for each of the 2 connections", dials)
+	}
+
+	_, err = NewAdapter(&Config{DialFunc: func() (redis.Conn, error) { return nil, nil }, Address: "127.0.0.1:6379", Password: "secret"})
+	if !errors.Is(err, ErrIgnoredOption) || !strings.Contains(err.Error(), "Password is ignored with DialFunc") {
+		t.Errorf("NewAdapter() with DialFunc and Password = %v, supposed to report Password ignored", err)
+	}
+}
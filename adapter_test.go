@@ -15,11 +15,17 @@
 package redisadapter
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/util"
 	"github.com/gomodule/redigo/redis"
 )
@@ -69,356 +75,624 @@ func initPolicy(t *testing.T, a *Adapter) {
 	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
 }
 
-func testSaveLoad(t *testing.T, a *Adapter) {
-	// Initialize some policy in DB.
-	initPolicy(t, a)
-	// Note: you don't need to look at the above code
-	// if you already have a working DB with policy inside.
+func testGetPolicyWithoutOrder(t *testing.T, e *casbin.Enforcer, res [][]string) {
+	myRes := e.GetPolicy()
+	log.Print("Policy: ", myRes)
 
-	// Now the DB has policy, so we can provide a normal use case.
-	// Create an adapter and an enforcer.
-	// NewEnforcer() will load the policy automatically.
-	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
-	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if !arrayEqualsWithoutOrder(myRes, res) {
+		t.Error("Policy: ", myRes, ", supposed to be ", res)
+	}
 }
 
-func testAutoSave(t *testing.T, a *Adapter) {
-	// Initialize some policy in DB.
-	initPolicy(t, a)
-	// Note: you don't need to look at the above code
-	// if you already have a working DB with policy inside.
+func arrayEqualsWithoutOrder(a [][]string, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
 
-	// Now the DB has policy, so we can provide a normal use case.
-	// Create an adapter and an enforcer.
-	// NewEnforcer() will load the policy automatically.
-	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	mapA := make(map[int]string)
+	mapB := make(map[int]string)
+	order := make(map[int]struct{})
+	l := len(a)
 
-	// AutoSave is enabled by default.
-	// Now we disable it.
-	e.EnableAutoSave(false)
+	for i := 0; i < l; i++ {
+		mapA[i] = util.ArrayToString(a[i])
+		mapB[i] = util.ArrayToString(b[i])
+	}
 
-	var err error
-	logErr := func(action string) {
-		if err != nil {
-			t.Fatalf("test action[%s] failed, err: %v", action, err)
+	for i := 0; i < l; i++ {
+		for j := 0; j < l; j++ {
+			if _, ok := order[j]; ok {
+				if j == l-1 {
+					return false
+				} else {
+					continue
+				}
+			}
+			if mapA[i] == mapB[j] {
+				order[j] = struct{}{}
+				break
+			} else if j == l-1 {
+				return false
+			}
 		}
 	}
+	return true
+}
 
-	// Because AutoSave is disabled, the policy change only affects the policy in Casbin enforcer,
-	// it doesn't affect the policy in the storage.
-	_, err = e.AddPolicy("alice", "data1", "write")
-	logErr("AddPolicy")
-	// Reload the policy from the storage to see the effect.
-	err = e.LoadPolicy()
-	logErr("LoadPolicy")
-	// This is still the original policy.
-	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+func TestSingleScanRemoval(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_single_scan", SingleScanRemoval: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Like LREM with a count of 1, a rule stored twice is removed once per occurrence given.
+	initPolicy(t, a)
+	if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"alice", "data1", "read"}})
+}
 
-	// Now we enable the AutoSave.
-	e.EnableAutoSave(true)
-
-	// Because AutoSave is enabled, the policy change not only affects the policy in Casbin enforcer,
-	// but also affects the policy in the storage.
-	_, err = e.AddPolicy("alice", "data1", "write")
-	logErr("AddPolicy2")
-	// Reload the policy from the storage to see the effect.
-	err = e.LoadPolicy()
-	logErr("LoadPolicy2")
-	// The policy has a new rule: {"alice", "data1", "write"}.
-	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"alice", "data1", "write"}})
-
-	// Remove the added rule.
-	_, err = e.RemovePolicy("alice", "data1", "write")
-	logErr("RemovePolicy")
-	err = e.LoadPolicy()
-	logErr("LoadPolicy3")
-	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+func TestUpdatePreservesOrder(t *testing.T) {
+	for _, encoding := range []Encoding{JSONEncoding, GobEncoding} {
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_update_order", Encoding: encoding})
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	// Remove "data2_admin" related policy rules via a filter.
-	// Two rules: {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"} are deleted.
-	_, err = e.RemoveFilteredPolicy(0, "data2_admin")
-	logErr("RemoveFilteredPolicy")
-	err = e.LoadPolicy()
-	logErr("LoadPolicy4")
+		// Same cardinality: the rules are replaced in place.
+		initPolicy(t, a)
+		if err = a.UpdatePolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}}, [][]string{{"alice", "data1", "write"}, {"data2_admin", "data2", "list"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err = a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data3", "write"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = a.UpdateFilteredPolicies("p", "p", [][]string{{"data2_admin", "data4", "read"}, {"data2_admin", "data4", "write"}}, 0, "data2_admin"); err != nil {
+			t.Fatal(err)
+		}
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicy(t, e, [][]string{{"alice", "data1", "write"}, {"bob", "data3", "write"}, {"data2_admin", "data4", "read"}, {"data2_admin", "data4", "write"}})
+
+		// Growing: the surplus is appended.
+		initPolicy(t, a)
+		if _, err = a.UpdateFilteredPolicies("p", "p", [][]string{{"alice", "data1", "write"}, {"alice", "data5", "read"}}, 0, "alice"); err != nil {
+			t.Fatal(err)
+		}
+		e, _ = casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicy(t, e, [][]string{{"alice", "data1", "write"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"alice", "data5", "read"}})
 
-	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+		// Shrinking: the first match is replaced, the others are removed.
+		initPolicy(t, a)
+		if _, err = a.UpdateFilteredPolicies("p", "p", [][]string{{"carol", "data2", "read"}}, 1, "data2"); err != nil {
+			t.Fatal(err)
+		}
+		e, _ = casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"carol", "data2", "read"}})
+	}
 }
 
-func testFilteredPolicy(t *testing.T, a *Adapter) {
-	// Initialize some policy in DB.
+func TestGetAllGrouped(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_grouped"})
+	if err != nil {
+		t.Fatal(err)
+	}
 	initPolicy(t, a)
-	// Note: you don't need to look at the above code
-	// if you already have a working DB with policy inside.
 
-	// Now the DB has policy, so we can provide a normal use case.
-	// Create an adapter and an enforcer.
-	// NewEnforcer() will load the policy automatically.
-	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
-	// Now set the adapter
-	e.SetAdapter(a)
+	if err = a.AddPolicy("p", "p2", []string{"carol", "data3", "read", "deny"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("g", "g2", []string{"data3", "data_group"}); err != nil {
+		t.Fatal(err)
+	}
 
-	var err error
-	logErr := func(action string) {
-		if err != nil {
-			t.Fatalf("test action[%s] failed, err: %v", action, err)
+	grouped, err := a.GetAllGrouped()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][][]string{
+		"p":  {{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}},
+		"g":  {{"alice", "data2_admin"}},
+		"p2": {{"carol", "data3", "read", "deny"}},
+		"g2": {{"data3", "data_group"}},
+	}
+	if len(grouped) != len(expected) {
+		t.Fatalf("GetAllGrouped() = %v, supposed to be %v", grouped, expected)
+	}
+	for ptype, rules := range expected {
+		if !arrayEqualsWithoutOrder(grouped[ptype], rules) {
+			t.Errorf("GetAllGrouped()[%q] = %v, supposed to be %v", ptype, grouped[ptype], rules)
 		}
 	}
+}
 
-	// Load only alice's policies
-	err = e.LoadFilteredPolicy(Filter{V0: []string{"alice"}})
-	logErr("LoadFilteredPolicy")
-	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+// brokenConn is a connection that failed, e.g. because the server was down when it was dialed.
+type brokenConn struct{}
 
-	// Load only bob's policies
-	err = e.LoadFilteredPolicy(Filter{V0: []string{"bob"}})
-	logErr("LoadFilteredPolicy2")
-	testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}})
+var errBrokenConn = errors.New("broken connection")
 
-	// Load policies for data2_admin
-	err = e.LoadFilteredPolicy(Filter{V0: []string{"data2_admin"}})
-	logErr("LoadFilteredPolicy3")
-	testGetPolicy(t, e, [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+func (brokenConn) Close() error                                   { return nil }
+func (brokenConn) Err() error                                     { return errBrokenConn }
+func (brokenConn) Do(string, ...interface{}) (interface{}, error) { return nil, errBrokenConn }
+func (brokenConn) Send(string, ...interface{}) error              { return errBrokenConn }
+func (brokenConn) Flush() error                                   { return errBrokenConn }
+func (brokenConn) Receive() (interface{}, error)                  { return nil, errBrokenConn }
 
-	// Load policies for alice and bob
-	err = e.LoadFilteredPolicy(Filter{V0: []string{"alice", "bob"}})
-	logErr("LoadFilteredPolicy4")
-	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
-}
+func TestPoolBrokenConnection(t *testing.T) {
+	dials := 0
+	a, err := NewAdapterWithPool(&redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			dials++
+			// The first connection is broken, the next ones are good.
+			if dials == 1 {
+				return brokenConn{}, nil
+			}
+			return redis.Dial("tcp", "127.0.0.1:6379")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-func testRemovePolicies(t *testing.T, a *Adapter) {
-	// Initialize some policy in DB.
 	initPolicy(t, a)
-	// Note: you don't need to look at the above code
-	// if you already have a working DB with policy inside.
+	if dials != 2 {
+		t.Errorf("dialed %d connections, supposed to be 2", dials)
+	}
 
-	// Now the DB has policy, so we can provide a normal use case.
-	// Create an adapter and an enforcer.
-	// NewEnforcer() will load the policy automatically.
-	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	// A pool yielding nothing but broken connections fails after a bounded number of attempts.
+	dials = 0
+	a, err = NewAdapterWithPool(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			dials++
+			return brokenConn{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != errBrokenConn {
+		t.Errorf("AddPolicy with broken connections returned %v, supposed to be %v", err, errBrokenConn)
+	}
+	if dials != maxConnAttempts {
+		t.Errorf("dialed %d connections, supposed to be %d", dials, maxConnAttempts)
+	}
+}
 
-	// Now set the adapter
-	e.SetAdapter(a)
+func TestFilterFieldValuesRoundTrip(t *testing.T) {
+	cases := []struct {
+		fieldIndex  int
+		fieldValues []string
+		filter      Filter
+	}{
+		{0, nil, Filter{}},
+		{0, []string{"alice"}, Filter{V0: []string{"alice"}}},
+		{1, []string{"data1", "read"}, Filter{V1: []string{"data1"}, V2: []string{"read"}}},
+		{3, []string{"a", "b", "c"}, Filter{V3: []string{"a"}, V4: []string{"b"}, V5: []string{"c"}}},
+	}
 
-	var err error
-	logErr := func(action string) {
-		if err != nil {
-			t.Fatalf("test action[%s] failed, err: %v", action, err)
+	for _, c := range cases {
+		if filter := FilterFromFieldValues(c.fieldIndex, c.fieldValues...); !reflect.DeepEqual(filter, c.filter) {
+			t.Errorf("FilterFromFieldValues(%d, %q) = %+v, supposed to be %+v", c.fieldIndex, c.fieldValues, filter, c.filter)
 		}
-	}
 
-	err = a.AddPolicies("p", "p", [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}, {"max", "data1", "delete"}})
-	logErr("AddPolicies")
+		fieldIndex, fieldValues := c.filter.ToFieldValues()
+		if fieldIndex != c.fieldIndex || !reflect.DeepEqual(fieldValues, c.fieldValues) {
+			t.Errorf("%+v.ToFieldValues() = (%d, %q), supposed to be (%d, %q)", c.filter, fieldIndex, fieldValues, c.fieldIndex, c.fieldValues)
+		}
+	}
 
-	// Load policies for max
-	err = e.LoadFilteredPolicy(Filter{V0: []string{"max"}})
-	logErr("LoadFilteredPolicy")
+	// Leading and trailing wildcards are dropped by the round trip.
+	fieldIndex, fieldValues := FilterFromFieldValues(0, "", "data1", "").ToFieldValues()
+	if fieldIndex != 1 || !reflect.DeepEqual(fieldValues, []string{"data1"}) {
+		t.Errorf("round trip of (0, \"\", \"data1\", \"\") = (%d, %q), supposed to be (1, [\"data1\"])", fieldIndex, fieldValues)
+	}
 
-	testGetPolicy(t, e, [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}, {"max", "data1", "delete"}})
+	// Values before V0 or past V5 are left out.
+	if filter := FilterFromFieldValues(5, "deny", "extra"); !reflect.DeepEqual(filter, Filter{V5: []string{"deny"}}) {
+		t.Errorf("FilterFromFieldValues(5, deny, extra) = %+v, supposed to be only V5", filter)
+	}
+	if filter := FilterFromFieldValues(-1, "alice", "data1"); !reflect.DeepEqual(filter, Filter{V0: []string{"data1"}}) {
+		t.Errorf("FilterFromFieldValues(-1, alice, data1) = %+v, supposed to be only V0", filter)
+	}
+}
 
-	// Remove policies
-	err = a.RemovePolicies("p", "p", [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}})
-	logErr("RemovePolicies")
+func TestFilterToFieldValuesNonContiguous(t *testing.T) {
+	cases := []struct {
+		filter Filter
+		err    error
+	}{
+		{FilterFromFieldValues(0, "alice", "", "read"), ErrNonContiguousFields},
+		{Filter{V2: []string{"read"}, V5: []string{"deny"}}, ErrNonContiguousFields},
+		{Filter{V0: []string{"alice", "bob"}}, ErrNotFieldValues},
+		{Filter{V0: []string{"alice"}, V1: []string{"read", "write"}}, ErrNotFieldValues},
+		{Filter{V1: []string{""}}, ErrNotFieldValues},
+	}
 
-	// Reload policies for max
-	err = e.LoadFilteredPolicy(Filter{V0: []string{"max"}})
-	logErr("LoadFilteredPolicy2")
+	for _, c := range cases {
+		if err := c.filter.CheckFieldValues(); !errors.Is(err, c.err) {
+			t.Errorf("%+v.CheckFieldValues() = %v, supposed to be %v", c.filter, err, c.err)
+		}
+		if fieldIndex, fieldValues := c.filter.ToFieldValues(); fieldIndex != -1 || fieldValues != nil {
+			t.Errorf("%+v.ToFieldValues() = (%d, %q), supposed to be (-1, [])", c.filter, fieldIndex, fieldValues)
+		}
+	}
 
-	testGetPolicy(t, e, [][]string{{"max", "data1", "delete"}})
+	// PType is not part of the field values.
+	filter := Filter{PType: []string{"p"}, V0: []string{"alice"}}
+	if err := filter.CheckFieldValues(); err != nil {
+		t.Errorf("CheckFieldValues() with PType = %v, supposed to be nil", err)
+	}
+	if fieldIndex, fieldValues := filter.ToFieldValues(); fieldIndex != 0 || !reflect.DeepEqual(fieldValues, []string{"alice"}) {
+		t.Errorf("ToFieldValues() with PType = (%d, %q), supposed to be (0, [\"alice\"])", fieldIndex, fieldValues)
+	}
 }
 
-func testAddPolicies(t *testing.T, a *Adapter) {
-	// Initialize some policy in DB.
+func TestFilteredPolicyPTypeDefault(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_ptype_default"})
+	if err != nil {
+		t.Fatal(err)
+	}
 	initPolicy(t, a)
-	// Note: you don't need to look at the above code
-	// if you already have a working DB with policy inside.
-
-	// Now the DB has policy, so we can provide a normal use case.
-	// Create an adapter and an enforcer.
-	// NewEnforcer() will load the policy automatically.
 	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
-
-	// Now set the adapter
 	e.SetAdapter(a)
 
-	var err error
-	logErr := func(action string) {
-		if err != nil {
-			t.Fatalf("test action[%s] failed, err: %v", action, err)
-		}
+	// alice also appears in V0 of the grouping rule, which is left out by default.
+	if err = e.LoadFilteredPolicy(&Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+	if g := e.GetGroupingPolicy(); len(g) != 0 {
+		t.Errorf("grouping policy = %v, supposed to be empty without PType", g)
 	}
 
-	err = a.AddPolicies("p", "p", [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}})
-	logErr("AddPolicies")
-
-	// Load policies for max
-	err = e.LoadFilteredPolicy(Filter{V0: []string{"max"}})
-	logErr("LoadFilteredPolicy")
+	if err = e.LoadFilteredPolicy(&Filter{PType: []string{"p", "g"}, V0: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+	if g := e.GetGroupingPolicy(); !util.Array2DEquals([][]string{{"alice", "data2_admin"}}, g) {
+		t.Errorf("grouping policy = %v, supposed to include the rules of the requested ptypes", g)
+	}
 
-	testGetPolicy(t, e, [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}})
+	a, err = NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_ptype_default", FilterAllSections: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetAdapter(a)
+	if err = e.LoadFilteredPolicy(&Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+	if g := e.GetGroupingPolicy(); !util.Array2DEquals([][]string{{"alice", "data2_admin"}}, g) {
+		t.Errorf("grouping policy = %v, supposed to include every section with FilterAllSections", g)
+	}
 }
 
-func testUpdatePolicies(t *testing.T, a *Adapter) {
-	// Initialize some policy in DB.
+func TestSaveEmptyPolicy(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_empty", SaveBatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A batch size smaller than the policy sends it in several batches.
 	initPolicy(t, a)
-	// Note: you don't need to look at the above code
-	// if you already have a working DB with policy inside.
 
-	// Now the DB has policy, so we can provide a normal use case.
-	// Create an adapter and an enforcer.
-	// NewEnforcer() will load the policy automatically.
-	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	e.ClearPolicy()
+	if err = e.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{})
+}
 
-	// Now set the adapter
-	e.SetAdapter(a)
+func TestToStringPolicyTrailingFields(t *testing.T) {
+	tests := []struct {
+		line CasbinRule
+		want []string
+	}{
+		{CasbinRule{PType: "p"}, []string{"p"}},
+		{CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}, []string{"p", "alice", "data1", "read"}},
+		{CasbinRule{PType: "p", V0: "alice", V2: "read"}, []string{"p", "alice", "", "read"}},
+		{CasbinRule{PType: "p", V1: "data1"}, []string{"p", "", "data1"}},
+	}
+	for _, test := range tests {
+		if got := test.line.toStringPolicy(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("toStringPolicy() of %+v = %q, supposed to be %q", test.line, got, test.want)
+		}
+	}
+}
 
-	var err error
-	logErr := func(action string) {
+func TestEmptyFieldRules(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_empty_fields"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+
+	stored := func() int {
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer a.release(conn)
+		n, err := redis.Int(conn.Do("LLEN", a.key))
 		if err != nil {
-			t.Fatalf("test action[%s] failed, err: %v", action, err)
+			t.Fatal(err)
 		}
+		return n
 	}
 
-	err = a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"alice", "data2", "write"})
-	logErr("UpdatePolicy")
+	// Adding and removing a rule with a single empty field targets the same entry,
+	// whichever form of it is used.
+	for _, removed := range [][]string{{""}, {}, {"", ""}} {
+		if err = a.AddPolicy("p", "p", []string{""}); err != nil {
+			t.Fatal(err)
+		}
+		if err = a.RemovePolicy("p", "p", removed); err != nil {
+			t.Fatal(err)
+		}
+		if n := stored(); n != 0 {
+			t.Errorf("%d rules stored after adding [\"\"] and removing %q, supposed to be 0", n, removed)
+		}
+	}
 
-	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"alice", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	// Empty fields between non-empty ones are kept.
+	if err = a.AddPolicies("p", "p", [][]string{{"alice", "", "read"}, {"bob", "data2", ""}}); err != nil {
+		t.Fatal(err)
+	}
+	// Trailing empty fields are loaded up to the tokens of the model.
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "", "read"}, {"bob", "data2", ""}})
 
-	err = a.UpdatePolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"alice", "data2", "write"}}, [][]string{{"bob", "data1", "read"}, {"bob", "data2", "write"}})
-	logErr("UpdatePolicies")
+	if err = a.RemovePolicies("p", "p", [][]string{{"alice", "", "read"}, {"bob", "data2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := stored(); n != 0 {
+		t.Errorf("%d rules stored after removing them, supposed to be 0", n)
+	}
 
-	testGetPolicy(t, e, [][]string{{"bob", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	// A rule the model has no tokens for fails the load instead of being dropped.
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read", "extra"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err == nil {
+		t.Error("LoadPolicy() of a rule with 4 fields succeeded, supposed to fail")
+	}
 }
 
-func testUpdateFilteredPolicies(t *testing.T, a *Adapter) {
-	// Initialize some policy in DB.
-	initPolicy(t, a)
-	// Note: you don't need to look at the above code
-	// if you already have a working DB with policy inside.
-
-	// Now the DB has policy, so we can provide a normal use case.
-	// Create an adapter and an enforcer.
-	// NewEnforcer() will load the policy automatically.
-	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
-
-	// Now set the adapter
-	e.SetAdapter(a)
+// peakHeapGrowth runs f and returns the highest heap growth observed while it ran.
+func peakHeapGrowth(f func()) uint64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	base := ms.HeapAlloc
+
+	var peak uint64
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		var ms runtime.MemStats
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			runtime.ReadMemStats(&ms)
+			if ms.HeapAlloc > base && ms.HeapAlloc-base > peak {
+				peak = ms.HeapAlloc - base
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
 
-	e.UpdateFilteredPolicies([][]string{{"alice", "data1", "write"}}, 0, "alice", "data1", "read")
-	e.UpdateFilteredPolicies([][]string{{"bob", "data2", "read"}}, 0, "bob", "data2", "write")
-	e.LoadPolicy()
-	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"bob", "data2", "read"}})
+	f()
+	close(done)
+	<-sampled
+	return peak
 }
 
-func testGetPolicyWithoutOrder(t *testing.T, e *casbin.Enforcer, res [][]string) {
-	myRes := e.GetPolicy()
-	log.Print("Policy: ", myRes)
+// BenchmarkSavePolicy compares the peak heap growth of SavePolicy for a 100k-rule
+// model with different batch sizes, the largest one sending the policy at once.
+func BenchmarkSavePolicy(b *testing.B) {
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 100000; i++ {
+		rule := []string{fmt.Sprintf("user%d", i), fmt.Sprintf("/data/%d", i), "read"}
+		m["p"]["p"].Policy = append(m["p"]["p"].Policy, rule)
+	}
 
-	if !arrayEqualsWithoutOrder(myRes, res) {
-		t.Error("Policy: ", myRes, ", supposed to be ", res)
+	for _, size := range []int{100, 1000, 1000000} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_bench", SaveBatchSize: size})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				growth := peakHeapGrowth(func() {
+					if err := a.SavePolicy(m); err != nil {
+						b.Fatal(err)
+					}
+				})
+				if growth > peak {
+					peak = growth
+				}
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+		})
 	}
 }
 
-func arrayEqualsWithoutOrder(a [][]string, b [][]string) bool {
-	if len(a) != len(b) {
-		return false
+func BenchmarkRemovePolicies(b *testing.B) {
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		b.Fatal(err)
 	}
-
-	mapA := make(map[int]string)
-	mapB := make(map[int]string)
-	order := make(map[int]struct{})
-	l := len(a)
-
-	for i := 0; i < l; i++ {
-		mapA[i] = util.ArrayToString(a[i])
-		mapB[i] = util.ArrayToString(b[i])
+	for i := 0; i < 100000; i++ {
+		rule := []string{fmt.Sprintf("user%d", i), fmt.Sprintf("/data/%d", i), "read"}
+		m["p"]["p"].Policy = append(m["p"]["p"].Policy, rule)
+	}
+	// Rules spread over the list, so that every LREM scans a large part of it.
+	var removed [][]string
+	for i := 0; i < 100000; i += 1000 {
+		removed = append(removed, m["p"]["p"].Policy[i])
 	}
 
-	for i := 0; i < l; i++ {
-		for j := 0; j < l; j++ {
-			if _, ok := order[j]; ok {
-				if j == l-1 {
-					return false
-				} else {
-					continue
-				}
+	for _, singleScan := range []bool{false, true} {
+		name := "LREM"
+		if singleScan {
+			name = "Lua"
+		}
+		b.Run(name, func(b *testing.B) {
+			a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_bench", SingleScanRemoval: singleScan})
+			if err != nil {
+				b.Fatal(err)
 			}
-			if mapA[i] == mapB[j] {
-				order[j] = struct{}{}
-				break
-			} else if j == l-1 {
-				return false
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				if err = a.SavePolicy(m); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				if err = a.RemovePolicies("p", "p", removed); err != nil {
+					b.Fatal(err)
+				}
 			}
-		}
+		})
 	}
-	return true
 }
 
-func TestAdapters(t *testing.T) {
-	a, _ := NewAdapterBasic("tcp", "127.0.0.1:6379")
-
-	// Use the following if Redis has password like "123"
-	// a, err := NewAdapterWithPassword("tcp", "127.0.0.1:6379", "123")
-
-	// Use the following if you use Redis with a account
-	// a, err := NewAdapterWithUser("tcp", "127.0.0.1:6379", "testaccount", "userpass")
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
-}
+func TestRuleFieldLimit(t *testing.T) {
+	logger := &recordingLogger{}
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_fields", Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+
+	full := []string{"alice", "data1", "read", "tenant1", "allow", "2025"}
+	if err = a.AddPolicy("p", "p", full); err != nil {
+		t.Fatalf("AddPolicy() with 6 fields = %v", err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"bob", "data2", "write", "tenant1", "allow", "2025"}); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := a.GetAllPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{append([]string{"p"}, full...), {"p", "bob", "data2", "write", "tenant1", "allow", "2025"}}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("GetAllPolicies() = %v, supposed to be %v", rules, want)
+	}
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "all 6 fields") {
+		t.Errorf("logged %q, supposed to warn once about the field limit", logger.messages)
+	}
 
-func TestAdapterWithOption(t *testing.T) {
-	a, _ := NewAdapterWithOption(WithNetwork("tcp"), WithAddress("127.0.0.1:6379"))
-	// User the following if use TLS to connect to redis
-	// var clientTLSConfig tls.Config
-	// a, err := NewAdapterWithOption(WithTls(&clientTLSConfig))
-
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
+	// Rules with more fields are rejected instead of being truncated.
+	if err = a.AddPolicy("p", "p", append(full, "extra")); !errors.Is(err, ErrTooManyFields) {
+		t.Errorf("AddPolicy() with 7 fields = %v, supposed to be ErrTooManyFields", err)
+	}
+	if err = a.UpdatePolicy("p", "p", full, append(full, "extra")); !errors.Is(err, ErrTooManyFields) {
+		t.Errorf("UpdatePolicy() to 7 fields = %v, supposed to be ErrTooManyFields", err)
+	}
+	if rules, _ = a.GetAllPolicies(); len(rules) != 2 {
+		t.Errorf("GetAllPolicies() = %v after rejected writes, supposed to hold 2 rules", rules)
+	}
 }
 
-func TestPoolAdapters(t *testing.T) {
-	a, err := NewAdapterWithPool(&redis.Pool{
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", "127.0.0.1:6379")
+func TestSavePolicyFailureKeepsPolicy(t *testing.T) {
+	other, err := redis.Dial("tcp", "127.0.0.1:6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	// Make the write of the new policy fail by turning its temporary key into a string.
+	var fail bool
+	var tmp string
+	a, err := NewAdapter(&Config{
+		Network: "tcp",
+		Address: "127.0.0.1:6379",
+		Key:     "casbin_rules_save_failure",
+		CommandHook: func(cmd string, args []interface{}) {
+			if cmd != "RPUSH" || !fail || tmp != "" {
+				return
+			}
+			if key, _ := args[0].(string); strings.Contains(key, ":saving:") {
+				tmp = key
+				if _, err := other.Do("SET", key, "x"); err != nil {
+					t.Error(err)
+				}
+			}
 		},
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	initPolicy(t, a)
 
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
+	replacement, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	replacement.AddPolicy("carol", "data3", "read")
+	fail = true
+	if err = a.SavePolicy(replacement.GetModel()); err == nil {
+		t.Fatal("SavePolicy() writing to a string key succeeded, supposed to fail")
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if n, _ := redis.Int(other.Do("EXISTS", tmp)); n != 0 {
+		t.Errorf("temporary key %s was left after the failed save", tmp)
+	}
 }
 
-func TestPoolAndOptionsAdapters(t *testing.T) {
-	a, err := NewAdapterWithPoolAndOptions(&redis.Pool{
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", "127.0.0.1:6379")
-		},
-	}, WithKey("casbin:policy:test"))
+func TestLoadErrorPosition(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_load_error", LoadErrorPosition: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	if err = a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Do("RPUSH", a.key, "{corrupted", `{"PType":"p","V0":"carol","V1":"data3","V2":"read"}`)
+	a.release(conn)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	testSaveLoad(t, a)
-	testAutoSave(t, a)
-	testFilteredPolicy(t, a)
-	testAddPolicies(t, a)
-	testRemovePolicies(t, a)
-	testUpdatePolicies(t, a)
-	testUpdateFilteredPolicies(t, a)
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.LoadPolicy(m)
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("LoadPolicy() = %v, supposed to be a *LoadError", err)
+	}
+	if loadErr.Index != 2 || loadErr.Loaded != 2 {
+		t.Errorf("LoadError = %+v, supposed to report index 2 and 2 rules loaded", loadErr)
+	}
+	if policy := m.GetPolicy("p", "p"); len(policy) != 2 {
+		t.Errorf("loaded policy = %v, supposed to hold the 2 rules before the failure", policy)
+	}
 }
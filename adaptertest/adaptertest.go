@@ -0,0 +1,402 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package adaptertest runs the behavior tests of the Redis adapter against
+// adapters of a factory, so adapters over another client than the redigo pool,
+// e.g. those of the goredis package, are held to the same tests:
+//
+//	func TestAdapter(t *testing.T) {
+//		adaptertest.TestAdapter(t, func(t *testing.T, key string) *redisadapter.Adapter {
+//			a, err := goredis.NewAdapter(rdb, redisadapter.WithKey(key))
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return a
+//		})
+//	}
+//
+// Run runs them in each Layout, against adapters of a factory taking it.
+//
+// The tests need the Redis server the factory connects to.
+package adaptertest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	redisadapter "github.com/casbin/redis-adapter/v3"
+)
+
+// rbacModel is examples/rbac_model.conf.
+const rbacModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+// initialPolicy is the policy rules of examples/rbac_policy.csv.
+var initialPolicy = [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}
+
+// Factory returns a new adapter storing the policy under key, on a server
+// shared by the tests, or under a key of its own if its constructor takes none,
+// which the tests then share one after another. The tests close it.
+type Factory func(t *testing.T, key string) *redisadapter.Adapter
+
+// TestAdapter runs the behavior tests against adapters of newAdapter, each
+// under a key of its own starting with "casbin_rules_adaptertest:" and named
+// after the test.
+func TestAdapter(t *testing.T, newAdapter Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, a *redisadapter.Adapter)
+	}{
+		{"SaveLoad", testSaveLoad},
+		{"AutoSave", testAutoSave},
+		{"FilteredPolicy", testFilteredPolicy},
+		{"AddPolicies", testAddPolicies},
+		{"RemovePolicies", testRemovePolicies},
+		{"UpdatePolicies", testUpdatePolicies},
+		{"UpdateFilteredPolicies", testUpdateFilteredPolicies},
+		{"LargeFilter", testLargeFilter},
+		{"Version", testVersion},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			a := newAdapter(t, "casbin_rules_adaptertest:"+t.Name())
+			defer a.Close()
+			test.test(t, a)
+		})
+	}
+}
+
+// layouts are the values of redisadapter.Layout, with whether they store a
+// rule added twice once.
+var layouts = []struct {
+	name        string
+	layout      redisadapter.Layout
+	deduplicate bool
+}{
+	{"List", redisadapter.ListLayout, false},
+	{"PTypeSet", redisadapter.PTypeSetLayout, true},
+	{"Stream", redisadapter.StreamLayout, false},
+	{"Hash", redisadapter.HashLayout, true},
+	{"ZSet", redisadapter.ZSetLayout, true},
+}
+
+// LayoutFactory returns a new adapter storing the policy under key in layout,
+// like Factory.
+type LayoutFactory func(t *testing.T, key string, layout redisadapter.Layout) *redisadapter.Adapter
+
+// Run runs TestAdapter against adapters of newAdapter in each Layout, and
+// checks how many copies of a rule added twice each layout stores.
+func Run(t *testing.T, newAdapter LayoutFactory) {
+	for _, layout := range layouts {
+		layout := layout
+		t.Run(layout.name, func(t *testing.T) {
+			TestAdapter(t, func(t *testing.T, key string) *redisadapter.Adapter {
+				return newAdapter(t, key, layout.layout)
+			})
+			t.Run("Duplicates", func(t *testing.T) {
+				a := newAdapter(t, "casbin_rules_adaptertest:"+t.Name(), layout.layout)
+				defer a.Close()
+				testDuplicates(t, a, layout.deduplicate)
+			})
+		})
+	}
+}
+
+// newEnforcer returns an enforcer of the RBAC model loading the policy from a.
+func newEnforcer(t *testing.T, a *redisadapter.Adapter) *casbin.Enforcer {
+	t.Helper()
+	m, err := model.NewModelFromString(rbacModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := casbin.NewEnforcer(m, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// initPolicy saves the policy of examples/rbac_policy.csv with a.
+func initPolicy(t *testing.T, a *redisadapter.Adapter) {
+	t.Helper()
+	m, err := model.NewModelFromString(rbacModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddPolicies("p", "p", initialPolicy)
+	m.AddPolicy("g", "g", []string{"alice", "data2_admin"})
+	if err = a.SavePolicy(m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testGetPolicy checks the policy rules of e, whatever their order.
+func testGetPolicy(t *testing.T, e *casbin.Enforcer, want [][]string) {
+	t.Helper()
+	got := e.GetPolicy()
+	if len(got) != len(want) {
+		t.Errorf("GetPolicy() = %v, supposed to be %v", got, want)
+		return
+	}
+	key := func(rules [][]string) []string {
+		keys := make([]string, len(rules))
+		for i, rule := range rules {
+			keys[i] = strings.Join(rule, ",")
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	gotKeys, wantKeys := key(got), key(want)
+	for i := range gotKeys {
+		if gotKeys[i] != wantKeys[i] {
+			t.Errorf("GetPolicy() = %v, supposed to be %v", got, want)
+			return
+		}
+	}
+}
+
+func testSaveLoad(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+	testGetPolicy(t, e, initialPolicy)
+	if ok, _ := e.Enforce("alice", "data2", "read"); !ok {
+		t.Error("Enforce(alice, data2, read) = false, supposed to be granted by the role data2_admin")
+	}
+}
+
+func testAutoSave(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+
+	// Without AutoSave the change only affects the policy in memory.
+	e.EnableAutoSave(false)
+	if _, err := e.AddPolicy("alice", "data1", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, initialPolicy)
+
+	e.EnableAutoSave(true)
+	if _, err := e.AddPolicy("alice", "data1", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, append([][]string{{"alice", "data1", "write"}}, initialPolicy...))
+
+	if _, err := e.RemovePolicy("alice", "data1", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, initialPolicy)
+
+	if _, err := e.RemoveFilteredPolicy(0, "data2_admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+}
+
+func testFilteredPolicy(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+
+	tests := []struct {
+		subjects []string
+		want     [][]string
+	}{
+		{[]string{"alice"}, [][]string{{"alice", "data1", "read"}}},
+		{[]string{"bob"}, [][]string{{"bob", "data2", "write"}}},
+		{[]string{"data2_admin"}, [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}},
+		{[]string{"alice", "bob"}, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}},
+	}
+	for _, test := range tests {
+		if err := e.LoadFilteredPolicy(redisadapter.Filter{V0: test.subjects}); err != nil {
+			t.Fatalf("LoadFilteredPolicy(%v) = %v", test.subjects, err)
+		}
+		testGetPolicy(t, e, test.want)
+	}
+}
+
+func testAddPolicies(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+
+	if err := a.AddPolicies("p", "p", [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadFilteredPolicy(redisadapter.Filter{V0: []string{"max"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}})
+}
+
+func testRemovePolicies(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+
+	if err := a.AddPolicies("p", "p", [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}, {"max", "data1", "delete"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RemovePolicies("p", "p", [][]string{{"max", "data2", "read"}, {"max", "data1", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadFilteredPolicy(redisadapter.Filter{V0: []string{"max"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"max", "data1", "delete"}})
+}
+
+func testUpdatePolicies(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+
+	if err := a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"alice", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"alice", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	if err := a.UpdatePolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"alice", "data2", "write"}}, [][]string{{"bob", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func testUpdateFilteredPolicies(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+
+	if _, err := e.UpdateFilteredPolicies([][]string{{"alice", "data1", "write"}}, 0, "alice", "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.UpdateFilteredPolicies([][]string{{"bob", "data2", "read"}}, 0, "bob", "data2", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"bob", "data2", "read"}})
+}
+
+func testLargeFilter(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+
+	// Far more values than the regex limit, so the filter is matched by set membership.
+	subjects := make([]string, 0, 10002)
+	for i := 0; i < 10000; i++ {
+		subjects = append(subjects, fmt.Sprintf("user%d", i))
+	}
+	subjects = append(subjects, "alice", "data2_admin")
+	start := time.Now()
+	if err := e.LoadFilteredPolicy(redisadapter.Filter{PType: []string{"p"}, V0: subjects}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("LoadFilteredPolicy() with a large filter took %v", elapsed)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func testVersion(t *testing.T, a *redisadapter.Adapter) {
+	initPolicy(t, a)
+	e := newEnforcer(t, a)
+
+	version, err := a.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The save expecting the version before the change is rejected, so it does
+	// not overwrite the change, and the one expecting the current version applies.
+	err = a.SavePolicyIfVersion(e.GetModel(), version)
+	if errors.Is(err, redisadapter.ErrWatchUnavailable) {
+		t.Skip("SavePolicyIfVersion() needs WATCH")
+	}
+	if !errors.Is(err, redisadapter.ErrVersionConflict) {
+		t.Errorf("SavePolicyIfVersion() of a stale version = %v, supposed to be %v", err, redisadapter.ErrVersionConflict)
+	}
+	if version, err = a.Version(); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.SavePolicyIfVersion(e.GetModel(), version); err != nil {
+		t.Errorf("SavePolicyIfVersion() of the current version = %v", err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, initialPolicy)
+}
+
+func testDuplicates(t *testing.T, a *redisadapter.Adapter, deduplicate bool) {
+	initPolicy(t, a)
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	grouped, err := a.GetAllGrouped()
+	if err != nil {
+		t.Fatal(err)
+	}
+	copies := 0
+	for _, rule := range grouped["p"] {
+		if strings.Join(rule, ",") == "alice,data1,read" {
+			copies++
+		}
+	}
+	want := 2
+	if deduplicate {
+		want = 1
+	}
+	if copies != want {
+		t.Errorf("GetAllGrouped() holds %d copies of a rule added twice, supposed to be %d", copies, want)
+	}
+}
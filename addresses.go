@@ -0,0 +1,99 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// defaultAddressTimeout bounds connecting to each of Config.Addresses
+	// without Config.ConnectTimeout, so that a dead address does not hold up
+	// the next ones.
+	defaultAddressTimeout = 5 * time.Second
+	// preferredRetryInterval is how long the adapter keeps dialing the last
+	// address that worked before trying the preferred ones again.
+	preferredRetryInterval = 30 * time.Second
+)
+
+// addressList dials the first reachable of Config.Addresses, starting from the
+// last one that was reachable.
+type addressList struct {
+	addrs []string
+
+	mu      sync.Mutex
+	good    int       // index of the last reachable address
+	goodAt  time.Time // when good was first dialed after a preferred one failed
+	timeNow func() time.Time
+}
+
+func newAddressList(addrs []string) *addressList {
+	return &addressList{addrs: append([]string(nil), addrs...), timeNow: time.Now}
+}
+
+// order returns the indexes of the addresses in the order they are dialed.
+func (l *addressList) order() []int {
+	l.mu.Lock()
+	start := l.good
+	if start > 0 && l.timeNow().Sub(l.goodAt) >= preferredRetryInterval {
+		start = 0
+	}
+	l.mu.Unlock()
+
+	order := make([]int, 0, len(l.addrs))
+	for i := start; i < len(l.addrs); i++ {
+		order = append(order, i)
+	}
+	for i := 0; i < start; i++ {
+		order = append(order, i)
+	}
+	return order
+}
+
+// reached records that the address of index i was dialed, trying first the
+// address of index first.
+func (l *addressList) reached(i, first int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// The preferred addresses are tried again after preferredRetryInterval
+	// from the last time they failed.
+	if i != l.good || first != l.good {
+		l.good, l.goodAt = i, l.timeNow()
+	}
+}
+
+// dial dials the addresses in turn with options and returns the first
+// connection, or an error listing why each address failed.
+func (l *addressList) dial(network string, options []redis.DialOption) (redis.Conn, error) {
+	order := l.order()
+	var failures []string
+	var err error
+	for _, i := range order {
+		var conn redis.Conn
+		if conn, err = redis.Dial(network, l.addrs[i], options...); err == nil {
+			l.reached(i, order[0])
+			return conn, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", l.addrs[i], err))
+	}
+	// The error of the last address is wrapped, the others are in the message.
+	failures = append(failures[:len(failures)-1], l.addrs[order[len(order)-1]])
+	return nil, fmt.Errorf("no address reachable: %s: %w", strings.Join(failures, "; "), err)
+}
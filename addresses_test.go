@@ -0,0 +1,78 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAddresses(t *testing.T) {
+	dead := closedAddr(t)
+	l := listen(t)
+	defer l.Close()
+	go serveNil(l, nil)
+
+	a, err := NewAdapter(&Config{Network: "tcp", Addresses: []string{dead, l.Addr().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if _, err = a.Version(); err != nil {
+		t.Fatal(err)
+	}
+
+	dead2 := closedAddr(t)
+	_, err = NewAdapter(&Config{Network: "tcp", Addresses: []string{dead, dead2}})
+	if err == nil || !strings.Contains(err.Error(), dead) || !strings.Contains(err.Error(), dead2) {
+		t.Errorf("NewAdapter() = %v, supposed to report both addresses", err)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("NewAdapter() = %v, supposed to wrap the error of the last address", err)
+	}
+}
+
+func TestAddressOrder(t *testing.T) {
+	now := time.Now()
+	l := newAddressList([]string{"primary", "dr1", "dr2"})
+	l.timeNow = func() time.Time { return now }
+	check := func(want []int) {
+		t.Helper()
+		if got := l.order(); !reflect.DeepEqual(got, want) {
+			t.Errorf("order() = %v, supposed to be %v", got, want)
+		}
+	}
+
+	check([]int{0, 1, 2})
+	l.reached(2, 0)
+	check([]int{2, 0, 1})
+
+	// The preferred address is tried again, and while it is still down the
+	// last reachable address keeps being dialed first.
+	now = now.Add(preferredRetryInterval)
+	check([]int{0, 1, 2})
+	l.reached(2, 0)
+	check([]int{2, 0, 1})
+
+	now = now.Add(preferredRetryInterval)
+	l.reached(0, 0)
+	check([]int{0, 1, 2})
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"github.com/casbin/casbin/v2/model"
+)
+
+// LoadPolicyAsync is LoadPolicy running on a new goroutine, which calls done with
+// its result, so a service can start serving with an empty or stale policy while
+// the policy loads.
+//
+// The rules are loaded into a private copy of model, so model is not touched
+// while Redis is read and is left unchanged if the load fails. Once the load
+// succeeds, the rules of each assertion of model are replaced by the loaded ones
+// before done is called. The copy and the replacement hold the lock of the
+// adapter operations, so they do not race with other LoadPolicyAsync calls or
+// with the operations of the adapter writing to model, such as LoadPolicy. The
+// enforcer does not take that lock: either do not enforce until done is called,
+// or load into a model the enforcer does not use and swap it in from done under
+// the lock guarding enforcement. Role links are not rebuilt, call BuildRoleLinks
+// of the enforcer from done.
+func (a *Adapter) LoadPolicyAsync(model model.Model, done func(error)) {
+	a.keyMu.RLock()
+	staged := model.Copy()
+	a.keyMu.RUnlock()
+	staged.ClearPolicy()
+	go func() {
+		err := a.LoadPolicy(staged)
+		if err == nil {
+			a.keyMu.Lock()
+			for sec, assertions := range staged {
+				for ptype, ast := range assertions {
+					if target, ok := model[sec][ptype]; ok {
+						target.Policy = ast.Policy
+						target.PolicyMap = ast.PolicyMap
+					}
+				}
+			}
+			a.keyMu.Unlock()
+		}
+		done(err)
+	}()
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestLoadPolicyAsync(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_async"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	e.SetAdapter(a)
+	done := make(chan error, 1)
+	a.LoadPolicyAsync(e.GetModel(), func(err error) { done <- err })
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatalf("LoadPolicyAsync() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("LoadPolicyAsync() did not call done")
+	}
+	if err = e.BuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if ok, _ := e.Enforce("alice", "data2", "read"); !ok {
+		t.Error("alice cannot read data2 through data2_admin after LoadPolicyAsync()")
+	}
+
+	// A failed load leaves the model unchanged.
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	a.LoadPolicyAsync(e.GetModel(), func(err error) { done <- err })
+	if err = <-done; err != ErrClosed {
+		t.Errorf("LoadPolicyAsync() after Close = %v, supposed to be ErrClosed", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestLoadPolicyAsyncConcurrent(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_async_concurrent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	e.SetAdapter(a)
+	m := e.GetModel()
+
+	// Asynchronous loads run along with each other, with loads of the adapter
+	// into the same model and with writes.
+	const n = 20
+	done := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		a.LoadPolicyAsync(m, func(err error) { done <- err })
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				if err := a.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data", "read"}); err != nil {
+					t.Error(err)
+				}
+			} else if err := a.RemovePolicy("p", "p", []string{fmt.Sprintf("user%d", i-1), "data", "read"}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			if err := a.LoadPolicy(m); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		if err = <-done; err != nil {
+			t.Errorf("LoadPolicyAsync() = %v", err)
+		}
+	}
+	wg.Wait()
+
+	a.LoadPolicyAsync(m, func(err error) { done <- err })
+	if err = <-done; err != nil {
+		t.Fatalf("LoadPolicyAsync() = %v", err)
+	}
+	if err = e.BuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := e.Enforce("alice", "data2", "read"); !ok {
+		t.Error("alice cannot read data2 through data2_admin after concurrent LoadPolicyAsync()")
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Audited operations, as recorded in AuditEntry.Op.
+const (
+	AuditAdd            = "add"
+	AuditRemove         = "remove"
+	AuditRemoveFiltered = "remove_filtered"
+	AuditUpdate         = "update"
+	AuditUpdateFiltered = "update_filtered"
+	AuditSave           = "save"
+)
+
+// errAuditEntry is returned by ReadAudit for entries it did not write.
+var errAuditEntry = errors.New("malformed audit entry")
+
+// AuditEntry is a policy change recorded in the audit stream.
+type AuditEntry struct {
+	// ID is the ID of the stream entry.
+	ID string
+	// Op is the operation, e.g. AuditAdd.
+	Op string
+	// Sec and PType are the section and ptype of the rules. They are empty for AuditSave.
+	Sec   string
+	PType string
+	// Rules are the added, removed or new rules.
+	Rules [][]string
+	// OldRules are the rules replaced by AuditUpdate and AuditUpdateFiltered.
+	OldRules [][]string
+	// FieldIndex and FieldValues are the filter of AuditRemoveFiltered and AuditUpdateFiltered.
+	FieldIndex  int
+	FieldValues []string
+	// Count is the number of rules saved by AuditSave.
+	Count int
+	// Time is when the change was made.
+	Time time.Time
+	// Actor is who made the change, or UnknownActor.
+	Actor string
+}
+
+// changed records a successful write once it is committed: it increments the
+// version and appends the entry to the audit stream, recording the actor of ctx,
+// in one transaction. Clients polling the version and readers of the audit
+// stream thus see the change together, and never see a write that failed. It is
+// deferred by the writing methods with their error result.
+func (a *Adapter) changed(ctx context.Context, err *error, entry AuditEntry) {
+	if *err != nil {
+		return
+	}
+	var args redis.Args
+	if a.auditStream != "" {
+		if args, *err = a.auditArgs(ctx, entry); *err != nil {
+			return
+		}
+	}
+
+	conn, e := a.getConn()
+	if e != nil {
+		*err = e
+		return
+	}
+	defer a.release(conn)
+
+	if args == nil {
+		if _, e = conn.Do("INCR", a.versionKey()); e != nil {
+			*err = fmt.Errorf("version counter: %w", e)
+		}
+		return
+	}
+	if *err = conn.Send("MULTI"); *err != nil {
+		return
+	}
+	if *err = conn.Send("INCR", a.versionKey()); *err != nil {
+		return
+	}
+	if *err = conn.Send("XADD", args...); *err != nil {
+		return
+	}
+	reply, e := conn.Do("EXEC")
+	if e == nil {
+		e = execError(reply)
+	}
+	if e != nil {
+		*err = fmt.Errorf("version counter and audit stream: %w", e)
+	}
+}
+
+// audit appends the entry to the audit stream after a successful write, recording
+// the actor of ctx, for the writes that increment the version themselves. It is
+// deferred by the writing methods with their error result.
+func (a *Adapter) audit(ctx context.Context, err *error, entry AuditEntry) {
+	if *err != nil || a.auditStream == "" {
+		return
+	}
+	args, e := a.auditArgs(ctx, entry)
+	if e != nil {
+		*err = e
+		return
+	}
+
+	conn, e := a.getConn()
+	if e != nil {
+		*err = e
+		return
+	}
+	defer a.release(conn)
+
+	_, *err = conn.Do("XADD", args...)
+}
+
+// auditArgs returns the arguments of the XADD appending the entry to the audit
+// stream, recording the actor of ctx.
+func (a *Adapter) auditArgs(ctx context.Context, entry AuditEntry) (redis.Args, error) {
+	entry.Actor = ActorFromContext(ctx)
+
+	args := redis.Args{}.Add(a.auditStream, "*", "op", entry.Op, "ts", time.Now().UnixNano()/int64(time.Millisecond))
+	addJSON := func(name string, value interface{}) error {
+		text, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		args = args.Add(name, text)
+		return nil
+	}
+	if entry.Op == AuditSave {
+		args = args.Add("count", entry.Count)
+	} else {
+		args = args.Add("sec", entry.Sec, "ptype", entry.PType)
+		if entry.Rules != nil {
+			if err := addJSON("rules", entry.Rules); err != nil {
+				return nil, err
+			}
+		}
+		if entry.OldRules != nil {
+			if err := addJSON("old_rules", entry.OldRules); err != nil {
+				return nil, err
+			}
+		}
+		if entry.FieldValues != nil {
+			if err := addJSON("field_values", entry.FieldValues); err != nil {
+				return nil, err
+			}
+			args = args.Add("field_index", entry.FieldIndex)
+		}
+	}
+	return args.Add("actor", entry.Actor), nil
+}
+
+// countRules returns the number of rules in the model.
+func countRules(model model.Model) int {
+	n := 0
+	for _, sec := range []string{"p", "g"} {
+		for _, ast := range model[sec] {
+			n += len(ast.Policy)
+		}
+	}
+	return n
+}
+
+// ReadAudit returns up to count entries of the audit stream, oldest first, that
+// were added after the entry with ID after. Pass "" to start at the beginning,
+// and the ID of the last returned entry to read the next page.
+func (a *Adapter) ReadAudit(after string, count int) ([]AuditEntry, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	conn, err := a.getConn()
+	if err != nil {
+		return nil, err
+	}
+	defer a.release(conn)
+
+	start := "-"
+	n := count
+	if after != "" {
+		// The range is inclusive, the entry with ID after is skipped below.
+		start = after
+		n++
+	}
+	values, err := redis.Values(conn.Do("XRANGE", a.auditStream, start, "+", "COUNT", n))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]AuditEntry, 0, len(values))
+	for _, value := range values {
+		item, err := redis.Values(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(item) != 2 {
+			return nil, errAuditEntry
+		}
+		id, err := redis.String(item[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		if id == after {
+			continue
+		}
+		fields, err := redis.StringMap(item[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := parseAuditEntry(id, fields)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		if len(entries) == count {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// parseAuditEntry converts the fields of a stream entry to an AuditEntry.
+func parseAuditEntry(id string, fields map[string]string) (AuditEntry, error) {
+	entry := AuditEntry{
+		ID:    id,
+		Op:    fields["op"],
+		Sec:   fields["sec"],
+		PType: fields["ptype"],
+		Actor: fields["actor"],
+	}
+
+	ts, err := strconv.ParseInt(fields["ts"], 10, 64)
+	if err != nil {
+		return entry, errAuditEntry
+	}
+	entry.Time = time.Unix(0, ts*int64(time.Millisecond))
+
+	for name, dest := range map[string]interface{}{"rules": &entry.Rules, "old_rules": &entry.OldRules, "field_values": &entry.FieldValues} {
+		if text, ok := fields[name]; ok {
+			if err = json.Unmarshal([]byte(text), dest); err != nil {
+				return entry, err
+			}
+		}
+	}
+	for name, dest := range map[string]*int{"field_index": &entry.FieldIndex, "count": &entry.Count} {
+		if text, ok := fields[name]; ok {
+			if *dest, err = strconv.Atoi(text); err != nil {
+				return entry, errAuditEntry
+			}
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestAuditStream(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_audit", AuditStream: "casbin_audit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("DEL", a.auditStream); err != nil {
+		t.Fatal(err)
+	}
+	a.release(conn)
+
+	start := time.Now().Add(-time.Second)
+	initPolicy(t, a)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if _, err = e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.UpdatePolicy([]string{"carol", "data3", "read"}, []string{"carol", "data3", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemoveFilteredPolicy(0, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AuditEntry{
+		{Op: AuditSave, Count: 5, Actor: UnknownActor},
+		{Op: AuditAdd, Sec: "p", PType: "p", Rules: [][]string{{"carol", "data3", "read"}}, Actor: UnknownActor},
+		{Op: AuditUpdate, Sec: "p", PType: "p", Rules: [][]string{{"carol", "data3", "write"}}, OldRules: [][]string{{"carol", "data3", "read"}}, Actor: UnknownActor},
+		{Op: AuditRemoveFiltered, Sec: "p", PType: "p", FieldValues: []string{"carol"}, Actor: UnknownActor},
+		{Op: AuditRemove, Sec: "p", PType: "p", Rules: [][]string{{"alice", "data1", "read"}}, Actor: UnknownActor},
+	}
+
+	// Read the stream two entries at a time.
+	var got []AuditEntry
+	after := ""
+	for {
+		page, err := a.ReadAudit(after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) > 2 {
+			t.Fatalf("ReadAudit() returned %d entries, supposed to be at most 2", len(page))
+		}
+		got = append(got, page...)
+		after = page[len(page)-1].ID
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ReadAudit() returned %d entries, supposed to be %d: %+v", len(got), len(want), got)
+	}
+	for i, entry := range got {
+		if entry.ID == "" || entry.Time.Before(start) || entry.Time.After(time.Now().Add(time.Second)) {
+			t.Errorf("entry %d has ID %q and time %v", i, entry.ID, entry.Time)
+		}
+		entry.ID, entry.Time = "", time.Time{}
+		if !reflect.DeepEqual(entry, want[i]) {
+			t.Errorf("entry %d = %+v, supposed to be %+v", i, entry, want[i])
+		}
+	}
+}
+
+func TestAuditActor(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_actor", AuditStream: "casbin_audit_actor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("DEL", a.auditStream); err != nil {
+		t.Fatal(err)
+	}
+	a.release(conn)
+
+	ctx := WithActor(context.Background(), "admin@example.com")
+	if err = a.AddPolicyCtx(ctx, "p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.UpdateFilteredPoliciesCtx(ctx, "p", "p", [][]string{{"carol", "data3", "write"}}, 0, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicy("p", "p", []string{"carol", "data3", "write"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := a.ReadAudit("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"admin@example.com", "admin@example.com", UnknownActor}
+	if len(entries) != len(want) {
+		t.Fatalf("ReadAudit() returned %d entries, supposed to be %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Actor != want[i] {
+			t.Errorf("actor of entry %d (%s) = %q, supposed to be %q", i, entry.Op, entry.Actor, want[i])
+		}
+	}
+}
+
+// refusingConn refuses the RPUSH commands, as a server refusing a write would.
+type refusingConn struct {
+	redis.Conn
+}
+
+var errRefused = errors.New("write refused")
+
+func (c refusingConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if strings.EqualFold(commandName, "RPUSH") {
+		return nil, errRefused
+	}
+	return c.Conn.Do(commandName, args...)
+}
+
+func TestAuditFailedWrite(t *testing.T) {
+	var changes []string
+	a, err := NewAdapter(&Config{
+		Key:         "casbin_rules_audit_failed",
+		AuditStream: "casbin_audit_failed",
+		Pool: &redis.Pool{Dial: func() (redis.Conn, error) {
+			conn, err := redis.Dial("tcp", "127.0.0.1:6379")
+			return refusingConn{conn}, err
+		}},
+		CommandHook: func(cmd string, args []interface{}) {
+			if cmd == "INCR" || cmd == "XADD" {
+				changes = append(changes, cmd)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := a.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); !errors.Is(err, errRefused) {
+		t.Fatalf("AddPolicy() = %v, supposed to be the refusal", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("failed AddPolicy() sent %v, supposed to record no change", changes)
+	}
+	if after, err := a.Version(); err != nil || after != version {
+		t.Errorf("Version() after a failed AddPolicy() = %d, %v, supposed to stay %d", after, err, version)
+	}
+	entries, err := a.ReadAudit("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("ReadAudit() after a failed AddPolicy() = %+v, supposed to be empty", entries)
+	}
+}
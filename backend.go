@@ -0,0 +1,383 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// RuleMatch selects the stored rules a filtered operation of a StorageBackend
+// applies to, e.g. the rules RemoveFilteredPolicy removes.
+type RuleMatch struct {
+	// Pattern is a Lua pattern matching the serialized rules, for backends
+	// matching them within a script. It is only set with RawPatternMatching, as
+	// it misses rules whose JSON is written differently.
+	Pattern string
+	// Match reports whether a serialized rule matches. It is always set, and
+	// returns an error for a rule that cannot be decoded.
+	Match func(rule []byte) (bool, error)
+}
+
+// StorageBackend stores the serialized rules of a policy under a Redis key, on
+// behalf of the adapter. The adapter marshals the rules, which are opaque to the
+// backend, and keeps the version, audit and other features around the backend.
+// Each layout but StreamLayout stores its rules with a backend: ListBackend,
+// SetBackend, HashBackend or ZSetBackend, which Config.Backend replaces. With
+// PTypeSetLayout and Config.SplitSections, the rules of each ptype are stored
+// under a key of their own.
+//
+// The rules of a key are stored under that key only, as SavePolicy writes them
+// to a temporary key with Append and renames it over the key. Backends may or
+// may not keep the order of the rules and duplicates. If they keep the order,
+// Append stores the rules after the stored ones and the replaced rules keep
+// their position; LoadAll and LoadMatching return them in order. If not, they
+// are used with PTypeSetLayout or HashLayout, which reject models whose effect
+// depends on the order of the rules, such as "priority(p_eft) || deny".
+//
+// The methods are called with the connection of the operation, which may run
+// the fencing check of Config.Fencing on write commands and scripts.
+type StorageBackend interface {
+	// LoadAll returns the rules stored under key. A missing key holds no rules.
+	LoadAll(conn redis.Conn, key string) ([][]byte, error)
+	// LoadMatching returns the rules stored under key that match.
+	LoadMatching(conn redis.Conn, key string, match RuleMatch) ([][]byte, error)
+	// Append stores rules under key.
+	Append(conn redis.Conn, key string, rules [][]byte) error
+	// Remove removes a stored copy of each of rules, ignoring those not stored.
+	Remove(conn redis.Conn, key string, rules [][]byte) error
+	// RemoveMatching removes the rules that match.
+	RemoveMatching(conn redis.Conn, key string, match RuleMatch) error
+	// Replace replaces the stored oldRules by the rules at the same index of
+	// newRules, which has the same length, ignoring those not stored.
+	Replace(conn redis.Conn, key string, oldRules, newRules [][]byte) error
+	// ReplaceMatching replaces the rules that match by newRules, in order. The
+	// matching rules left over are removed, and the new rules left over are
+	// appended. It returns the replaced rules.
+	ReplaceMatching(conn redis.Conn, key string, match RuleMatch, newRules [][]byte) ([][]byte, error)
+	// Clear removes the rules stored under key.
+	Clear(conn redis.Conn, key string) error
+}
+
+// ListBackend is the StorageBackend of ListLayout, which stores the rules in a
+// list. It keeps their order and duplicates. Replace with a single rule only
+// replaces its first copy, like UpdatePolicy, while several rules replace every
+// copy, like UpdatePolicies. The filtered operations match the rules by their
+// Pattern within a script, or decode them in the adapter without one. NewAdapter
+// uses it for ListLayout, the snapshot of StreamLayout and Config.SplitSections
+// unless Config.Backend is set.
+type ListBackend struct {
+	// SingleScanRemoval makes Remove remove all the rules in a single scan of the
+	// list by a Lua script, instead of one LREM per rule, see
+	// Config.SingleScanRemoval.
+	SingleScanRemoval bool
+}
+
+// removeOnceScript removes one occurrence of each value in ARGV, like one LREM
+// with a count of 1 per value, in a single scan of the list.
+var removeOnceScript = newWriteScript(1, `
+	local key = KEYS[1]
+
+	local pending = {}
+	for i=1, #ARGV do
+		pending[ARGV[i]] = (pending[ARGV[i]] or 0) + 1
+	end
+	local removed = 0
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		local n = pending[r[i]]
+		if n and n > 0 then
+			redis.call('lset', key, i-1, '__CASBIN_DELETED__')
+			pending[r[i]] = n - 1
+			removed = removed + 1
+		end
+	end
+	if removed > 0 then
+		redis.call('lrem', key, 0, '__CASBIN_DELETED__')
+	end
+	return removed
+`)
+
+// removePatternScript removes the values matching the pattern ARGV[1].
+var removePatternScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local pattern = ARGV[1]
+
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if string.find(r[i], pattern) then
+			redis.call('lset', key, i-1, '__CASBIN_DELETED__')
+		end
+	end
+	redis.call('lrem', key, 0, '__CASBIN_DELETED__')
+	return
+`)
+
+// updateOnceScript replaces the first occurrence of ARGV[1] by ARGV[2].
+var updateOnceScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local old = ARGV[1]
+	local newRule = ARGV[2]
+
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1,#r do
+		if r[i] == old then
+			redis.call('lset', key, i-1, newRule)
+			return true
+		end
+	end
+	return false
+`)
+
+// updateAllScript replaces the occurrences of each value in the first half of
+// ARGV by the value at the same index in the second half.
+var updateAllScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local len = #ARGV/2
+
+	local map = {}
+	for i = 1, len, 1 do
+		map[ARGV[i]] = ARGV[i + len] -- map[oldRule] = newRule
+	end
+
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1,#r do
+		if map[r[i]] ~= nil then
+			redis.call('lset', key, i-1, map[r[i]])
+		end
+	end
+
+	return false
+`)
+
+// replacePatternScript replaces the values matching the pattern ARGV[1] in place
+// by the values after it, in order. Matching values left over are removed, and
+// values left over are appended. It returns the replaced values.
+var replacePatternScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local pattern = ARGV[1]
+	local n = #ARGV - 1
+
+	local ret = {}
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if string.find(r[i], pattern) then
+			table.insert(ret, r[i])
+			if #ret <= n then
+				redis.call('lset', key, i-1, ARGV[#ret+1])
+			else
+				redis.call('lset', key, i-1, '__CASBIN_DELETED__')
+			end
+		end
+	end
+	if #ret > n then
+		redis.call('lrem', key, 0, '__CASBIN_DELETED__')
+	end
+	for i=#ret+1, n do
+		redis.call('rpush', key, ARGV[i+1])
+	end
+
+	return ret
+`)
+
+// LoadAll returns the rules of the list, leaving out the tombstones of TrimTo.
+func (b ListBackend) LoadAll(conn redis.Conn, key string) ([][]byte, error) {
+	num, err := redis.Int(conn.Do("LLEN", key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	values, err := redis.Values(conn.Do("LRANGE", key, 0, num))
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([][]byte, 0, len(values))
+	for _, value := range values {
+		rule, err := valueBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if string(rule) != tombstone {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// LoadMatching returns the rules of the list that match, decoding every rule.
+func (b ListBackend) LoadMatching(conn redis.Conn, key string, match RuleMatch) ([][]byte, error) {
+	rules, err := b.LoadAll(conn, key)
+	if err != nil {
+		return nil, err
+	}
+	return matchRules(rules, match)
+}
+
+// Append pushes the rules at the end of the list.
+func (b ListBackend) Append(conn redis.Conn, key string, rules [][]byte) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err := conn.Do("RPUSH", redis.Args{}.Add(key).AddFlat(rules)...)
+	return err
+}
+
+// Remove removes the first copy of each rule from the list.
+func (b ListBackend) Remove(conn redis.Conn, key string, rules [][]byte) error {
+	if b.SingleScanRemoval && len(rules) > 1 {
+		_, err := runScript(removeOnceScript, conn, redis.Args{}.Add(key).AddFlat(rules)...)
+		return err
+	}
+	for _, rule := range rules {
+		if _, err := conn.Do("LREM", key, 1, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveMatching removes every rule of the list that matches.
+func (b ListBackend) RemoveMatching(conn redis.Conn, key string, match RuleMatch) error {
+	if match.Pattern != "" {
+		_, err := runScript(removePatternScript, conn, key, match.Pattern)
+		return err
+	}
+	matched, err := b.LoadMatching(conn, key, match)
+	if err != nil || len(matched) == 0 {
+		return err
+	}
+	_, err = runScript(removeValuesScript, conn, redis.Args{}.Add(key).AddFlat(matched)...)
+	return err
+}
+
+// Replace replaces the rules of the list in place.
+func (b ListBackend) Replace(conn redis.Conn, key string, oldRules, newRules [][]byte) error {
+	var err error
+	switch len(oldRules) {
+	case 0:
+	case 1:
+		_, err = runScript(updateOnceScript, conn, key, oldRules[0], newRules[0])
+	default:
+		_, err = runScript(updateAllScript, conn, redis.Args{}.Add(key).AddFlat(oldRules).AddFlat(newRules)...)
+	}
+	return err
+}
+
+// ReplaceMatching replaces the matching rules of the list in place.
+func (b ListBackend) ReplaceMatching(conn redis.Conn, key string, match RuleMatch, newRules [][]byte) ([][]byte, error) {
+	if match.Pattern != "" {
+		args := redis.Args{}.Add(key, match.Pattern).AddFlat(newRules)
+		return redis.ByteSlices(runScript(replacePatternScript, conn, args...))
+	}
+	matched, err := b.LoadMatching(conn, key, match)
+	if err != nil {
+		return nil, err
+	}
+	args := redis.Args{}.Add(key, len(matched)).AddFlat(matched).AddFlat(newRules)
+	if _, err = runScript(replaceValuesScript, conn, args...); err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// Clear deletes the list.
+func (b ListBackend) Clear(conn redis.Conn, key string) error {
+	_, err := conn.Do("DEL", key)
+	return err
+}
+
+// matchRules returns the rules that match, in place.
+func matchRules(rules [][]byte, match RuleMatch) ([][]byte, error) {
+	matched := rules[:0]
+	for _, rule := range rules {
+		ok, err := match.Match(rule)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, rule)
+		}
+	}
+	return matched, nil
+}
+
+// layoutBackend returns the StorageBackend of the layout of config, which
+// NewAdapter uses unless Config.Backend is set.
+func layoutBackend(config *Config) StorageBackend {
+	switch config.Layout {
+	case PTypeSetLayout:
+		return SetBackend{}
+	case HashLayout:
+		return HashBackend{}
+	case ZSetLayout:
+		return ZSetBackend{}
+	}
+	return ListBackend{SingleScanRemoval: config.SingleScanRemoval && !config.DisableLua}
+}
+
+// ruleMatch returns the RuleMatch of the arguments of RemoveFilteredPolicy and
+// UpdateFilteredPolicies.
+func (a *Adapter) ruleMatch(sec, ptype string, fieldIndex int, fieldValues ...string) RuleMatch {
+	matcher := fieldValuesMatcher(ptype, fieldIndex, fieldValues...)
+	match := RuleMatch{Match: func(rule []byte) (bool, error) {
+		var line CasbinRule
+		if err := a.unmarshalStored(ptype, rule, &line); err != nil {
+			return false, err
+		}
+		return matcher(&line), nil
+	}}
+	// The members of PTypeSetLayout leave out the ptype the pattern matches.
+	if a.rawMatching && a.layout != PTypeSetLayout {
+		match.Pattern = filterFieldToLuaPattern(a.jsonKeys, sec, ptype, fieldIndex, fieldValues...)
+	}
+	return match
+}
+
+// ownBackend reports whether the rules are stored by the StorageBackend of the
+// layout, rather than one of Config.Backend, which the operations working on
+// its keys directly, such as RemovePoliciesChecked, need.
+func (a *Adapter) ownBackend() bool {
+	switch a.backend.(type) {
+	case ListBackend:
+		return a.layout == ListLayout
+	case SetBackend:
+		return a.layout == PTypeSetLayout
+	case HashBackend:
+		return a.layout == HashLayout
+	case ZSetBackend:
+		return a.layout == ZSetLayout
+	}
+	return false
+}
+
+// plainList reports whether the rules are in the single list of ListBackend, which the
+// operations working on the list directly, such as TrimTo, need.
+func (a *Adapter) plainList() bool {
+	return a.layout == ListLayout && a.ownBackend() && !a.splitSections
+}
+
+// validateBackend checks the options Backend cannot be combined with.
+func (c *Config) validateBackend(v *configCheck) {
+	if c.Backend == nil {
+		return
+	}
+	v.exclude("Backend", configOption{"SoftDelete", c.SoftDelete}, configOption{"TrackCreationOrder", c.TrackCreationOrder},
+		configOption{"DisableLua", c.DisableLua})
+	if c.SingleScanRemoval {
+		v.report(ErrIgnoredOption, "SingleScanRemoval is ignored with Backend")
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// recordingBackend is a ListBackend recording the methods called on it.
+type recordingBackend struct {
+	ListBackend
+	calls *[]string
+}
+
+func (b recordingBackend) LoadAll(conn redis.Conn, key string) ([][]byte, error) {
+	*b.calls = append(*b.calls, "LoadAll")
+	return b.ListBackend.LoadAll(conn, key)
+}
+
+func (b recordingBackend) Append(conn redis.Conn, key string, rules [][]byte) error {
+	*b.calls = append(*b.calls, "Append")
+	return b.ListBackend.Append(conn, key, rules)
+}
+
+func (b recordingBackend) Remove(conn redis.Conn, key string, rules [][]byte) error {
+	*b.calls = append(*b.calls, "Remove")
+	return b.ListBackend.Remove(conn, key, rules)
+}
+
+func TestBackend(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	var calls []string
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", Backend: recordingBackend{calls: &calls}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err = a.SavePolicy(source.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+
+	if want := []string{"Append", "Append", "Remove", "LoadAll"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("backend calls = %v, supposed to be %v", calls, want)
+	}
+	if _, err = a.TrimTo(1); !errors.Is(err, errLayoutUnsupported) {
+		t.Errorf("TrimTo() with a backend = %v, supposed to be unsupported", err)
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	a := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}, rawMatching: true}
+	match := a.ruleMatch("p", "p", 1, "data1")
+	if want := filterFieldToLuaPattern(DefaultJSONKeys, "p", "p", 1, "data1"); match.Pattern != want {
+		t.Errorf("Pattern = %q, supposed to be %q", match.Pattern, want)
+	}
+	for _, test := range []struct {
+		rule []string
+		want bool
+	}{
+		{[]string{"alice", "data1", "read"}, true},
+		{[]string{"alice", "data2", "read"}, false},
+	} {
+		text, _ := a.marshal(savePolicyLine("p", test.rule))
+		if ok, err := match.Match(text); ok != test.want || err != nil {
+			t.Errorf("Match(%v) = %v, %v, supposed to be %v", test.rule, ok, err, test.want)
+		}
+	}
+	if _, err := match.Match([]byte("{")); err == nil {
+		t.Error("Match() of a corrupt rule succeeded, supposed to fail")
+	}
+
+	a.rawMatching = false
+	if match = a.ruleMatch("p", "p", 1, "data1"); match.Pattern != "" {
+		t.Errorf("Pattern without RawPatternMatching = %q, supposed to be empty", match.Pattern)
+	}
+	if ok, err := match.Match([]byte(` { "V1" : "data1", "V0":"alice", "PType" : "p" } `)); !ok || err != nil {
+		t.Errorf("Match() of a reordered rule = %v, %v, supposed to match", ok, err)
+	}
+}
+
+func TestListBackend(t *testing.T) {
+	conn, err := redis.Dial("tcp", "127.0.0.1:6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	a := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}, rawMatching: true}
+	texts := func(rules ...string) [][]byte {
+		ret := make([][]byte, len(rules))
+		for i, rule := range rules {
+			ret[i], _ = a.marshal(savePolicyLine("p", []string{rule, "data", "read"}))
+		}
+		return ret
+	}
+	const key = "casbin_rules_backend"
+	for _, b := range []ListBackend{{}, {SingleScanRemoval: true}} {
+		for _, encoding := range []Encoding{JSONEncoding, GobEncoding} {
+			a.encoding, a.codec, a.rawMatching = encoding, newCodec(encoding, DefaultJSONKeys), encoding == JSONEncoding
+			if err = b.Clear(conn, key); err != nil {
+				t.Fatal(err)
+			}
+			check := func(step string, want [][]byte) {
+				t.Helper()
+				got, err := b.LoadAll(conn, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+					t.Errorf("%s: LoadAll() = %q, supposed to be %q", step, got, want)
+				}
+			}
+
+			if err = b.Append(conn, key, texts("a", "b", "c", "b")); err != nil {
+				t.Fatal(err)
+			}
+			check("Append", texts("a", "b", "c", "b"))
+
+			// Duplicates are kept, and Remove removes one copy.
+			if err = b.Remove(conn, key, texts("b", "x")); err != nil {
+				t.Fatal(err)
+			}
+			check("Remove", texts("a", "c", "b"))
+
+			if err = b.Replace(conn, key, texts("a", "b"), texts("d", "e")); err != nil {
+				t.Fatal(err)
+			}
+			check("Replace", texts("d", "c", "e"))
+
+			match := a.ruleMatch("p", "p", 0, "c")
+			matched, err := b.LoadMatching(conn, key, match)
+			if err != nil || !reflect.DeepEqual(matched, texts("c")) {
+				t.Errorf("LoadMatching() = %q, %v, supposed to be c", matched, err)
+			}
+			replaced, err := b.ReplaceMatching(conn, key, match, texts("f", "g"))
+			if err != nil || !reflect.DeepEqual(replaced, texts("c")) {
+				t.Errorf("ReplaceMatching() = %q, %v, supposed to be c", replaced, err)
+			}
+			check("ReplaceMatching", texts("d", "f", "e", "g"))
+
+			if err = b.RemoveMatching(conn, key, a.ruleMatch("p", "p", 0, "f")); err != nil {
+				t.Fatal(err)
+			}
+			check("RemoveMatching", texts("d", "e", "g"))
+
+			if err = b.Clear(conn, key); err != nil {
+				t.Fatal(err)
+			}
+			check("Clear", nil)
+		}
+	}
+}
+
+func TestLayoutBackends(t *testing.T) {
+	conn, err := redis.Dial("tcp", "127.0.0.1:6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	a := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}}
+	texts := func(rules ...string) [][]byte {
+		ret := make([][]byte, len(rules))
+		for i, rule := range rules {
+			ret[i], _ = a.marshal(savePolicyLine("p", []string{rule, "data", "read"}))
+		}
+		return ret
+	}
+	const key = "casbin_rules_backend"
+	for _, test := range []struct {
+		backend StorageBackend
+		ordered bool
+	}{
+		{SetBackend{}, false},
+		{HashBackend{}, false},
+		{ZSetBackend{}, true},
+	} {
+		b := test.backend
+		for _, encoding := range []Encoding{JSONEncoding, GobEncoding} {
+			a.encoding, a.codec, a.rawMatching = encoding, newCodec(encoding, DefaultJSONKeys), encoding == JSONEncoding
+			if err = b.Clear(conn, key); err != nil {
+				t.Fatal(err)
+			}
+			check := func(step string, want [][]byte) {
+				t.Helper()
+				got, err := b.LoadAll(conn, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !test.ordered {
+					sort.Slice(got, func(i, j int) bool { return string(got[i]) < string(got[j]) })
+					sort.Slice(want, func(i, j int) bool { return string(want[i]) < string(want[j]) })
+				}
+				if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+					t.Errorf("%T %s: LoadAll() = %q, supposed to be %q", b, step, got, want)
+				}
+			}
+
+			// Duplicates are stored once.
+			if err = b.Append(conn, key, texts("a", "b", "c", "b")); err != nil {
+				t.Fatal(err)
+			}
+			check("Append", texts("a", "b", "c"))
+
+			if err = b.Remove(conn, key, texts("b", "x")); err != nil {
+				t.Fatal(err)
+			}
+			check("Remove", texts("a", "c"))
+
+			if err = b.Replace(conn, key, texts("a", "x"), texts("d", "e")); err != nil {
+				t.Fatal(err)
+			}
+			check("Replace", texts("d", "c"))
+
+			match := a.ruleMatch("p", "p", 0, "c")
+			replaced, err := b.ReplaceMatching(conn, key, match, texts("f", "g"))
+			if err != nil || !reflect.DeepEqual(replaced, texts("c")) {
+				t.Errorf("%T ReplaceMatching() = %q, %v, supposed to be c", b, replaced, err)
+			}
+			check("ReplaceMatching", texts("d", "f", "g"))
+
+			if err = b.RemoveMatching(conn, key, a.ruleMatch("p", "p", 0, "f")); err != nil {
+				t.Fatal(err)
+			}
+			check("RemoveMatching", texts("d", "g"))
+
+			if err = b.Clear(conn, key); err != nil {
+				t.Fatal(err)
+			}
+			check("Clear", nil)
+		}
+	}
+}
+
+// countingBackend counts the rules appended through a StorageBackend it wraps.
+type countingBackend struct {
+	StorageBackend
+	appended *int
+}
+
+func (b countingBackend) Append(conn redis.Conn, key string, rules [][]byte) error {
+	*b.appended += len(rules)
+	return b.StorageBackend.Append(conn, key, rules)
+}
+
+func TestBackendWithLayout(t *testing.T) {
+	for _, layout := range []Layout{PTypeSetLayout, HashLayout, ZSetLayout} {
+		appended := 0
+		config := &Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_backend_layout", Layout: layout}
+		config.Backend = countingBackend{StorageBackend: layoutBackend(config), appended: &appended}
+		a, err := NewAdapter(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.dropTable()
+
+		source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+		if err = a.SavePolicy(source.GetModel()); err != nil {
+			t.Fatal(err)
+		}
+		if err = a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err = a.RemoveFilteredPolicy("p", "p", 0, "bob"); err != nil {
+			t.Fatal(err)
+		}
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+
+		if appended != 6 {
+			t.Errorf("rules appended with layout %d = %d, supposed to be 6", layout, appended)
+		}
+		if err = a.EnsureKey(); err != nil {
+			t.Errorf("EnsureKey() with layout %d = %v", layout, err)
+		}
+		a.dropTable()
+		a.Close()
+	}
+}
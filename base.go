@@ -0,0 +1,48 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// loadBase merges the rules of Config.BaseAdapter into model, after the rules
+// loaded from Redis. A base rule also stored in Redis is skipped, so Redis wins:
+// the rule keeps its position among the Redis rules, which matters to policy
+// effects taking the first matching rule, such as priority(p.eft) || deny.
+func (a *Adapter) loadBase(m model.Model) error {
+	if a.baseAdapter == nil {
+		return nil
+	}
+
+	base := m.Copy()
+	base.ClearPolicy()
+	if err := a.baseAdapter.LoadPolicy(base); err != nil {
+		return fmt.Errorf("base adapter: %w", err)
+	}
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range base[sec] {
+			for _, rule := range ast.Policy {
+				if err := persist.LoadPolicyArray(append([]string{ptype}, rule...), m); err != nil {
+					return fmt.Errorf("base adapter: %w", err)
+				}
+			}
+		}
+	}
+	return nil
+}
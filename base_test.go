@@ -0,0 +1,65 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+)
+
+func TestBaseAdapter(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_base"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	// Overrides: a new rule and one of the base rules.
+	if err = a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err = NewAdapter(&Config{
+		Network:     "tcp",
+		Address:     "127.0.0.1:6379",
+		Key:         "casbin_rules_base",
+		BaseAdapter: fileadapter.NewAdapter("examples/rbac_policy.csv"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Redis rules come first, the base rule stored in Redis too is loaded once.
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}, {"bob", "data2", "write"}, {"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if ok, _ := e.Enforce("alice", "data2", "read"); !ok {
+		t.Error("alice cannot read data2 through the base grouping rule")
+	}
+
+	// Writes only go to Redis.
+	if _, err = e.AddPolicy("dave", "data4", "write"); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := a.GetAllPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 {
+		t.Errorf("Redis holds %v, supposed to hold the 3 rules added to it", rules)
+	}
+}
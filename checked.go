@@ -0,0 +1,179 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// removeCheckedScript is removeOnceScript returning, for each value in ARGV,
+// 1 if an occurrence of it was removed and 0 otherwise.
+var removeCheckedScript = newWriteScript(1, `
+	local key = KEYS[1]
+
+	local pending = {}
+	local status = {}
+	for i=1, #ARGV do
+		pending[ARGV[i]] = pending[ARGV[i]] or {}
+		table.insert(pending[ARGV[i]], i)
+		status[i] = 0
+	end
+	local removed = 0
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		local waiting = pending[r[i]]
+		if waiting and #waiting > 0 then
+			redis.call('lset', key, i-1, '`+tombstone+`')
+			status[table.remove(waiting, 1)] = 1
+			removed = removed + 1
+		end
+	end
+	if removed > 0 then
+		redis.call('lrem', key, 0, '`+tombstone+`')
+	end
+	return status
+`)
+
+// softDeleteCheckedScript is softDeleteScript returning, for each rule in
+// ARGV[2:], 1 if it was stored and not deleted yet, and 0 otherwise.
+var softDeleteCheckedScript = newWriteScript(2, countStored+`
+	local deleted = KEYS[2]
+
+	for i=2, #ARGV do
+		if (stored[ARGV[i]] or 0) > 1 then
+			return redis.error_reply('`+duplicatePrefix+` ' .. ARGV[i])
+		end
+	end
+	local status = {}
+	for i=2, #ARGV do
+		status[i-1] = 0
+		if stored[ARGV[i]] then
+			status[i-1] = redis.call('zadd', deleted, 'NX', ARGV[1], ARGV[i])
+		end
+	end
+	return status
+`)
+
+// RemovePoliciesChecked is RemovePolicies reporting, for each rule, whether it
+// was stored and removed, e.g. for reconciliation to tell which expected rules
+// were missing. A rule given twice is only removed twice if it is stored twice.
+// The list is scanned once by a Lua script. It is not supported by StreamLayout,
+// nor with Config.Backend or Config.SplitSections.
+func (a *Adapter) RemovePoliciesChecked(sec string, ptype string, rules [][]string) ([]bool, error) {
+	return a.RemovePoliciesCheckedCtx(context.Background(), sec, ptype, rules)
+}
+
+// RemovePoliciesCheckedCtx is RemovePoliciesChecked with a context carrying the
+// actor of the change, see WithActor. The context also bounds waiting for the
+// write rate limit.
+func (a *Adapter) RemovePoliciesCheckedCtx(ctx context.Context, sec string, ptype string, rules [][]string) (removed []bool, err error) {
+	defer a.observe("RemovePoliciesChecked", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+	defer a.changed(ctx, &err, AuditEntry{Op: AuditRemove, Sec: sec, PType: ptype, Rules: rules})
+
+	if err := checkRules(rules); err != nil {
+		return nil, err
+	}
+	if err := a.waitWrite(ctx); err != nil {
+		return nil, err
+	}
+	if !a.ownBackend() || a.splitSections {
+		return nil, errLayoutUnsupported
+	}
+	if len(rules) == 0 {
+		return []bool{}, nil
+	}
+
+	texts, err := a.marshalMembers(ptype, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer a.release(conn)
+
+	if a.lookupStoredForms() {
+		if texts, err = a.storedForms(conn, texts); err != nil {
+			return nil, err
+		}
+	}
+	var status []int
+	switch {
+	case a.layout == PTypeSetLayout:
+		status, err = a.removeEach(conn, "SREM", a.ptypeKey(ptype), texts)
+	case a.layout == HashLayout:
+		status, err = a.removeEach(conn, "HDEL", a.key, stringsToBytes(ruleDigests(texts)))
+	case a.layout == ZSetLayout:
+		status, err = a.removeEach(conn, "ZREM", a.key, texts)
+	case a.softDelete:
+		args := redis.Args{}.Add(a.key, a.deletedKey(), deletionTime(time.Now())).AddFlat(texts)
+		status, err = redis.Ints(a.doScript(softDeleteCheckedScript, conn, args...))
+		err = duplicateError(err)
+	case a.disableLua:
+		status, err = a.removeEach(conn, "LREM", a.key, texts)
+	default:
+		status, err = redis.Ints(a.doScript(removeCheckedScript, conn, redis.Args{}.Add(a.key).AddFlat(texts)...))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	removed = make([]bool, len(texts))
+	var removedTexts [][]byte
+	for i := range removed {
+		removed[i] = i < len(status) && status[i] > 0
+		if removed[i] {
+			removedTexts = append(removedTexts, texts[i])
+		}
+	}
+	if a.layout == PTypeSetLayout || a.layout == HashLayout || a.layout == ZSetLayout || a.softDelete {
+		return removed, nil
+	}
+	return removed, a.forgetCreated(conn, removedTexts)
+}
+
+// removeEach removes each of texts from key with command, SREM or LREM with a
+// count of 1, in a transaction, and returns the number of removed values.
+func (a *Adapter) removeEach(conn redis.Conn, command, key string, texts [][]byte) ([]int, error) {
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	for _, text := range texts {
+		args := redis.Args{}.Add(key)
+		if command == "LREM" {
+			args = args.Add(1)
+		}
+		if err := conn.Send(command, args.Add(text)...); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := conn.Do("EXEC")
+	if err == nil {
+		err = execError(reply)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return redis.Ints(reply, nil)
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestRemovePoliciesChecked(t *testing.T) {
+	for _, config := range []*Config{
+		{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_checked"},
+		{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_checked_set", Layout: PTypeSetLayout},
+	} {
+		a, err := NewAdapter(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.dropTable()
+		initPolicy(t, a)
+
+		removed, err := a.RemovePoliciesChecked("p", "p", [][]string{
+			{"alice", "data1", "read"},
+			{"carol", "data3", "read"},
+			{"data2_admin", "data2", "write"},
+			{"alice", "data1", "read"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The second alice rule is absent, the only stored one was removed first.
+		if want := []bool{true, false, true, false}; !reflect.DeepEqual(removed, want) {
+			t.Errorf("RemovePoliciesChecked() with layout %d = %v, supposed to be %v", config.Layout, removed, want)
+		}
+
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}})
+	}
+}
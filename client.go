@@ -0,0 +1,90 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Client is the client the adapter runs its commands through. Each operation
+// takes a connection with Get, sends its commands and scripts over it, scripts
+// running with EVALSHA and EVAL, and gives it back with Put. The redigo pool of
+// Config.Pool or of the adapter, and the client over REST, implement it.
+// Other Redis clients implement it, with connections implementing redis.Conn,
+// to back an adapter created with NewAdapterWithClient; see the goredis
+// package for github.com/redis/go-redis.
+type Client interface {
+	// Get returns a connection for one operation, waiting for a free one while
+	// ctx allows.
+	Get(ctx context.Context) (redis.Conn, error)
+	// Put gives back a connection returned by Get.
+	Put(conn redis.Conn)
+	// Close releases the connections of the client.
+	Close() error
+}
+
+// poolClient is the Client of a redigo pool.
+type poolClient struct {
+	pool        *redis.Pool
+	waitTimeout time.Duration // see Config.PoolWaitTimeout
+}
+
+// Get discards the pooled connections that are already broken, e.g. those
+// dialed while the server was down, and fetches another one.
+func (c poolClient) Get(ctx context.Context) (redis.Conn, error) {
+	if c.waitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.waitTimeout)
+		defer cancel()
+	}
+	var err error
+	for i := 0; i < maxConnAttempts; i++ {
+		conn, _ := c.pool.GetContext(ctx)
+		if err = conn.Err(); err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("waiting for a pooled connection: %w", err)
+		}
+		// The pool does not keep connections that are closed in an error state.
+		conn.Close()
+	}
+	return nil, err
+}
+
+func (c poolClient) Put(conn redis.Conn) {
+	conn.Close()
+}
+
+func (c poolClient) Close() error {
+	return c.pool.Close()
+}
+
+// NewAdapterWithClient creates an adapter running its commands through client.
+// Of the options, those dialing the server are ignored, e.g. WithKey applies
+// but WithAddress does not. Closing the adapter closes client.
+func NewAdapterWithClient(client Client, opts ...Option) (*Adapter, error) {
+	return NewAdapterWithOption(append(opts, withClient(client))...)
+}
+
+func withClient(client Client) Option {
+	return func(a *Adapter) {
+		a.client = client
+	}
+}
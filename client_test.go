@@ -0,0 +1,114 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// countingClient is a Client handing out conn and counting the
+// connections in use.
+type countingClient struct {
+	conn redis.Conn
+
+	mu          sync.Mutex
+	gets, inUse int
+	closed      bool
+}
+
+func (c *countingClient) Get(context.Context) (redis.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	c.inUse++
+	return c.conn, nil
+}
+
+func (c *countingClient) Put(redis.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inUse--
+}
+
+func (c *countingClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func TestRedisClient(t *testing.T) {
+	server := httptest.NewServer(&fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}})
+	defer server.Close()
+	a, err := NewAdapter(&Config{RestURL: server.URL, RestToken: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &countingClient{conn: newRestConn(http.DefaultClient, server.URL, "secret")}
+	a.client = client
+
+	if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.gets == 0 || client.inUse != 0 || !client.closed {
+		t.Errorf("client got %d connections, %d still in use, closed %v, supposed to get and put back every connection and be closed",
+			client.gets, client.inUse, client.closed)
+	}
+}
+
+func TestNewAdapterWithClient(t *testing.T) {
+	server := httptest.NewServer(&fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}})
+	defer server.Close()
+	client := &countingClient{conn: newRestConn(http.DefaultClient, server.URL, "secret")}
+	a, err := NewAdapterWithClient(client, WithKey("casbin_rules_client"), WithAddress("ignored:6379"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if a.KeyLayout()["main"] != "casbin_rules_client" {
+		t.Errorf("key = %q, supposed to be set by WithKey", a.KeyLayout()["main"])
+	}
+	if err = a.WarmPool(1); err == nil {
+		t.Error("WarmPool() without a pool succeeded")
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.gets == 0 || client.inUse != 0 || !client.closed {
+		t.Errorf("client got %d connections, %d still in use, closed %v", client.gets, client.inUse, client.closed)
+	}
+}
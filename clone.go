@@ -0,0 +1,66 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// CloneWithKey returns an adapter like a but for the policy key key, e.g. one
+// adapter per environment, sharing the connections of a rather than dialing
+// its own. KeyPrefix applies to key as to Config.Key. The clone has a state of
+// its own: it is not filtered by the loads of a, and closing it leaves the
+// connections open for a. Closing a closes them for the clone as well, unless a
+// leaves them open, see Config.ClosePoolOnShutdown. An adapter of
+// NewAdapterWithConn cannot be cloned, as its single connection would be shared.
+func (a *Adapter) CloneWithKey(key string) (*Adapter, error) {
+	if strings.TrimSpace(key) == "" {
+		return nil, fmt.Errorf("Key %q is empty or white space: %w", key, ErrInvalidKey)
+	}
+	if a.singleConn {
+		return nil, errors.New("an adapter of NewAdapterWithConn cannot be cloned")
+	}
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	clone := *a
+	clone.key = a.prefixed(key)
+	clone.keyMu = &sync.RWMutex{}
+	clone.state = newLifecycle()
+	clone.ownsPool = false
+	// The finalizer of a would close the connections of the clone.
+	clone.origin = a
+	clone.isFiltered = 0
+	clone.mergedKeys = nil
+	for _, merged := range a.mergedKeys {
+		if merged != clone.key {
+			clone.mergedKeys = append(clone.mergedKeys, merged)
+		}
+	}
+	// The caches and the fencing token hold for the key of a.
+	if a.negativeCache != nil {
+		clone.negativeCache = newNegativeCache(a.negativeCache.ttl)
+	}
+	if a.fenceToken != nil {
+		clone.fenceToken = new(int64)
+	}
+	clone.fieldLimitWarned = &sync.Map{}
+	return &clone, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestCloneWithKey(t *testing.T) {
+	server := &routedServer{}
+	l := listen(t)
+	go serve(l, server.reply)
+	var dials int32
+	a, err := NewAdapter(&Config{Key: "casbin_rules_prod", DialFunc: func() (redis.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return redis.Dial("tcp", l.Addr().String())
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var clones []*Adapter
+	for _, key := range []string{"casbin_rules_staging", "casbin_rules_flags", "casbin_rules_canary"} {
+		clone, err := a.CloneWithKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clones = append(clones, clone)
+	}
+	for _, b := range append([]*Adapter{a}, clones...) {
+		if err = b.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var pushed []string
+	for _, command := range server.take() {
+		if strings.HasPrefix(command, "RPUSH ") {
+			pushed = append(pushed, strings.TrimPrefix(command, "RPUSH "))
+		}
+	}
+	want := []string{"casbin_rules_prod", "casbin_rules_staging", "casbin_rules_flags", "casbin_rules_canary"}
+	if !reflect.DeepEqual(pushed, want) {
+		t.Errorf("rules added to %v, supposed to be %v", pushed, want)
+	}
+	if n := atomic.LoadInt32(&dials); n != 1 {
+		t.Errorf("dialed %d connections, supposed to share 1", n)
+	}
+
+	// The clones are filtered on their own.
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = clones[0].LoadFilteredPolicy(m, &Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !clones[0].IsFiltered() || a.IsFiltered() || clones[1].IsFiltered() {
+		t.Error("a filtered load of a clone filtered other adapters")
+	}
+
+	// Closing a clone leaves the connections open for the others.
+	if err = clones[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = clones[0].AddPolicy("p", "p", []string{"bob", "data2", "write"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("AddPolicy() of a closed clone = %v, supposed to fail with ErrClosed", err)
+	}
+	if err = clones[1].AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Errorf("AddPolicy() after closing another clone = %v", err)
+	}
+	if _, err = a.Version(); err != nil {
+		t.Errorf("Version() after closing a clone = %v", err)
+	}
+
+	if _, err = a.CloneWithKey(" "); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("CloneWithKey() of a white space key = %v, supposed to fail with ErrInvalidKey", err)
+	}
+	single, err := NewAdapterWithConn(noScriptConn{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = single.CloneWithKey("casbin_rules_staging"); err == nil {
+		t.Error("CloneWithKey() of an adapter of NewAdapterWithConn succeeded")
+	}
+}
+
+// TestCloneWithKeyRedis checks with CLIENT LIST that clones do not open
+// connections of their own.
+func TestCloneWithKeyRedis(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_clone_a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	clients := func() int {
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer a.release(conn)
+		list, err := redis.String(conn.Do("CLIENT", "LIST"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(list, "\n")
+	}
+	before := clients()
+
+	b, err := a.CloneWithKey("casbin_rules_clone_b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	initPolicy(t, a)
+	initPolicy(t, b)
+	if after := clients(); after > before {
+		t.Errorf("CLIENT LIST grew from %d to %d connections with a clone", before, after)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// tenantCodec is a toy Codec storing the fields of a rule separated by unit
+// separators after the tenant, e.g. "acme\x1fp\x1falice\x1fdata1\x1fread".
+type tenantCodec struct {
+	tenant           string
+	encodes, decodes *int64
+}
+
+func newTenantCodec(tenant string) tenantCodec {
+	return tenantCodec{tenant: tenant, encodes: new(int64), decodes: new(int64)}
+}
+
+func (c tenantCodec) Encode(line CasbinRule) ([]byte, error) {
+	atomic.AddInt64(c.encodes, 1)
+	fields := []string{c.tenant, line.PType, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	for len(fields) > 2 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	return []byte(strings.Join(fields, "\x1f")), nil
+}
+
+func (c tenantCodec) Decode(text []byte) (CasbinRule, error) {
+	atomic.AddInt64(c.decodes, 1)
+	fields := strings.Split(string(text), "\x1f")
+	if fields[0] != c.tenant || len(fields) > 8 {
+		return CasbinRule{}, fmt.Errorf("rule %q is not of tenant %s", text, c.tenant)
+	}
+	return savePolicyLine(fields[1], fields[2:]), nil
+}
+
+// testCodecStorage saves and loads the policy with a, which uses codec, and
+// checks that codec encoded the stored rules. TestSuite runs the other tests.
+func testCodecStorage(t *testing.T, a *Adapter, codec tenantCodec, stored func() [][]byte) {
+	t.Helper()
+	initPolicy(t, a)
+
+	if atomic.LoadInt64(codec.encodes) == 0 || atomic.LoadInt64(codec.decodes) == 0 {
+		t.Errorf("codec encoded %d and decoded %d rules, supposed to be used", *codec.encodes, *codec.decodes)
+	}
+	values := stored()
+	if len(values) == 0 {
+		t.Error("no rules stored")
+	}
+	for _, value := range values {
+		if !bytes.HasPrefix(value, []byte(codec.tenant+"\x1f")) {
+			t.Errorf("stored rule %q was not encoded by the codec", value)
+		}
+	}
+}
+
+func TestJSONCodec(t *testing.T) {
+	rule := CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}
+	for _, test := range []struct {
+		codec JSONCodec
+		want  string
+	}{
+		{JSONCodec{}, `{"PType":"p","V0":"alice","V1":"data1","V2":"read","V3":"","V4":"","V5":""}`},
+		{JSONCodec{Keys: LowercaseJSONKeys}, `{"ptype":"p","v0":"alice","v1":"data1","v2":"read","v3":"","v4":"","v5":""}`},
+	} {
+		text, err := test.codec.Encode(rule)
+		if err != nil || string(text) != test.want {
+			t.Errorf("Encode() = %s, %v, supposed to be %s", text, err, test.want)
+		}
+		if line, err := test.codec.Decode(text); err != nil || line != rule {
+			t.Errorf("Decode(%s) = %+v, %v, supposed to be %+v", text, line, err, rule)
+		}
+	}
+}
+
+func TestCodec(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// The fake REST server does not know EVAL, so the rules are removed and
+	// updated by rewriting the list.
+	codec := newTenantCodec("acme")
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", DisableLua: true, Codec: codec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCodecStorage(t, a, codec, func() [][]byte {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		var values [][]byte
+		for _, value := range server.lists["casbin_rules"] {
+			values = append(values, []byte(value))
+		}
+		return values
+	})
+
+	// Rules of another tenant fail to decode.
+	other, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", Codec: newTenantCodec("globex")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = other.GetAllPolicies(); err == nil {
+		t.Error("GetAllPolicies() of rules of another tenant succeeded, supposed to fail")
+	}
+}
+
+func TestCodecRedis(t *testing.T) {
+	for _, layout := range []Layout{ListLayout, PTypeSetLayout, HashLayout} {
+		codec := newTenantCodec("acme")
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_codec", Layout: layout, Codec: codec})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testCodecStorage(t, a, codec, func() [][]byte {
+			conn, err := a.getConn()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.release(conn)
+			var values [][]byte
+			switch layout {
+			case PTypeSetLayout:
+				for _, ptype := range []string{"p", "g"} {
+					members, err := redis.ByteSlices(conn.Do("SMEMBERS", a.ptypeKey(ptype)))
+					if err != nil {
+						t.Fatal(err)
+					}
+					values = append(values, members...)
+				}
+			case HashLayout:
+				values, err = redis.ByteSlices(conn.Do("HVALS", a.key))
+			default:
+				values, err = redis.ByteSlices(conn.Do("LRANGE", a.key, 0, -1))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			return values
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// KeysEqual reports whether the policy lists under keyA and keyB hold the same
+// rules, ignoring their order and duplicates, e.g. to verify a policy built under
+// a temporary key before renaming it over the live one. KeyPrefix applies to both
+// keys. It is not supported by PTypeSetLayout and StreamLayout.
+func (a *Adapter) KeysEqual(keyA, keyB string) (bool, error) {
+	if err := a.begin(); err != nil {
+		return false, err
+	}
+	defer a.end()
+
+	if a.layout != ListLayout {
+		return false, errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return false, err
+	}
+	defer a.release(conn)
+
+	rulesA, err := a.ruleSet(conn, a.prefixed(keyA))
+	if err != nil {
+		return false, err
+	}
+	rulesB, err := a.ruleSet(conn, a.prefixed(keyB))
+	if err != nil {
+		return false, err
+	}
+
+	if len(rulesA) != len(rulesB) {
+		return false, nil
+	}
+	for rule := range rulesA {
+		if _, ok := rulesB[rule]; !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ruleSet returns the set of rules stored under key. Rules are compared decoded,
+// so equal rules match whatever their serialization.
+func (a *Adapter) ruleSet(conn redis.Conn, key string) (map[CasbinRule]struct{}, error) {
+	texts, err := a.loadKeyValues(conn, key)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", key, err)
+	}
+
+	rules := make(map[CasbinRule]struct{}, len(texts))
+	var line CasbinRule
+	for _, text := range texts {
+		if err = a.unmarshal(text, &line); err != nil {
+			return nil, fmt.Errorf("key %s: %w", key, err)
+		}
+		rules[line] = struct{}{}
+	}
+	return rules, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestKeysEqual(t *testing.T) {
+	save := func(key string, rules [][]string) *Adapter {
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+		e.ClearPolicy()
+		e.AddPolicies(rules)
+		if err = a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	a := save("casbin_rules_equal_a", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+	save("casbin_rules_equal_b", [][]string{{"bob", "data2", "write"}, {"alice", "data1", "read"}})
+	save("casbin_rules_equal_c", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "read"}})
+
+	equal, err := a.KeysEqual("casbin_rules_equal_a", "casbin_rules_equal_b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Error("KeysEqual() of the same rules in a different order = false, supposed to be true")
+	}
+
+	equal, err = a.KeysEqual("casbin_rules_equal_a", "casbin_rules_equal_c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equal {
+		t.Error("KeysEqual() of different rules = true, supposed to be false")
+	}
+
+	equal, err = a.KeysEqual("casbin_rules_equal_a", "casbin_rules_equal_missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equal {
+		t.Error("KeysEqual() with a missing key = true, supposed to be false")
+	}
+}
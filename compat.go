@@ -0,0 +1,75 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// lookupStoredForms reports whether removals and updates look up the stored
+// form of the rules, see storedForms.
+func (a *Adapter) lookupStoredForms() bool {
+	return a.compatOfficial || a.compression != NoCompression || a.cipher != nil
+}
+
+// storedForms returns texts with each rule replaced by the form it is stored in,
+// for Config.CompatOfficialAdapter, Config.Compression and Config.EncryptionKey.
+// Rules written by another adapter can decode to the same rule while differing
+// in bytes, e.g. by the casing of the keys, the order of the fields or white
+// space, as can rules written before the compression was set or encrypted with
+// another nonce, and the scripts removing and replacing rules compare bytes.
+// Rules not stored are returned as they are.
+func (a *Adapter) storedForms(conn redis.Conn, texts [][]byte) ([][]byte, error) {
+	stored, err := a.loadValues(conn)
+	if err != nil {
+		return nil, err
+	}
+	forms := make(map[CasbinRule][]byte, len(stored))
+	var line CasbinRule
+	for _, text := range stored {
+		if err = a.unmarshal(text, &line); err != nil {
+			return nil, err
+		}
+		if _, ok := forms[line]; !ok {
+			forms[line] = text
+		}
+	}
+
+	ret := make([][]byte, len(texts))
+	for i, text := range texts {
+		if err = a.unmarshal(text, &line); err != nil {
+			return nil, err
+		}
+		if form, ok := forms[line]; ok {
+			ret[i] = form
+		} else {
+			ret[i] = text
+		}
+	}
+	return ret, nil
+}
+
+// validateCompat checks that CompatOfficialAdapter stores the JSON rules of the
+// official adapter in a plain list.
+func (c *Config) validateCompat(v *configCheck) {
+	if !c.CompatOfficialAdapter {
+		return
+	}
+	v.exclude("CompatOfficialAdapter", configOption{"an Encoding other than JSONEncoding", c.Encoding != JSONEncoding},
+		configOption{"custom JSONKeys", c.JSONKeys != JSONKeys{} && c.JSONKeys != DefaultJSONKeys},
+		configOption{"Codec", c.Codec != nil}, configOption{"a Layout other than ListLayout", c.Layout != ListLayout},
+		configOption{"Backend", c.Backend != nil}, configOption{"SoftDelete", c.SoftDelete},
+		configOption{"RawPatternMatching", c.RawPatternMatching})
+}
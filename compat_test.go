@@ -0,0 +1,120 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// officialRows are the rules of examples/rbac_policy.csv as the official adapter
+// stores them, followed by a rule written by another client with lowercase keys
+// in another order.
+var officialRows = []string{
+	`{"PType":"p","V0":"alice","V1":"data1","V2":"read","V3":"","V4":"","V5":""}`,
+	`{"PType":"p","V0":"bob","V1":"data2","V2":"write","V3":"","V4":"","V5":""}`,
+	`{"PType":"p","V0":"data2_admin","V1":"data2","V2":"read","V3":"","V4":"","V5":""}`,
+	`{"PType":"p","V0":"data2_admin","V1":"data2","V2":"write","V3":"","V4":"","V5":""}`,
+	`{"PType":"g","V0":"alice","V1":"data2_admin","V2":"","V3":"","V4":"","V5":""}`,
+	`{"v0": "carol", "ptype": "p", "v1": "data3", "v2": "read"}`,
+}
+
+func TestCompatOfficialAdapter(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{"casbin_rules": append([]string{}, officialRows...)}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", CompatOfficialAdapter: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+
+	// The rows are matched by their decoded fields, not their bytes.
+	e.ClearPolicy()
+	if err = a.LoadFilteredPolicy(e.GetModel(), &Filter{PType: []string{"p"}, V1: []string{"data3"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}})
+
+	// Rules are written as the official adapter writes them, and removed in the
+	// form they are stored in.
+	if err = a.AddPolicy("p", "p", []string{"dave", "data4", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{officialRows[0], officialRows[2], officialRows[3], officialRows[4],
+		`{"PType":"p","V0":"dave","V1":"data4","V2":"read","V3":"","V4":"","V5":""}`}
+	server.mu.Lock()
+	stored := server.lists["casbin_rules"]
+	server.mu.Unlock()
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("stored rows = %q, supposed to be %q", stored, want)
+	}
+
+	if _, err = NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", CompatOfficialAdapter: true, JSONKeys: LowercaseJSONKeys}); err == nil {
+		t.Error("NewAdapter() with CompatOfficialAdapter and LowercaseJSONKeys succeeded, supposed to fail")
+	}
+}
+
+func TestCompatOfficialAdapterRedis(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_compat", CompatOfficialAdapter: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	if _, err = conn.Do("RPUSH", redis.Args{}.Add(a.key).AddFlat(officialRows)...); err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if _, err = e.UpdatePolicy([]string{"carol", "data3", "read"}, []string{"carol", "data3", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.UpdateFilteredPolicies([][]string{{"bob", "data5", "write"}}, 0, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemoveFilteredPolicy(0, "data2_admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data5", "write"}, {"carol", "data3", "write"}})
+
+	stored, err := redis.Strings(conn.Do("LRANGE", a.key, 0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{officialRows[0],
+		`{"PType":"p","V0":"bob","V1":"data5","V2":"write","V3":"","V4":"","V5":""}`,
+		officialRows[4],
+		`{"PType":"p","V0":"carol","V1":"data3","V2":"write","V3":"","V4":"","V5":""}`}
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("stored rows = %q, supposed to be %q", stored, want)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"sync"
+)
+
+// Compression selects how the serialized rules are compressed in Redis.
+type Compression int
+
+const (
+	// NoCompression stores the rules as serialized. It is the default.
+	NoCompression Compression = iota
+	// GzipCompression compresses the rules with gzip, which shrinks them the
+	// most but costs the most CPU.
+	GzipCompression
+	// SnappyCompression compresses the rules with the snappy block format, which
+	// is much faster than gzip but shrinks them less.
+	SnappyCompression
+)
+
+// compressedPrefix starts the compressed values, followed by a byte naming the
+// compression. No serialized rule starts with a zero byte, so values written
+// before Compression was set are told apart and still read.
+const compressedPrefix = "\x00z"
+
+const (
+	gzipMark   = 'g'
+	snappyMark = 's'
+)
+
+var errUnknownCompression = errors.New("unknown compression of stored rule")
+
+// gzipWriters and gzipReaders hold gzip writers and readers to reset, as new
+// ones allocate large tables, which would cost more than compressing a rule.
+var (
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+	gzipReaders sync.Pool
+)
+
+// compress compresses text with the configured compression. Texts that do not
+// shrink, e.g. short rules under gzip, are stored as they are. The output only
+// depends on text, as removals compare the stored bytes.
+func (a *Adapter) compress(text []byte) ([]byte, error) {
+	var packed []byte
+	switch a.compression {
+	case GzipCompression:
+		var buf bytes.Buffer
+		buf.WriteString(compressedPrefix)
+		buf.WriteByte(gzipMark)
+		w := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(w)
+		w.Reset(&buf)
+		if _, err := w.Write(text); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		packed = buf.Bytes()
+	case SnappyCompression:
+		packed = append([]byte(compressedPrefix+string(snappyMark)), snappyEncode(text)...)
+	default:
+		return text, nil
+	}
+	if len(packed) >= len(text) {
+		return text, nil
+	}
+	return packed, nil
+}
+
+// decompress returns the serialized rule of a stored value, whatever the
+// configured compression.
+func decompress(text []byte) ([]byte, error) {
+	if !bytes.HasPrefix(text, []byte(compressedPrefix)) || len(text) == len(compressedPrefix) {
+		return text, nil
+	}
+	data := text[len(compressedPrefix)+1:]
+	switch text[len(compressedPrefix)] {
+	case gzipMark:
+		r, ok := gzipReaders.Get().(*gzip.Reader)
+		var err error
+		if ok {
+			err = r.Reset(bytes.NewReader(data))
+		} else {
+			r, err = gzip.NewReader(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReaders.Put(r)
+		return ioutil.ReadAll(r)
+	case snappyMark:
+		return snappyDecode(data)
+	}
+	return nil, errUnknownCompression
+}
+
+// validateCompression checks Compression and the options it cannot be combined
+// with.
+func (c *Config) validateCompression(v *configCheck) {
+	switch c.Compression {
+	case NoCompression:
+		return
+	case GzipCompression, SnappyCompression:
+	default:
+		v.report(ErrInvalidValue, "unknown compression %d", c.Compression)
+	}
+	v.exclude("Compression", configOption{"a Layout other than ListLayout", c.Layout != ListLayout},
+		configOption{"Backend", c.Backend != nil}, configOption{"SoftDelete", c.SoftDelete},
+		configOption{"RawPatternMatching", c.RawPatternMatching},
+		configOption{"CompatOfficialAdapter", c.CompatOfficialAdapter}, configOption{"RestURL", c.RestURL != ""})
+}
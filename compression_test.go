@@ -0,0 +1,191 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// longPathRule returns a rule with long object paths, which compress well.
+func longPathRule(i int) CasbinRule {
+	return CasbinRule{PType: "p", V0: fmt.Sprintf("user%d", i%1000),
+		V1: fmt.Sprintf("/api/v2/organizations/org%d/projects/project%d/environments/production/resources/*", i%50, i%200),
+		V2: "read"}
+}
+
+func TestSnappyRoundTrip(t *testing.T) {
+	random := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(random)
+	for _, src := range [][]byte{
+		nil,
+		[]byte("a"),
+		[]byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		[]byte(`{"PType":"p","V0":"alice","V1":"data1","V2":"read","V3":"","V4":"","V5":""}`),
+		bytes.Repeat([]byte("/api/v2/resources/"), 5000),
+		random,
+	} {
+		packed := snappyEncode(src)
+		got, err := snappyDecode(packed)
+		if err != nil || !bytes.Equal(got, src) {
+			t.Errorf("snappyDecode(snappyEncode(%.20q)) = %.20q, %v", src, got, err)
+		}
+	}
+
+	// A literal of 3 bytes claiming 4, and a copy before the start.
+	for _, corrupt := range [][]byte{{4, 3 << 2, 'a', 'b', 'c'}, {4, 0, 'a', 1<<2 | snappyCopy2, 2, 0}} {
+		if got, err := snappyDecode(corrupt); err == nil {
+			t.Errorf("snappyDecode(%v) = %q, supposed to fail", corrupt, got)
+		}
+	}
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	long := longPathRule(1)
+	short := CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}
+	plain := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}}
+	legacy, _ := plain.marshal(long)
+
+	for _, compression := range []Compression{GzipCompression, SnappyCompression} {
+		for _, encoding := range []Encoding{JSONEncoding, GobEncoding, CSVEncoding} {
+			a := &Adapter{jsonKeys: DefaultJSONKeys, encoding: encoding, codec: newCodec(encoding, DefaultJSONKeys), compression: compression}
+			encoded, _ := a.encode(long)
+			text, err := a.marshal(long)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(text) > len(encoded) || (encoding == JSONEncoding && !bytes.HasPrefix(text, []byte(compressedPrefix))) {
+				t.Errorf("compression %d, encoding %d: %d bytes stored for %d encoded, supposed to be at most as long", compression, encoding, len(text), len(encoded))
+			}
+			if again, _ := a.marshal(long); !bytes.Equal(again, text) {
+				t.Errorf("compression %d, encoding %d: marshal() is not deterministic", compression, encoding)
+			}
+			var line CasbinRule
+			if err = a.unmarshal(text, &line); err != nil || line != long {
+				t.Errorf("compression %d, encoding %d: unmarshal() = %+v, %v, supposed to be %+v", compression, encoding, line, err, long)
+			}
+		}
+
+		// Rules that do not shrink are stored as encoded, and rules stored
+		// before the compression was set are still read.
+		a := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}, compression: compression}
+		text, _ := a.marshal(short)
+		if encoded, _ := a.encode(short); compression == GzipCompression && !bytes.Equal(text, encoded) {
+			t.Errorf("marshal() of a short rule with gzip = %q, supposed to be %q", text, encoded)
+		}
+		var line CasbinRule
+		if err := a.unmarshal(legacy, &line); err != nil || line != long {
+			t.Errorf("compression %d: unmarshal() of an uncompressed rule = %+v, %v", compression, line, err)
+		}
+	}
+
+	var line CasbinRule
+	if err := plain.unmarshal([]byte(compressedPrefix+"x..."), &line); err == nil {
+		t.Error("unmarshal() of an unknown compression succeeded, supposed to fail")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_compressed", Compression: SnappyCompression})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+
+	// A rule written before the compression was set.
+	plain := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}}
+	legacy, _ := plain.marshal(longPathRule(0))
+	if _, err = conn.Do("RPUSH", a.key, legacy); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	for i := 1; i < 4; i++ {
+		line := longPathRule(i)
+		if _, err = e.AddPolicy(line.V0, line.V1, line.V2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	texts, err := redis.ByteSlices(conn.Do("LRANGE", a.key, 0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range texts[1:] {
+		if !bytes.HasPrefix(text, []byte(compressedPrefix)) {
+			t.Errorf("stored rule %q is not compressed", text)
+		}
+	}
+
+	removed := longPathRule(0)
+	if _, err = e.RemovePolicy(removed.V0, removed.V1, removed.V2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemoveFilteredPolicy(0, longPathRule(1).V0); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	want := make([][]string, 0, 2)
+	for i := 2; i < 4; i++ {
+		line := longPathRule(i)
+		want = append(want, []string{line.V0, line.V1, line.V2})
+	}
+	testGetPolicy(t, e, want)
+}
+
+// BenchmarkCompression reports the bytes stored per rule of a 100k-rule policy
+// with long object paths, and the time to marshal and unmarshal all of them.
+//
+// Each rule is compressed on its own, so only what repeats within a rule is
+// saved. On an x86-64 server, the 154 bytes of JSON of a rule are stored in 138
+// bytes with gzip, at about 5 times the time of JSON alone, and in 151 bytes
+// with snappy, at about 1.3 times.
+func BenchmarkCompression(b *testing.B) {
+	const n = 100000
+	for _, compression := range []Compression{NoCompression, GzipCompression, SnappyCompression} {
+		compression := compression
+		b.Run(fmt.Sprintf("compression=%d", compression), func(b *testing.B) {
+			a := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}, compression: compression}
+			var stored int
+			var line CasbinRule
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				stored = 0
+				for j := 0; j < n; j++ {
+					text, err := a.marshal(longPathRule(j))
+					if err != nil {
+						b.Fatal(err)
+					}
+					if err = a.unmarshal(text, &line); err != nil {
+						b.Fatal(err)
+					}
+					stored += len(text)
+				}
+			}
+			b.ReportMetric(float64(stored)/n, "stored-B/rule")
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NewAdapterWithConn creates an adapter using conn, a connection dialed and
+// authenticated by the caller, who keeps owning it: neither Close nor the
+// finalizer of the adapter close it. A connection cannot be used by several
+// goroutines at once, so each operation of the adapter waits until no other
+// one uses it. Of the options, those dialing the server are ignored, e.g.
+// WithKey applies but WithAddress does not. The adapter cannot be cloned with
+// CloneWithKey.
+func NewAdapterWithConn(conn redis.Conn, opts ...Option) (*Adapter, error) {
+	// A pool of the single connection makes the operations take turns.
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return borrowedConn{conn}, nil
+		},
+		MaxIdle:   1,
+		MaxActive: 1,
+		Wait:      true,
+	}
+	a, err := NewAdapterWithOption(append(opts, WithPool(pool))...)
+	if err != nil {
+		return nil, err
+	}
+	a.singleConn = true
+	return a, nil
+}
+
+// borrowedConn is a connection of the caller, which closing leaves open.
+type borrowedConn struct {
+	redis.Conn
+}
+
+func (borrowedConn) Close() error {
+	return nil
+}
+
+func (c borrowedConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+}
+
+func (c borrowedConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func (c borrowedConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	return redis.DoContext(c.Conn, ctx, commandName, args...)
+}
+
+func (c borrowedConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return redis.ReceiveContext(c.Conn, ctx)
+}
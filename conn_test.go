@@ -0,0 +1,77 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestNewAdapterWithConn(t *testing.T) {
+	l := listen(t)
+	defer l.Close()
+	var mu sync.Mutex
+	var keys []string
+	go serveNil(l, func(args []string) {
+		if args[0] == "RPUSH" {
+			mu.Lock()
+			keys = append(keys, args[1])
+			mu.Unlock()
+		}
+	})
+
+	conn, err := redis.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	a, err := NewAdapterWithConn(conn, WithKey("casbin_rules_conn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The operations take turns on the connection, replies are not mixed up.
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- a.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data", "read"})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	if len(keys) != 20 || keys[0] != "casbin_rules_conn" {
+		t.Errorf("RPUSH to %q, supposed to push 20 times to casbin_rules_conn", keys)
+	}
+	mu.Unlock()
+
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("PING"); err != nil {
+		t.Errorf("conn.Do() after closing the adapter = %v, supposed to leave the connection open", err)
+	}
+}
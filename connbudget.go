@@ -0,0 +1,68 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+// ConnBudget caps the number of connections in use at once by the adapters
+// sharing it, so that many adapters talking to one server, each with its own
+// pool, stay within a global connection budget. Create one budget with
+// NewConnBudget and pass it as Config.ConnBudget to every adapter that should
+// share it. An operation needing a connection while the budget is used up waits
+// until another operation releases its connection.
+//
+// Only connections in use are counted. Idle connections kept by pools are not,
+// so bound them with Pool.MaxIdle to keep the total connection count in check.
+type ConnBudget struct {
+	slots chan struct{}
+}
+
+// NewConnBudget returns a budget of n connections in use at once.
+func NewConnBudget(n int) *ConnBudget {
+	if n < 1 {
+		n = 1
+	}
+	return &ConnBudget{slots: make(chan struct{}, n)}
+}
+
+// acquire waits for a free slot.
+func (b *ConnBudget) acquire() {
+	if b != nil {
+		b.slots <- struct{}{}
+	}
+}
+
+// tryAcquire takes a free slot if there is one, without waiting.
+func (b *ConnBudget) tryAcquire() bool {
+	if b == nil {
+		return true
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a slot taken by acquire.
+func (b *ConnBudget) release() {
+	if b != nil {
+		<-b.slots
+	}
+}
+
+// InUse returns the number of connections currently in use under the budget.
+func (b *ConnBudget) InUse() int {
+	return len(b.slots)
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestConnBudget(t *testing.T) {
+	budget := NewConnBudget(1)
+	newAdapter := func() *Adapter {
+		pool := &redis.Pool{Dial: func() (redis.Conn, error) { return noScriptConn{}, nil }}
+		a, err := NewAdapter(&Config{Pool: pool, ConnBudget: budget})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	a1, a2 := newAdapter(), newAdapter()
+
+	conn, err := a1.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if budget.InUse() != 1 {
+		t.Fatalf("InUse() = %d, supposed to be 1", budget.InUse())
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := a2.Version()
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("the second adapter got a connection beyond the budget")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	a1.release(conn)
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the second adapter did not get the released connection")
+	}
+	if budget.InUse() != 0 {
+		t.Errorf("InUse() = %d after every operation finished, supposed to be 0", budget.InUse())
+	}
+}
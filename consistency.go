@@ -0,0 +1,132 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Consistency selects whether a read goes to Config.ReadPool or to the primary.
+type Consistency int
+
+const (
+	// Eventual reads from Config.ReadPool, which may lag behind the primary and
+	// miss recent writes. It is the default.
+	Eventual Consistency = iota
+	// Strong reads from the primary, so the read sees every committed write,
+	// e.g. to load the policy right after changing it.
+	Strong
+)
+
+type consistencyKey struct{}
+
+// WithConsistency returns a copy of ctx selecting the consistency of the reads
+// of the context-aware load methods, such as LoadPolicyCtx. It only matters
+// with Config.ReadPool, reads go to the primary otherwise.
+func WithConsistency(ctx context.Context, consistency Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, consistency)
+}
+
+// consistencyFromContext returns the consistency set by WithConsistency, or Eventual.
+func consistencyFromContext(ctx context.Context) Consistency {
+	if consistency, ok := ctx.Value(consistencyKey{}).(Consistency); ok {
+		return consistency
+	}
+	return Eventual
+}
+
+// LoadPolicyFromPrimary is LoadPolicy reading from the primary even with
+// Config.ReadPool, so the loaded policy reflects every committed write.
+func (a *Adapter) LoadPolicyFromPrimary(model model.Model) error {
+	return a.LoadPolicyCtx(WithConsistency(context.Background(), Strong), model)
+}
+
+// LoadFilteredPolicyFromPrimary is LoadFilteredPolicy reading from the primary
+// even with Config.ReadPool.
+func (a *Adapter) LoadFilteredPolicyFromPrimary(model model.Model, filter interface{}) error {
+	return a.LoadFilteredPolicyCtx(WithConsistency(context.Background(), Strong), model, filter)
+}
+
+// replicaConn is a connection of Config.ReadPool, which release closes whatever
+// the primary connection is.
+type replicaConn struct {
+	redis.Conn
+}
+
+// read runs fetch, a read-only operation, over a connection of Config.ReadPool
+// unless ctx asks for Strong consistency, of the primary otherwise. If the
+// read pool fails, fetch runs again over a connection of the primary, and the
+// error reports both failures if that fails too.
+func (a *Adapter) read(ctx context.Context, fetch func(conn redis.Conn) error) error {
+	if a.readPool == nil || consistencyFromContext(ctx) == Strong {
+		return a.readPrimary(ctx, fetch)
+	}
+
+	conn, err := a.getReplicaConn(ctx)
+	if err == nil {
+		err = fetch(conn)
+		a.release(conn)
+	}
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	if primaryErr := a.readPrimary(ctx, fetch); primaryErr != nil {
+		return fmt.Errorf("read pool: %v; primary: %w", err, primaryErr)
+	}
+	a.logger.Printf("redis-adapter: read from the primary, the read pool failed: %v", err)
+	return nil
+}
+
+func (a *Adapter) readPrimary(ctx context.Context, fetch func(conn redis.Conn) error) error {
+	conn, err := a.getConnCtx(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+	return fetch(conn)
+}
+
+// getReplicaConn returns a connection of Config.ReadPool.
+func (a *Adapter) getReplicaConn(ctx context.Context) (redis.Conn, error) {
+	a.connBudget.acquire()
+	return a.budgetedReplicaConn(ctx)
+}
+
+// budgetedReplicaConn is budgetedConn for Config.ReadPool.
+func (a *Adapter) budgetedReplicaConn(ctx context.Context) (redis.Conn, error) {
+	conn, err := a.readPool.GetContext(ctx)
+	if err == nil {
+		err = conn.Err()
+	}
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		a.connBudget.release()
+		return nil, err
+	}
+	if a.commandHook != nil {
+		conn = hookConn{Conn: conn, hook: a.commandHook}
+	}
+	if a.operationTimeout > 0 {
+		conn = timeoutConn{Conn: conn, ctx: ctx, deadline: time.Now().Add(a.operationTimeout), timeout: a.operationTimeout}
+	}
+	return replicaConn{Conn: conn}, nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestConsistency(t *testing.T) {
+	// The replica is a copy of the primary that is only updated when the test
+	// replicates, so it lags behind until then.
+	primary := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	primaryServer := httptest.NewServer(primary)
+	defer primaryServer.Close()
+	replica := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	replicaServer := httptest.NewServer(replica)
+	defer replicaServer.Close()
+	replicate := func() {
+		primary.mu.Lock()
+		defer primary.mu.Unlock()
+		replica.mu.Lock()
+		defer replica.mu.Unlock()
+		for key, list := range primary.lists {
+			replica.lists[key] = append([]string{}, list...)
+		}
+	}
+
+	readPool := &redis.Pool{Dial: func() (redis.Conn, error) { return newRestConn(http.DefaultClient, replicaServer.URL, "secret"), nil }}
+	a, err := NewAdapter(&Config{RestURL: primaryServer.URL, RestToken: "secret", ReadPool: readPool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err = a.SavePolicy(source.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	replicate()
+
+	// A write the replica has not caught up with yet.
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	stale := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}
+	fresh := append(stale, []string{"carol", "data3", "read"})
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, stale)
+
+	if err = a.LoadPolicyFromPrimary(e.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, fresh)
+
+	e.ClearPolicy()
+	if err = a.LoadPolicyCtx(WithConsistency(context.Background(), Strong), e.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, fresh)
+
+	e.ClearPolicy()
+	if err = a.LoadFilteredPolicyFromPrimary(e.GetModel(), &Filter{V0: []string{"carol"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}})
+	e.ClearPolicy()
+	if err = a.LoadFilteredPolicy(e.GetModel(), &Filter{V0: []string{"carol"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{})
+
+	// Once the replica caught up, eventual reads see the write as well.
+	replicate()
+	e.ClearPolicy()
+	if err = a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, fresh)
+}
+
+// routedServer stores nothing and records the commands it gets with their key,
+// or fails them while down.
+type routedServer struct {
+	mu       sync.Mutex
+	commands []string
+	down     bool
+}
+
+func (s *routedServer) reply(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return "-ERR server down\r\n"
+	}
+	s.commands = append(s.commands, args[0]+" "+args[1])
+	if args[0] == "LRANGE" {
+		return "*0\r\n"
+	}
+	return ":0\r\n"
+}
+
+// take returns the commands recorded since the last call.
+func (s *routedServer) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	commands := s.commands
+	s.commands = nil
+	return commands
+}
+
+func (s *routedServer) setDown(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+// pool starts the server and returns a pool of connections to it.
+func (s *routedServer) pool(t *testing.T) *redis.Pool {
+	l := listen(t)
+	go serve(l, s.reply)
+	return &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", l.Addr().String()) }}
+}
+
+func TestReadPoolRouting(t *testing.T) {
+	primary, replica := &routedServer{}, &routedServer{}
+	a, err := NewAdapter(&Config{Pool: primary.pool(t), ReadPool: replica.pool(t), Keys: []string{"casbin_rules_a", "casbin_rules_b"}, LoadConcurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(server *routedServer, name string, want []string) {
+		t.Helper()
+		got := server.take()
+		if len(want) == 0 && len(got) == 0 {
+			return
+		}
+		// The keys of Config.Keys are loaded concurrently.
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s got %q, supposed to get %q", name, got, want)
+		}
+	}
+	loads := []string{"LLEN casbin_rules", "LLEN casbin_rules_a", "LLEN casbin_rules_b", "LRANGE casbin_rules", "LRANGE casbin_rules_a", "LRANGE casbin_rules_b"}
+
+	if err = a.LoadPolicy(m); err != nil {
+		t.Fatal(err)
+	}
+	check(replica, "replica", loads)
+	check(primary, "primary", nil)
+
+	if err = a.LoadFilteredPolicy(m, &Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	check(replica, "replica", loads)
+	check(primary, "primary", nil)
+
+	if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	check(replica, "replica", nil)
+	check(primary, "primary", []string{"INCR casbin_rules:version", "RPUSH casbin_rules"})
+
+	if err = a.LoadPolicyFromPrimary(m); err != nil {
+		t.Fatal(err)
+	}
+	check(replica, "replica", nil)
+	check(primary, "primary", loads)
+}
+
+func TestReadPoolFallback(t *testing.T) {
+	primary, replica := &routedServer{}, &routedServer{}
+	logger := &recordingLogger{}
+	a, err := NewAdapter(&Config{Pool: primary.pool(t), ReadPool: replica.pool(t), Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replica.setDown(true)
+	if err = a.LoadPolicy(m); err != nil {
+		t.Fatalf("LoadPolicy() with the read pool down = %v, supposed to read from the primary", err)
+	}
+	if got := primary.take(); !reflect.DeepEqual(got, []string{"LLEN casbin_rules", "LRANGE casbin_rules"}) {
+		t.Errorf("primary got %q, supposed to serve the read", got)
+	}
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "server down") {
+		t.Errorf("logged %q, supposed to log the failure of the read pool", logger.messages)
+	}
+
+	primary.setDown(true)
+	err = a.LoadPolicy(m)
+	if err == nil || !strings.Contains(err.Error(), "read pool: ") || !strings.Contains(err.Error(), "primary: ") {
+		t.Errorf("LoadPolicy() with both down = %v, supposed to report both failures", err)
+	}
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) {
+		t.Errorf("LoadPolicy() = %v, supposed to wrap the error of the primary", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// csvNeedsQuotes reports whether a field of a CSV line must be quoted to be read
+// back as is: it holds a separator, a quote or a line break, or white space the
+// reader would trim or casbin tooling would strip.
+func csvNeedsQuotes(field string) bool {
+	return strings.ContainsAny(field, ",\"\r\n") || strings.TrimSpace(field) != field
+}
+
+// marshalCSV returns fields as a casbin CSV line, e.g. "p, alice, data1, read",
+// without the trailing empty fields. Fields that need it are quoted, with their
+// quotes doubled, e.g. p, alice, "data1,data2", read.
+func marshalCSV(fields []string) []byte {
+	for len(fields) > 1 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	var buf bytes.Buffer
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if csvNeedsQuotes(field) {
+			buf.WriteByte('"')
+			buf.WriteString(strings.Replace(field, `"`, `""`, -1))
+			buf.WriteByte('"')
+		} else {
+			buf.WriteString(field)
+		}
+	}
+	return buf.Bytes()
+}
+
+// marshalCSVRule serializes a rule as a CSV line starting with its ptype.
+func marshalCSVRule(line CasbinRule) []byte {
+	return marshalCSV([]string{line.PType, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5})
+}
+
+// unmarshalCSV returns the fields of a CSV line written by marshalCSV, or
+// patched by hand: white space before the fields is ignored.
+func unmarshalCSV(text []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(text))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	fields, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV rule %q: %w", text, err)
+	}
+	if _, err = r.Read(); err == nil {
+		return nil, fmt.Errorf("invalid CSV rule %q: several lines", text)
+	}
+	return fields, nil
+}
+
+// unmarshalCSVRule deserializes a CSV line holding a ptype and up to 6 fields.
+func unmarshalCSVRule(text []byte, line *CasbinRule) error {
+	fields, err := unmarshalCSV(text)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 7 {
+		return fmt.Errorf("invalid CSV rule %q: %d fields", text, len(fields)-1)
+	}
+	*line = savePolicyLine(fields[0], fields[1:])
+	return nil
+}
+
+// MigrateToCSV converts the JSON-encoded rules of the policy list in place to
+// the CSV lines of CSVEncoding, e.g. before switching an existing deployment to
+// CSVEncoding. Rules already in CSV are left as they are, and the version is
+// left as is. It is only supported by CSVEncoding with ListLayout, without
+// Config.Codec, Config.Backend, SoftDelete and TrackCreationOrder, which record the rules in
+// further keys.
+func (a *Adapter) MigrateToCSV() error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if a.encoding != CSVEncoding || a.customCodec || !a.plainList() || a.softDelete || a.trackOrder {
+		return errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	// The list is watched, so rules written meanwhile by a client still using
+	// JSONEncoding are not lost.
+	for i := 0; i < maxRewriteAttempts; i++ {
+		if _, err = conn.Do("WATCH", a.key); err != nil {
+			return err
+		}
+		values, err := redis.ByteSlices(conn.Do("LRANGE", a.key, 0, -1))
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+		texts := make([][]byte, 0, len(values))
+		for _, value := range values {
+			if string(value) == tombstone {
+				continue
+			}
+			plain, err := a.restore(value)
+			if err != nil {
+				_, _ = conn.Do("UNWATCH")
+				return err
+			}
+			if bytes.HasPrefix(plain, []byte("{")) {
+				var line CasbinRule
+				if err = a.unmarshalJSON(plain, &line); err != nil {
+					_, _ = conn.Do("UNWATCH")
+					return err
+				}
+				if value, err = a.store(marshalCSVRule(line)); err != nil {
+					_, _ = conn.Do("UNWATCH")
+					return err
+				}
+			}
+			texts = append(texts, value)
+		}
+
+		if err = conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if err = conn.Send("DEL", a.key); err != nil {
+			return err
+		}
+		if len(texts) > 0 {
+			if err = conn.Send("RPUSH", redis.Args{}.Add(a.key).AddFlat(texts)...); err != nil {
+				return err
+			}
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return execError(reply)
+		}
+	}
+	return errors.New("policy kept changing while it was migrated")
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	a := &Adapter{encoding: CSVEncoding, codec: csvCodec{}}
+
+	tests := []struct {
+		line CasbinRule
+		text string
+	}{
+		{CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}, "p, alice, data1, read"},
+		{CasbinRule{PType: "g", V0: "alice", V1: "data2_admin"}, "g, alice, data2_admin"},
+		{CasbinRule{PType: "p", V0: "bob", V2: "write", V5: "deny"}, "p, bob, , write, , , deny"},
+		{CasbinRule{PType: "p", V0: "carol", V1: "data1,data2", V2: `say "hi"`}, `p, carol, "data1,data2", "say ""hi"""`},
+		{CasbinRule{PType: "p", V0: " dave", V1: "two\nlines"}, "p, \" dave\", \"two\nlines\""},
+	}
+	var line CasbinRule
+	for _, test := range tests {
+		text, err := a.marshal(test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != test.text {
+			t.Errorf("marshal(%+v) = %q, supposed to be %q", test.line, text, test.text)
+		}
+		if err = a.unmarshal(text, &line); err != nil {
+			t.Fatal(err)
+		}
+		if line != test.line {
+			t.Errorf("round trip of %+v = %+v", test.line, line)
+		}
+	}
+
+	// Lines patched by hand may be spaced differently.
+	if err := a.unmarshal([]byte(`p,alice,   "data1,data2",read`), &line); err != nil {
+		t.Fatal(err)
+	}
+	if want := (CasbinRule{PType: "p", V0: "alice", V1: "data1,data2", V2: "read"}); line != want {
+		t.Errorf("unmarshal of a respaced line = %+v, supposed to be %+v", line, want)
+	}
+	for _, text := range []string{`p, "alice`, "p, a, b, c, d, e, f, g", "p, alice\ng, bob"} {
+		if err := a.unmarshal([]byte(text), &line); err == nil {
+			t.Errorf("unmarshal(%q) succeeded, supposed to fail", text)
+		}
+	}
+
+	// The members of PTypeSetLayout leave the ptype out.
+	member, _ := a.marshalMember(tests[3].line)
+	if string(member) != `carol, "data1,data2", "say ""hi"""` {
+		t.Errorf("marshalMember() = %q", member)
+	}
+	if err := a.unmarshalMember("p", member, &line); err != nil || line != tests[3].line {
+		t.Errorf("unmarshalMember() = %+v, %v, supposed to be %+v", line, err, tests[3].line)
+	}
+}
+
+func TestCSVEncoding(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_csv", Encoding: CSVEncoding})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initPolicy(t, a)
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	first, err := redis.String(conn.Do("LINDEX", a.key, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != "p, alice, data1, read" {
+		t.Errorf("stored rule = %q, supposed to be a CSV line", first)
+	}
+
+	// Values holding commas are matched as a whole.
+	if err = a.AddPolicy("p", "p", []string{"carol", "data1,data2", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemoveFilteredPolicy("p", "p", 1, "data1"); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data1,data2", "read"}})
+}
+
+func TestMigrateToCSV(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	jsonAdapter, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, jsonAdapter)
+	server.mu.Lock()
+	server.lists["casbin_rules"] = append(server.lists["casbin_rules"], "p, carol, data3, read")
+	server.mu.Unlock()
+
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", Encoding: CSVEncoding})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.MigrateToCSV(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"p, alice, data1, read", "p, bob, data2, write", "p, data2_admin, data2, read", "p, data2_admin, data2, write", "g, alice, data2_admin", "p, carol, data3, read"}
+	server.mu.Lock()
+	stored := server.lists["casbin_rules"]
+	server.mu.Unlock()
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("migrated list = %q, supposed to be %q", stored, want)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+	if err = a.RemovePolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = jsonAdapter.MigrateToCSV(); err != errLayoutUnsupported {
+		t.Errorf("MigrateToCSV() with JSONEncoding = %v, supposed to be unsupported", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// distinctFieldScript returns the distinct non-empty values of the field ARGV[1]
+// of the JSON-encoded rules in the list, skipping tombstones (ARGV[2]) and, if
+// ARGV[3] is "1", the soft-deleted rules recorded in KEYS[2].
+var distinctFieldScript = redis.NewScript(2, `
+	local key = KEYS[1]
+	local deleted = KEYS[2]
+
+	local seen = {}
+	local values = {}
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if r[i] ~= ARGV[2] and (ARGV[3] ~= '1' or not redis.call('zscore', deleted, r[i])) then
+			local v = cjson.decode(r[i])[ARGV[1]]
+			if type(v) == 'string' and v ~= '' and not seen[v] then
+				seen[v] = true
+				values[#values+1] = v
+			end
+		end
+	end
+	return values
+`)
+
+// DistinctV0 returns the distinct V0 values of the stored rules, sorted, e.g. the
+// subjects having any policy. With JSONEncoding they are collected server-side,
+// so the rules are not transferred, unless Config.Codec,
+// Config.CompatOfficialAdapter, Config.Compression or Config.EncryptionKey is
+// set. Rules stored under Config.Keys are not included. It is only supported by
+// ListLayout without Config.Backend.
+func (a *Adapter) DistinctV0() ([]string, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	if !a.plainList() {
+		return nil, errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return nil, err
+	}
+	defer a.release(conn)
+
+	var values []string
+	if a.encoding == JSONEncoding && !a.customCodec && !a.disableLua && !a.compatOfficial && a.compression == NoCompression && a.cipher == nil {
+		softDelete := "0"
+		if a.softDelete {
+			softDelete = "1"
+		}
+		values, err = redis.Strings(a.doScript(distinctFieldScript, conn, a.key, a.deletedKey(), a.jsonKeys[1], tombstone, softDelete))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		texts, err := a.loadValues(conn)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]struct{})
+		var line CasbinRule
+		for _, text := range texts {
+			if err = a.unmarshal(text, &line); err != nil {
+				return nil, err
+			}
+			if _, ok := seen[line.V0]; !ok && line.V0 != "" {
+				seen[line.V0] = struct{}{}
+				values = append(values, line.V0)
+			}
+		}
+	}
+	sort.Strings(values)
+	return values, nil
+}
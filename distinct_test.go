@@ -0,0 +1,42 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistinctV0(t *testing.T) {
+	// JSON rules are collected server-side, gob rules client-side.
+	for _, encoding := range []Encoding{JSONEncoding, GobEncoding} {
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_distinct", Encoding: encoding})
+		if err != nil {
+			t.Fatal(err)
+		}
+		initPolicy(t, a)
+		if err = a.AddPolicy("p", "p", []string{"alice", "data3", "read"}); err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := a.DistinctV0()
+		if err != nil {
+			t.Fatalf("DistinctV0() = %v", err)
+		}
+		if want := []string{"alice", "bob", "data2_admin"}; !reflect.DeepEqual(values, want) {
+			t.Errorf("DistinctV0() with encoding %v = %v, supposed to be %v", encoding, values, want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Encoding selects how rules are serialized in Redis.
+type Encoding int
+
+const (
+	// JSONEncoding stores each rule as a JSON object. It is the default and can be
+	// read by any language.
+	JSONEncoding Encoding = iota
+	// GobEncoding stores each rule with encoding/gob. It is more compact and faster
+	// to decode, but the data can only be read by Go programs. Filtered operations
+	// decode and compare every rule instead of matching patterns in Redis.
+	GobEncoding
+	// CSVEncoding stores each rule as its line in a casbin CSV file, e.g.
+	// "p, alice, data1, read", which is easy to read and patch with redis-cli.
+	// Values holding a comma, a quote, a line break or surrounding white space
+	// are quoted, doubling their quotes, e.g. p, alice, "data1,data2", read.
+	// Filtered operations decode and compare every rule, like with GobEncoding.
+	// MigrateToCSV converts JSON-encoded rules.
+	CSVEncoding
+)
+
+// JSONKeys are the keys of the PType and V0 to V5 fields of a JSON-encoded rule,
+// in this order.
+type JSONKeys [7]string
+
+var (
+	// DefaultJSONKeys are the keys of the fields of CasbinRule, e.g.
+	// {"PType":"p","V0":"alice",...}.
+	DefaultJSONKeys = JSONKeys{"PType", "V0", "V1", "V2", "V3", "V4", "V5"}
+	// LowercaseJSONKeys are lowercase keys, e.g. {"ptype":"p","v0":"alice",...}.
+	LowercaseJSONKeys = JSONKeys{"ptype", "v0", "v1", "v2", "v3", "v4", "v5"}
+)
+
+// validate checks that the keys are set and distinct.
+func (keys JSONKeys) validate() error {
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			return fmt.Errorf("invalid JSON keys: %q", keys)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// patternFormat returns a format for fmt.Sprintf matching a JSON-encoded rule,
+// with the keys escaped by quote and a %s verb for the pattern of each value.
+func (keys JSONKeys) patternFormat(quote func(string) string) string {
+	fields := make([]string, len(keys))
+	for i, key := range keys {
+		name, _ := json.Marshal(key)
+		fields[i] = strings.Replace(quote(string(name)), "%", "%%", -1) + `:"%s"`
+	}
+	return strings.Join(fields, ",")
+}
+
+// marshal serializes a rule with the configured encoding, compression and
+// encryption.
+func (a *Adapter) marshal(line CasbinRule) ([]byte, error) {
+	text, err := a.encode(line)
+	if err != nil {
+		return nil, err
+	}
+	return a.store(text)
+}
+
+// encode serializes a rule with the configured codec.
+func (a *Adapter) encode(line CasbinRule) ([]byte, error) {
+	return a.codec.Encode(line)
+}
+
+// unmarshal deserializes a rule with the configured codec.
+func (a *Adapter) unmarshal(text []byte, line *CasbinRule) error {
+	text, err := a.restore(text)
+	if err != nil {
+		*line = CasbinRule{}
+		return err
+	}
+	*line, err = a.codec.Decode(text)
+	return err
+}
+
+// unmarshalJSON deserializes a JSON-encoded rule.
+func (a *Adapter) unmarshalJSON(text []byte, line *CasbinRule) error {
+	var err error
+	*line, err = JSONCodec{Keys: a.jsonKeys}.Decode(text)
+	return err
+}
+
+// removeValuesScript removes every occurrence of the values in ARGV.
+var removeValuesScript = newWriteScript(1, `
+	local key = KEYS[1]
+
+	local set = {}
+	for i=1, #ARGV do
+		set[ARGV[i]] = true
+	end
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if set[r[i]] then
+			redis.call('lset', key, i-1, '__CASBIN_DELETED__')
+		end
+	end
+	redis.call('lrem', key, 0, '__CASBIN_DELETED__')
+	return
+`)
+
+// replaceValuesScript replaces the occurrences of the ARGV[1] values following it
+// in place by the remaining values, in order. Occurrences left over are removed,
+// and values left over are appended.
+var replaceValuesScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local n = tonumber(ARGV[1])
+
+	local set = {}
+	for i=2, n+1 do
+		set[ARGV[i]] = true
+	end
+	local m = #ARGV - n - 1
+	local replaced = 0
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if set[r[i]] then
+			replaced = replaced + 1
+			if replaced <= m then
+				redis.call('lset', key, i-1, ARGV[n+1+replaced])
+			else
+				redis.call('lset', key, i-1, '__CASBIN_DELETED__')
+			end
+		end
+	end
+	if replaced > m then
+		redis.call('lrem', key, 0, '__CASBIN_DELETED__')
+	end
+	for i=replaced+1, m do
+		redis.call('rpush', key, ARGV[n+1+i])
+	end
+	return
+`)
+
+// fieldValuesMatcher returns a function reporting whether a rule matches the
+// arguments of RemoveFilteredPolicy, an empty field value matching anything.
+func fieldValuesMatcher(ptype string, fieldIndex int, fieldValues ...string) func(line *CasbinRule) bool {
+	return func(line *CasbinRule) bool {
+		if line.PType != ptype {
+			return false
+		}
+		fields := [6]string{line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+		for i, v := range fieldValues {
+			idx := fieldIndex + i
+			if idx < 0 || idx >= len(fields) || v == "" {
+				continue
+			}
+			if fields[idx] != v {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// findMatching decodes the stored rules and returns the matching ones along
+// with their serialized form. The backend of ListLayout selects them, unless
+// soft-deleted rules are to be left out.
+func (a *Adapter) findMatching(conn redis.Conn, match func(line *CasbinRule) bool) ([][]byte, []CasbinRule, error) {
+	var texts [][]byte
+	var err error
+	if a.layout == ListLayout && !a.softDelete {
+		texts, err = a.backend.LoadMatching(conn, a.key, RuleMatch{Match: func(text []byte) (bool, error) {
+			var line CasbinRule
+			if err := a.unmarshal(text, &line); err != nil {
+				return false, err
+			}
+			return match(&line), nil
+		}})
+	} else {
+		texts, err = a.loadValues(conn)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var matched [][]byte
+	var lines []CasbinRule
+	for _, text := range texts {
+		var line CasbinRule
+		if err := a.unmarshal(text, &line); err != nil {
+			return nil, nil, err
+		}
+		if match(&line) {
+			matched = append(matched, text)
+			lines = append(lines, line)
+		}
+	}
+	return matched, lines, nil
+}
+
+// removeFilteredDecoded is RemoveFilteredPolicy for encodings that cannot be
+// matched by a Lua pattern.
+func (a *Adapter) removeFilteredDecoded(conn redis.Conn, ptype string, fieldIndex int, fieldValues ...string) error {
+	matched, _, err := a.findMatching(conn, fieldValuesMatcher(ptype, fieldIndex, fieldValues...))
+	if err != nil || len(matched) == 0 {
+		return err
+	}
+
+	if a.softDelete {
+		return a.markDeleted(conn, matched)
+	}
+	if a.disableLua {
+		err = a.rewriteRemove(conn, matched)
+	} else {
+		_, err = a.doScript(removeValuesScript, conn, redis.Args{}.Add(a.key).AddFlat(matched)...)
+	}
+	if err != nil {
+		return err
+	}
+	return a.forgetCreated(conn, matched)
+}
+
+// updateFilteredDecoded is UpdateFilteredPolicies for encodings that cannot be
+// matched by a Lua pattern. It returns the replaced rules.
+func (a *Adapter) updateFilteredDecoded(conn redis.Conn, ptype string, newTexts []string, fieldIndex int, fieldValues ...string) ([]CasbinRule, error) {
+	matched, lines, err := a.findMatching(conn, fieldValuesMatcher(ptype, fieldIndex, fieldValues...))
+	if err != nil {
+		return nil, err
+	}
+
+	if a.disableLua {
+		err = a.rewriteReplace(conn, matched, newTexts)
+	} else {
+		args := redis.Args{}.Add(a.key, len(matched)).AddFlat(matched).AddFlat(newTexts)
+		_, err = a.doScript(replaceValuesScript, conn, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = a.carryCreated(conn, matched, stringsToBytes(newTexts)); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestGobRoundTrip(t *testing.T) {
+	a := &Adapter{encoding: GobEncoding, codec: gobCodec{}}
+
+	lines := []CasbinRule{
+		{PType: "p", V0: "alice", V1: "data1", V2: "read"},
+		{PType: "g", V0: "alice", V1: "data2_admin"},
+		{PType: "p", V0: "bob", V1: "", V2: "write", V5: "deny"},
+	}
+
+	// Decode into the same variable, as the load loop does.
+	var line CasbinRule
+	for _, want := range lines {
+		text, err := a.marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = a.unmarshal(text, &line); err != nil {
+			t.Fatal(err)
+		}
+		if line != want {
+			t.Errorf("round trip of %+v = %+v", want, line)
+		}
+	}
+
+	// Equal rules must serialize to equal bytes, removals match by value.
+	text1, _ := a.marshal(lines[0])
+	text2, _ := a.marshal(lines[0])
+	if !bytes.Equal(text1, text2) {
+		t.Error("gob encoding is not deterministic")
+	}
+}
+
+func TestGobEncoding(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_gob", Encoding: GobEncoding})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initPolicy(t, a)
+
+	// The stored rules are not JSON.
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	text, err := redis.Bytes(conn.Do("LINDEX", a.key, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(text) == 0 || text[0] == '{' {
+		t.Errorf("stored rule %q is not gob encoded", text)
+	}
+}
+
+func TestUnknownEncoding(t *testing.T) {
+	_, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Encoding: Encoding(42)})
+	if err == nil {
+		t.Error("NewAdapter should fail with an unknown encoding")
+	}
+}
+
+func TestJSONKeysPatterns(t *testing.T) {
+	filter := &Filter{PType: []string{"p"}, V0: []string{"alice", "bob"}}
+	want := `^\{"PType":"(?:p)","V0":"(?:alice|bob)","V1":".*","V2":".*","V3":".*","V4":".*","V5":".*"\}$`
+	if got := filterToRegexPattern(filter, DefaultJSONKeys); got != want {
+		t.Errorf("filterToRegexPattern() = %s, supposed to be %s", got, want)
+	}
+	want = `^\{"ptype":"(?:p)","v0":"(?:alice|bob)","v1":".*","v2":".*","v3":".*","v4":".*","v5":".*"\}$`
+	if got := filterToRegexPattern(filter, LowercaseJSONKeys); got != want {
+		t.Errorf("filterToRegexPattern() = %s, supposed to be %s", got, want)
+	}
+
+	want = `^{"ptype":"p","v0":".*","v1":"data1","v2":".*","v3":".*","v4":".*","v5":".*"}$`
+	if got := filterFieldToLuaPattern(LowercaseJSONKeys, "p", "p", 1, "data1"); got != want {
+		t.Errorf("filterFieldToLuaPattern() = %s, supposed to be %s", got, want)
+	}
+}
+
+func TestLowercaseJSONKeys(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_lowercase", JSONKeys: LowercaseJSONKeys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := redis.String(conn.Do("LINDEX", a.key, 0))
+	a.release(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"ptype":"p","v0":"alice","v1":"data1","v2":"read","v3":"","v4":"","v5":""}`; first != want {
+		t.Errorf("stored rule = %s, supposed to be %s", first, want)
+	}
+
+	initPolicy(t, a)
+	if err = a.RemoveFilteredPolicy("p", "p", 0, "data2_admin"); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+
+	if _, err = NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", JSONKeys: JSONKeys{"ptype", "v0", "v0"}}); err == nil {
+		t.Error("NewAdapter() with invalid JSON keys succeeded, supposed to fail")
+	}
+}
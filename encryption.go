@@ -0,0 +1,188 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrWrongEncryptionKey is returned when a stored rule was encrypted with
+	// another key than Config.EncryptionKey, or Config.EncryptionKey is not set.
+	ErrWrongEncryptionKey = errors.New("rule encrypted with another key")
+	// ErrCorruptCiphertext is returned when an encrypted rule fails to decrypt
+	// with the key it was encrypted with, e.g. as it was truncated or tampered
+	// with.
+	ErrCorruptCiphertext = errors.New("encrypted rule is corrupt")
+)
+
+// encryptedPrefix starts the encrypted values, followed by the key ID, the nonce
+// and the sealed rule. Like compressedPrefix, it tells them apart from the
+// rules written before Config.EncryptionKey was set.
+const encryptedPrefix = "\x00e"
+
+// keyIDLen is the length of the key ID, the start of the SHA-256 of the key, by
+// which rules encrypted with another key are told from corrupt ones.
+const keyIDLen = 4
+
+// ruleCipher encrypts the stored rules with AES-GCM for Config.EncryptionKey.
+type ruleCipher struct {
+	aead  cipher.AEAD
+	keyID []byte
+}
+
+// newRuleCipher returns the ruleCipher of an AES-128, AES-192 or AES-256 key.
+func newRuleCipher(key []byte) (*ruleCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &ruleCipher{aead: aead, keyID: sum[:keyIDLen]}, nil
+}
+
+// seal encrypts text with a random nonce. The prefix and key ID are
+// authenticated along with it.
+func (c *ruleCipher) seal(text []byte) ([]byte, error) {
+	header := append([]byte(encryptedPrefix), c.keyID...)
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(header)+len(nonce)+len(text)+c.aead.Overhead())
+	out = append(append(out, header...), nonce...)
+	return c.aead.Seal(out, nonce, text, header), nil
+}
+
+// open decrypts a value sealed by seal.
+func (c *ruleCipher) open(value []byte) ([]byte, error) {
+	header := value[:len(encryptedPrefix)+keyIDLen]
+	if !bytes.Equal(header[len(encryptedPrefix):], c.keyID) {
+		return nil, ErrWrongEncryptionKey
+	}
+	sealed := value[len(header):]
+	if len(sealed) < c.aead.NonceSize()+c.aead.Overhead() {
+		return nil, ErrCorruptCiphertext
+	}
+	nonce := sealed[:c.aead.NonceSize()]
+	text, err := c.aead.Open(nil, nonce, sealed[len(nonce):], header)
+	if err != nil {
+		return nil, ErrCorruptCiphertext
+	}
+	return text, nil
+}
+
+// encrypted reports whether a stored value is encrypted.
+func encrypted(value []byte) bool {
+	return bytes.HasPrefix(value, []byte(encryptedPrefix))
+}
+
+// store returns the value stored for a serialized rule, compressed and
+// encrypted as configured.
+func (a *Adapter) store(text []byte) ([]byte, error) {
+	text, err := a.compress(text)
+	if err != nil || a.cipher == nil {
+		return text, err
+	}
+	return a.cipher.seal(text)
+}
+
+// restore returns the serialized rule of a stored value, decrypting and
+// decompressing it as needed. Values stored before Config.EncryptionKey was set
+// are read as they are.
+func (a *Adapter) restore(value []byte) ([]byte, error) {
+	if encrypted(value) {
+		if len(value) < len(encryptedPrefix)+keyIDLen {
+			return nil, ErrCorruptCiphertext
+		}
+		if a.cipher == nil {
+			return nil, fmt.Errorf("%w: Config.EncryptionKey is not set", ErrWrongEncryptionKey)
+		}
+		var err error
+		if value, err = a.cipher.open(value); err != nil {
+			return nil, err
+		}
+	}
+	return decompress(value)
+}
+
+// MigrateToEncrypted encrypts in place the rules of the policy list stored in
+// plaintext, e.g. after setting Config.EncryptionKey on an existing deployment.
+// Rules already encrypted are left as they are, and the version is left as is.
+// It is only supported with Config.EncryptionKey and ListLayout, without
+// Config.Backend.
+func (a *Adapter) MigrateToEncrypted() error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if a.cipher == nil || !a.plainList() {
+		return errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	// The list is watched, so rules written meanwhile by a client without the
+	// key are not lost, and encrypted in the next attempt.
+	var sealErr error
+	err = a.rewriteList(conn, func(values [][]byte) [][]byte {
+		sealed := make([][]byte, len(values))
+		for i, value := range values {
+			sealed[i] = value
+			if encrypted(value) || string(value) == tombstone {
+				continue
+			}
+			if sealed[i], sealErr = a.cipher.seal(value); sealErr != nil {
+				return values
+			}
+		}
+		return sealed
+	})
+	if sealErr != nil {
+		return sealErr
+	}
+	return err
+}
+
+// validateEncryption checks the length of EncryptionKey and the options it
+// cannot be combined with.
+func (c *Config) validateEncryption(v *configCheck) {
+	n := len(c.EncryptionKey)
+	if n == 0 {
+		return
+	}
+	if n != 16 && n != 24 && n != 32 {
+		v.report(ErrInvalidValue, "EncryptionKey of %d bytes, supposed to be 16, 24 or 32", n)
+	}
+	v.exclude("EncryptionKey", configOption{"a Layout other than ListLayout", c.Layout != ListLayout},
+		configOption{"Backend", c.Backend != nil}, configOption{"SoftDelete", c.SoftDelete},
+		configOption{"TrackCreationOrder", c.TrackCreationOrder}, configOption{"RawPatternMatching", c.RawPatternMatching},
+		configOption{"CompatOfficialAdapter", c.CompatOfficialAdapter}, configOption{"RestURL", c.RestURL != ""})
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	testEncryptionKey  = []byte("0123456789abcdef0123456789abcdef")
+	otherEncryptionKey = []byte("fedcba9876543210")
+)
+
+// encryptingAdapter returns an adapter encrypting rules with key, for tests
+// that do not need Redis.
+func encryptingAdapter(t *testing.T, key []byte, compression Compression) *Adapter {
+	t.Helper()
+	cipher, err := newRuleCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}, compression: compression, cipher: cipher}
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	rule := CasbinRule{PType: "p", V0: "alice@example.com", V1: "data1", V2: "read"}
+	for _, compression := range []Compression{NoCompression, GzipCompression, SnappyCompression} {
+		a := encryptingAdapter(t, testEncryptionKey, compression)
+		text, err := a.marshal(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !encrypted(text) || bytes.Contains(text, []byte("alice")) {
+			t.Errorf("marshal() = %q, supposed to be encrypted", text)
+		}
+		if again, _ := a.marshal(rule); bytes.Equal(again, text) {
+			t.Error("marshal() twice returned the same ciphertext, supposed to use a random nonce")
+		}
+		var line CasbinRule
+		if err = a.unmarshal(text, &line); err != nil || line != rule {
+			t.Errorf("compression %d: unmarshal() = %+v, %v, supposed to be %+v", compression, line, err, rule)
+		}
+	}
+
+	a := encryptingAdapter(t, testEncryptionKey, NoCompression)
+	text, _ := a.marshal(rule)
+	var line CasbinRule
+	if err := encryptingAdapter(t, otherEncryptionKey, NoCompression).unmarshal(text, &line); !errors.Is(err, ErrWrongEncryptionKey) {
+		t.Errorf("unmarshal() with another key = %v, supposed to be ErrWrongEncryptionKey", err)
+	}
+	plain := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}}
+	if err := plain.unmarshal(text, &line); !errors.Is(err, ErrWrongEncryptionKey) {
+		t.Errorf("unmarshal() without a key = %v, supposed to be ErrWrongEncryptionKey", err)
+	}
+	for _, corrupt := range [][]byte{
+		append(append([]byte{}, text[:len(text)-1]...), text[len(text)-1]^1),
+		text[:len(encryptedPrefix)+keyIDLen+5],
+		text[:3],
+	} {
+		if err := a.unmarshal(corrupt, &line); !errors.Is(err, ErrCorruptCiphertext) {
+			t.Errorf("unmarshal() of corrupt %q = %v, supposed to be ErrCorruptCiphertext", corrupt, err)
+		}
+	}
+
+	// Rules stored before the key was set are still read.
+	legacy, _ := plain.marshal(rule)
+	if err := a.unmarshal(legacy, &line); err != nil || line != rule {
+		t.Errorf("unmarshal() of a plaintext rule = %+v, %v, supposed to be %+v", line, err, rule)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_encrypted", EncryptionKey: testEncryptionKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+
+	// Rules written before the key was set.
+	plain := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}}
+	for _, rule := range [][]string{{"alice@example.com", "data1", "read"}, {"bob@example.com", "data2", "write"}} {
+		text, _ := plain.marshal(savePolicyLine("p", rule))
+		if _, err = conn.Do("RPUSH", a.key, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if _, err = e.AddPolicies([][]string{{"carol@example.com", "data1", "read"}, {"dave@example.com", "data3", "read"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemovePolicy("alice@example.com", "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.UpdatePolicy([]string{"carol@example.com", "data1", "read"}, []string{"carol@example.com", "data1", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemoveFilteredPolicy(1, "data3"); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob@example.com", "data2", "write"}, {"carol@example.com", "data1", "write"}})
+
+	if err = a.MigrateToEncrypted(); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := redis.ByteSlices(conn.Do("LRANGE", a.key, 0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range texts {
+		if !encrypted(text) || bytes.Contains(text, []byte("@example.com")) {
+			t.Errorf("stored rule %q is not encrypted", text)
+		}
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob@example.com", "data2", "write"}, {"carol@example.com", "data1", "write"}})
+
+	other, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_encrypted", EncryptionKey: otherEncryptionKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = casbin.NewEnforcer("examples/rbac_model.conf", other); !errors.Is(err, ErrWrongEncryptionKey) {
+		t.Errorf("LoadPolicy() with another key = %v, supposed to be ErrWrongEncryptionKey", err)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// defaultEnvPrefix is the prefix of the variables read by NewAdapterFromEnv.
+const defaultEnvPrefix = "REDIS_"
+
+// EnvOption is an option of NewAdapterFromEnv.
+type EnvOption func(*envOptions)
+
+type envOptions struct {
+	prefix string
+}
+
+// WithEnvPrefix makes NewAdapterFromEnv read the variables with prefix instead
+// of REDIS_, e.g. CASBIN_ADDRESS with "CASBIN_".
+func WithEnvPrefix(prefix string) EnvOption {
+	return func(o *envOptions) {
+		o.prefix = prefix
+	}
+}
+
+// NewAdapterFromEnv creates an adapter configured by environment variables:
+//
+//   - REDIS_ADDRESS: Config.Address, required
+//   - REDIS_NETWORK: Config.Network (default: "tcp")
+//   - REDIS_USERNAME and REDIS_PASSWORD: Config.Username and Config.Password
+//   - REDIS_KEY: Config.Key
+//   - REDIS_DB: Config.DB
+//   - REDIS_TLS: a boolean enabling TLS, e.g. "true"
+//
+// Empty variables are treated as unset.
+func NewAdapterFromEnv(opts ...EnvOption) (*Adapter, error) {
+	o := envOptions{prefix: defaultEnvPrefix}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	config, err := configFromEnv(o.prefix)
+	if err != nil {
+		return nil, err
+	}
+	return NewAdapter(config)
+}
+
+// configFromEnv returns the Config of the variables with prefix, see
+// NewAdapterFromEnv.
+func configFromEnv(prefix string) (*Config, error) {
+	env := func(name string) (string, string) {
+		name = prefix + name
+		return name, os.Getenv(name)
+	}
+
+	config := &Config{Network: "tcp"}
+	name, address := env("ADDRESS")
+	if address == "" {
+		return nil, fmt.Errorf("environment variable %s is required: %w", name, ErrMissingAddress)
+	}
+	config.Address = address
+	if _, network := env("NETWORK"); network != "" {
+		config.Network = network
+	}
+	_, config.Username = env("USERNAME")
+	_, config.Password = env("PASSWORD")
+	_, config.Key = env("KEY")
+
+	if name, db := env("DB"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: invalid database %q", name, db)
+		}
+		config.DB = n
+	}
+	if name, useTLS := env("TLS"); useTLS != "" {
+		enabled, err := strconv.ParseBool(useTLS)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: invalid boolean %q", name, useTLS)
+		}
+		if enabled {
+			config.TLSConfig = &tls.Config{}
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("CASBIN_ADDRESS", "redis.internal:6380")
+	t.Setenv("CASBIN_USERNAME", "casbin")
+	t.Setenv("CASBIN_PASSWORD", "secret")
+	t.Setenv("CASBIN_KEY", "casbin_rules_env")
+	t.Setenv("CASBIN_DB", "2")
+	t.Setenv("CASBIN_TLS", "true")
+
+	config, err := configFromEnv("CASBIN_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{Network: "tcp", Address: "redis.internal:6380", Username: "casbin", Password: "secret",
+		Key: "casbin_rules_env", DB: 2, TLSConfig: config.TLSConfig}
+	if config.TLSConfig == nil {
+		t.Error("CASBIN_TLS=true did not enable TLS")
+	}
+	if !reflect.DeepEqual(*config, want) {
+		t.Errorf("configFromEnv() = %+v, supposed to be %+v", *config, want)
+	}
+
+	t.Setenv("CASBIN_DB", "two")
+	if _, err = configFromEnv("CASBIN_"); err == nil || !strings.Contains(err.Error(), "CASBIN_DB") {
+		t.Errorf("configFromEnv() with a malformed DB = %v, supposed to name CASBIN_DB", err)
+	}
+	t.Setenv("CASBIN_DB", "")
+	t.Setenv("CASBIN_TLS", "maybe")
+	if _, err = configFromEnv("CASBIN_"); err == nil || !strings.Contains(err.Error(), "CASBIN_TLS") {
+		t.Errorf("configFromEnv() with a malformed TLS = %v, supposed to name CASBIN_TLS", err)
+	}
+
+	t.Setenv("REDIS_ADDRESS", "")
+	if _, err = NewAdapterFromEnv(); !errors.Is(err, ErrMissingAddress) || !strings.Contains(err.Error(), "REDIS_ADDRESS") {
+		t.Errorf("NewAdapterFromEnv() without REDIS_ADDRESS = %v, supposed to name REDIS_ADDRESS", err)
+	}
+}
+
+func TestNewAdapterFromEnv(t *testing.T) {
+	t.Setenv("CASBIN_ADDRESS", "127.0.0.1:6379")
+	t.Setenv("CASBIN_KEY", "casbin_rules_env")
+	a, err := NewAdapterFromEnv(WithEnvPrefix("CASBIN_"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.key != "casbin_rules_env" {
+		t.Errorf("key = %q, supposed to be casbin_rules_env", a.key)
+	}
+	initPolicy(t, a)
+}
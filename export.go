@@ -0,0 +1,151 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/gomodule/redigo/redis"
+)
+
+// exportChunkSize is the number of rules ExportReader reads from Redis at once.
+var exportChunkSize = 1000
+
+// errExportClosed is returned by reads from a closed ExportReader.
+var errExportClosed = errors.New("export reader is closed")
+
+// ExportFiltered loads the rules matching filter and saves them to dst, e.g. to
+// move the rules of one tenant to a store of its own. The rules are loaded into a
+// private copy of m, so m is left unchanged. dst is saved with SavePolicy, which
+// replaces all of its rules.
+func (a *Adapter) ExportFiltered(dst persist.Adapter, m model.Model, filter *Filter) error {
+	staged := m.Copy()
+	staged.ClearPolicy()
+	if err := a.LoadFilteredPolicy(staged, filter); err != nil {
+		return err
+	}
+	return dst.SavePolicy(staged)
+}
+
+// ExportReader returns the rules stored under the key as CSV, one rule per line
+// in the format of Casbin policy files, e.g. "p,alice,data1,read". The rules are
+// read from Redis in chunks as the returned reader is read, so the export is
+// never held in memory as a whole. Since the chunks are read separately, rules
+// written while the export is read may be missed or exported twice.
+//
+// The reader holds a connection until it is closed, and Close of the adapter
+// waits for it like for any operation in flight. It is only supported by
+// ListLayout without Config.Backend.
+func (a *Adapter) ExportReader() (io.ReadCloser, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	if !a.plainList() {
+		a.end()
+		return nil, errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		a.end()
+		return nil, err
+	}
+	r := &exportReader{a: a, conn: conn}
+	if a.softDelete {
+		if r.deleted, err = a.loadDeleted(conn, a.deletedKey()); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	r.csv = csv.NewWriter(&r.buf)
+	return r, nil
+}
+
+// exportReader is the reader returned by ExportReader.
+type exportReader struct {
+	a       *Adapter
+	conn    redis.Conn
+	deleted map[string]struct{}
+	next    int  // index of the next rule to read from Redis
+	done    bool // whether all rules were read from Redis
+	closed  bool
+	buf     bytes.Buffer // CSV lines not read yet
+	csv     *csv.Writer
+}
+
+func (r *exportReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, errExportClosed
+	}
+	for r.buf.Len() == 0 && !r.done {
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+	if r.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return r.buf.Read(p)
+}
+
+// fill reads the next chunk of rules from Redis into buf.
+func (r *exportReader) fill() error {
+	select {
+	case <-r.a.state.done:
+		return ErrClosed
+	default:
+	}
+
+	values, err := redis.ByteSlices(r.conn.Do("LRANGE", r.a.key, r.next, r.next+exportChunkSize-1))
+	if err != nil {
+		return err
+	}
+	r.next += len(values)
+	r.done = len(values) < exportChunkSize
+
+	var line CasbinRule
+	for _, text := range values {
+		if string(text) == tombstone {
+			continue
+		}
+		if _, ok := r.deleted[string(text)]; ok {
+			continue
+		}
+		if err = r.a.unmarshal(text, &line); err != nil {
+			return err
+		}
+		if err = r.csv.Write(line.toStringPolicy()); err != nil {
+			return err
+		}
+	}
+	r.csv.Flush()
+	return r.csv.Error()
+}
+
+// Close releases the connection of the reader.
+func (r *exportReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.a.release(r.conn)
+	r.a.end()
+	return nil
+}
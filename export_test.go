@@ -0,0 +1,78 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestExportFiltered(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_export_src"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+	dst, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_export_dst"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err = a.ExportFiltered(dst, e.GetModel(), &Filter{PType: []string{"p"}, V0: []string{"alice"}}); err != nil {
+		t.Fatalf("ExportFiltered() = %v", err)
+	}
+	// The source model is left unchanged.
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	e2, _ := casbin.NewEnforcer("examples/rbac_model.conf", dst)
+	testGetPolicy(t, e2, [][]string{{"alice", "data1", "read"}})
+	if groups := e2.GetGroupingPolicy(); len(groups) != 0 {
+		t.Errorf("exported grouping policy = %v, supposed to be empty", groups)
+	}
+}
+
+func TestExportReader(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_export_reader"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	// Read in chunks smaller than the policy.
+	defer func(size int) { exportChunkSize = size }(exportChunkSize)
+	exportChunkSize = 2
+
+	r, err := a.ExportReader()
+	if err != nil {
+		t.Fatalf("ExportReader() = %v", err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading the export: %v", err)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "p,alice,data1,read\np,bob,data2,write\np,data2_admin,data2,read\np,data2_admin,data2,write\ng,alice,data2_admin\n"
+	if string(data) != want {
+		t.Errorf("export = %q, supposed to be %q", data, want)
+	}
+	if _, err = r.Read(make([]byte, 1)); err == nil {
+		t.Error("Read() after Close() succeeded, supposed to fail")
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// With Config.Fencing, AcquireLeadership hands out fencing tokens: each new
+// leader takes the next value of the "fence" field of the meta key. Every write
+// carries the token of its adapter, 0 if it never led, and the server rejects it
+// if a newer leader took a higher token. Plain write commands run through
+// fencedCommand and write scripts through a copy prefixed with the check,
+// so the check and the write are atomic.
+
+// ErrFenced is returned by writes rejected because a newer leader holds the
+// fencing token, see Config.Fencing.
+var ErrFenced = errors.New("write rejected: a newer leader took over the policy")
+
+// fencedPrefix starts the error replies of writes with a stale token.
+const fencedPrefix = "FENCED"
+
+// fenceCheck is prefixed to the fenced scripts. It pops the meta key from the
+// end of KEYS and the token from the end of ARGV, so the script itself sees
+// its own keys and arguments.
+const fenceCheck = `
+	local fenceKey = table.remove(KEYS)
+	local fenceToken = tonumber(table.remove(ARGV))
+	if tonumber(redis.call('hget', fenceKey, 'fence') or '0') > fenceToken then
+		return redis.error_reply('` + fencedPrefix + ` stale fencing token')
+	end
+`
+
+// fencedChunk is the number of values fencedCommand passes to a single call, well
+// below the limit of the Lua stack on unpack.
+const fencedChunk = 1000
+
+// fencedCommand runs the command ARGV[1] on the keys in KEYS and the values
+// following ARGV[2] after the fence check. When the values come in groups of
+// ARGV[2], e.g. the score and member of ZADD, it writes them in chunks, summing
+// the replies, or returning the last one for RPUSH. It is sent with EVAL rather
+// than EVALSHA, since commands sent in a pipeline could not be retried when the
+// script is not cached.
+var fencedCommand = fenceCheck + `
+	local command = ARGV[1]
+	local group = tonumber(ARGV[2])
+	if group == 0 or #ARGV - 2 <= ` + strconv.Itoa(fencedChunk) + ` then
+		local args = {unpack(KEYS)}
+		for i=3, #ARGV do
+			table.insert(args, ARGV[i])
+		end
+		return redis.call(command, unpack(args))
+	end
+
+	local size = ` + strconv.Itoa(fencedChunk) + ` - ` + strconv.Itoa(fencedChunk) + ` % group
+	local reply = 0
+	for first=3, #ARGV, size do
+		local args = {unpack(KEYS)}
+		for i=first, math.min(first+size-1, #ARGV) do
+			table.insert(args, ARGV[i])
+		end
+		local n = redis.call(command, unpack(args))
+		if string.upper(command) == 'RPUSH' then
+			reply = n
+		else
+			reply = reply + n
+		end
+	end
+	return reply
+`
+
+// acquireFencedScript takes the lease KEYS[1] for ARGV[1] during ARGV[2]
+// milliseconds, if it is free, and returns the next fencing token, or 0.
+var acquireFencedScript = redis.NewScript(2, `
+	if redis.call('set', KEYS[1], ARGV[1], 'PX', ARGV[2], 'NX') then
+		return redis.call('hincrby', KEYS[2], 'fence', 1)
+	end
+	return 0
+`)
+
+// fencedScript is the fenced copy of a write script.
+type fencedScript struct {
+	keyCount int
+	src      string
+	hash     string
+}
+
+// writeScripts maps the hashes of the write scripts to their fenced copies.
+var writeScripts sync.Map
+
+// newWriteScript is redis.NewScript for scripts writing the policy, which
+// Config.Fencing runs with the fence check.
+func newWriteScript(keyCount int, src string) *redis.Script {
+	script := redis.NewScript(keyCount, src)
+	if _, ok := writeScripts.Load(script.Hash()); !ok {
+		fenced := fenceCheck + src
+		h := sha1.Sum([]byte(fenced))
+		writeScripts.Store(script.Hash(), &fencedScript{keyCount: keyCount, src: fenced, hash: hex.EncodeToString(h[:])})
+	}
+	return script
+}
+
+// fencedArgs describes the arguments of a write command: how many of the first
+// ones are keys, -1 for all of them, and the size of the groups of values after
+// them that fencedCommand may write in chunks, or 0 if they cannot be split.
+type fencedArgs struct {
+	keys  int
+	group int
+}
+
+// fencedCommands are the write commands fencedConn runs with the fence check.
+var fencedCommands = map[string]fencedArgs{
+	"RPUSH": {1, 1}, "LREM": {1, 0}, "DEL": {-1, 0}, "RENAME": {2, 0}, "PERSIST": {1, 0}, "PEXPIRE": {1, 0},
+	"SADD": {1, 1}, "SREM": {1, 1}, "ZADD": {1, 2}, "ZREM": {1, 1}, "INCR": {1, 0}, "INCRBY": {1, 0},
+	"HSET": {1, 2}, "HDEL": {1, 1}, "XADD": {1, 0},
+}
+
+// fencedConn runs the write commands and scripts sent over it with the fence
+// check, presenting the token of the adapter. getConn only wraps connections in
+// it with Config.Fencing.
+type fencedConn struct {
+	redis.Conn
+	metaKey string
+	token   *int64
+}
+
+func (c fencedConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	commandName, args = c.fence(commandName, args)
+	reply, err := c.Conn.Do(commandName, args...)
+	return reply, fenceError(err)
+}
+
+func (c fencedConn) Send(commandName string, args ...interface{}) error {
+	commandName, args = c.fence(commandName, args)
+	return c.Conn.Send(commandName, args...)
+}
+
+func (c fencedConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	return reply, fenceError(err)
+}
+
+func (c fencedConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	commandName, args = c.fence(commandName, args)
+	reply, err := redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+	return reply, fenceError(err)
+}
+
+func (c fencedConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	reply, err := redis.ReceiveWithTimeout(c.Conn, timeout)
+	return reply, fenceError(err)
+}
+
+func (c fencedConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	commandName, args = c.fence(commandName, args)
+	reply, err := redis.DoContext(c.Conn, ctx, commandName, args...)
+	return reply, fenceError(err)
+}
+
+func (c fencedConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	reply, err := redis.ReceiveContext(c.Conn, ctx)
+	return reply, fenceError(err)
+}
+
+// fence rewrites a write command or script to its fenced form.
+func (c fencedConn) fence(commandName string, args []interface{}) (string, []interface{}) {
+	token := atomic.LoadInt64(c.token)
+	name := strings.ToUpper(commandName)
+	spec, fencedWrite := fencedCommands[name]
+	switch {
+	case fencedWrite:
+		keys := spec.keys
+		if keys < 0 || keys > len(args) {
+			keys = len(args)
+		}
+		fenced := make([]interface{}, 0, len(args)+6)
+		fenced = append(fenced, fencedCommand, keys+1)
+		fenced = append(fenced, args[:keys]...)
+		fenced = append(fenced, c.metaKey, commandName, spec.group)
+		fenced = append(fenced, args[keys:]...)
+		return "EVAL", append(fenced, token)
+	case (name == "EVALSHA" || name == "EVAL") && len(args) >= 2:
+		hash := fmt.Sprint(args[0])
+		if name == "EVAL" {
+			h := sha1.Sum([]byte(hash))
+			hash = hex.EncodeToString(h[:])
+		}
+		v, ok := writeScripts.Load(hash)
+		if !ok {
+			return commandName, args
+		}
+		script := v.(*fencedScript)
+		fenced := make([]interface{}, 0, len(args)+2)
+		if name == "EVAL" {
+			fenced = append(fenced, script.src)
+		} else {
+			fenced = append(fenced, script.hash)
+		}
+		fenced = append(fenced, script.keyCount+1)
+		fenced = append(fenced, args[2:2+script.keyCount]...)
+		fenced = append(fenced, c.metaKey)
+		fenced = append(fenced, args[2+script.keyCount:]...)
+		return commandName, append(fenced, token)
+	}
+	return commandName, args
+}
+
+// fenceError converts the error replies of writes with a stale token to ErrFenced.
+func fenceError(err error) error {
+	if e, ok := err.(redis.Error); ok && strings.HasPrefix(string(e), fencedPrefix) {
+		return fmt.Errorf("%w: %v", ErrFenced, err)
+	}
+	return err
+}
+
+// FencingToken returns the fencing token the adapter presents with its writes,
+// the one of its last leadership, or 0 if it never led. See Config.Fencing.
+func (a *Adapter) FencingToken() int64 {
+	if a.fenceToken == nil {
+		return 0
+	}
+	return atomic.LoadInt64(a.fenceToken)
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestFencing(t *testing.T) {
+	config := &Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_fencing", Fencing: true}
+	stale, err := NewAdapter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := NewAdapter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fence left by an earlier run would reject the cleanup of a fenced
+	// connection, whose token is 0.
+	conn, err := stale.conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("DEL", stale.key, stale.leaderKey(), stale.metaKey()); err != nil {
+		t.Fatal(err)
+	}
+	stale.client.Put(conn)
+
+	ctx := context.Background()
+	isLeader, _, _, err := stale.AcquireLeadership(ctx, 200*time.Millisecond)
+	if err != nil || !isLeader {
+		t.Fatalf("AcquireLeadership() = %v, %v, supposed to be true", isLeader, err)
+	}
+	if err = stale.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy() of the leader = %v", err)
+	}
+
+	// The lease expires without being renewed, and another adapter takes over.
+	time.Sleep(400 * time.Millisecond)
+	isLeader, _, release, err := current.AcquireLeadership(ctx, 10*time.Second)
+	if err != nil || !isLeader {
+		t.Fatalf("AcquireLeadership() = %v, %v after expiry, supposed to be true", isLeader, err)
+	}
+	defer release()
+	if current.FencingToken() <= stale.FencingToken() {
+		t.Fatalf("FencingToken() = %d, supposed to be greater than %d", current.FencingToken(), stale.FencingToken())
+	}
+
+	// Plain commands and scripts of the stale leader are rejected.
+	if err = stale.AddPolicy("p", "p", []string{"bob", "data2", "write"}); !errors.Is(err, ErrFenced) {
+		t.Fatalf("AddPolicy() of the stale leader = %v, supposed to be ErrFenced", err)
+	}
+	if err = stale.RemoveFilteredPolicy("p", "p", 0, "alice"); !errors.Is(err, ErrFenced) {
+		t.Fatalf("RemoveFilteredPolicy() of the stale leader = %v, supposed to be ErrFenced", err)
+	}
+
+	if err = current.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("AddPolicy() of the current leader = %v", err)
+	}
+	if err = current.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("RemovePolicy() of the current leader = %v", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", current)
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}})
+}
+
+func TestFencingLargeWrites(t *testing.T) {
+	rules := make([][]string, 2*fencedChunk+1)
+	for i := range rules {
+		rules[i] = []string{fmt.Sprintf("user%d", i), "data1", "read"}
+	}
+
+	for _, layout := range []Layout{ListLayout, HashLayout, ZSetLayout} {
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_fencing_large", Layout: layout, Fencing: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = conn.Do("DEL", a.key, a.metaKey()); err != nil {
+			t.Fatal(err)
+		}
+		a.release(conn)
+
+		// The values are written in chunks, past the limit of the Lua stack.
+		if err = a.AddPolicies("p", "p", rules); err != nil {
+			t.Fatalf("AddPolicies() with layout %v = %v", layout, err)
+		}
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		if n := len(e.GetPolicy()); n != len(rules) {
+			t.Errorf("len(GetPolicy()) with layout %v = %d, supposed to be %d", layout, n, len(rules))
+		}
+		if err = a.RemovePolicies("p", "p", rules); err != nil {
+			t.Fatalf("RemovePolicies() with layout %v = %v", layout, err)
+		}
+		_ = a.Close()
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// checkFieldNames validates Config.FieldNames: a ptype names at most the six
+// fields V0 to V5, each with a distinct non-empty name.
+func checkFieldNames(fieldNames map[string][]string) error {
+	for ptype, names := range fieldNames {
+		if len(names) > 6 {
+			return fmt.Errorf("FieldNames of %s: %d names for 6 fields", ptype, len(names))
+		}
+		seen := make(map[string]bool, len(names))
+		for _, name := range names {
+			if name == "" {
+				return fmt.Errorf("FieldNames of %s: empty name", ptype)
+			}
+			if seen[name] {
+				return fmt.Errorf("FieldNames of %s: duplicate name %s", ptype, name)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
+}
+
+// FilterByName builds a Filter from pairs of field names of Config.FieldNames and
+// values, e.g. FilterByName("sub", "alice", "act", "read"). A name given twice
+// matches either value. PType is set to the ptypes that name all the fields, and
+// a name must refer to the same field in all of them.
+func (a *Adapter) FilterByName(namesAndValues ...string) (Filter, error) {
+	var filter Filter
+	if len(namesAndValues)%2 != 0 {
+		return filter, fmt.Errorf("name %s without a value", namesAndValues[len(namesAndValues)-1])
+	}
+
+	// The ptypes are sorted to resolve a name against the same ptype every time.
+	ptypes := make([]string, 0, len(a.fieldNames))
+	for ptype := range a.fieldNames {
+		ptypes = append(ptypes, ptype)
+	}
+	sort.Strings(ptypes)
+
+	matching := ptypes
+	fields := filter.fields()
+	for i := 0; i < len(namesAndValues); i += 2 {
+		name, value := namesAndValues[i], namesAndValues[i+1]
+		index := -1
+		var next []string
+		for _, ptype := range matching {
+			idx := fieldIndexOf(a.fieldNames[ptype], name)
+			if idx < 0 {
+				continue
+			}
+			if index >= 0 && idx != index {
+				return Filter{}, fmt.Errorf("field %s is V%d of %s but V%d of %s", name, index, next[0], idx, ptype)
+			}
+			index = idx
+			next = append(next, ptype)
+		}
+		if index < 0 {
+			return Filter{}, fmt.Errorf("no ptype names field %s", name)
+		}
+		matching = next
+		*fields[index] = append(*fields[index], value)
+	}
+	if len(namesAndValues) > 0 {
+		filter.PType = matching
+	}
+	return filter, nil
+}
+
+// fieldIndexOf returns the index of name among names, or -1.
+func fieldIndexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
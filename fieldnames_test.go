@@ -0,0 +1,77 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestFieldNames(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_field_names",
+		FieldNames: map[string][]string{"p": {"sub", "obj", "act"}, "g": {"user", "role"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	named, err := a.FilterByName("sub", "alice", "act", "read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexed := Filter{PType: []string{"p"}, V0: []string{"alice"}, V2: []string{"read"}}
+	if !reflect.DeepEqual(named, indexed) {
+		t.Errorf("FilterByName() = %+v, supposed to be %+v", named, indexed)
+	}
+
+	load := func(filter Filter) [][]string {
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+		e.SetAdapter(a)
+		if err := e.LoadFilteredPolicy(filter); err != nil {
+			t.Fatal(err)
+		}
+		return append(e.GetPolicy(), e.GetGroupingPolicy()...)
+	}
+	if got, want := load(named), load(indexed); !reflect.DeepEqual(got, want) {
+		t.Errorf("policy loaded by name = %v, supposed to be %v", got, want)
+	}
+	if got := load(named); !reflect.DeepEqual(got, [][]string{{"alice", "data1", "read"}}) {
+		t.Errorf("policy loaded by name = %v, supposed to be alice's rule", got)
+	}
+
+	// The ptype follows from the names.
+	named, err = a.FilterByName("role", "data2_admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := load(named); !reflect.DeepEqual(got, [][]string{{"alice", "data2_admin"}}) {
+		t.Errorf("policy loaded by role = %v, supposed to be alice's grouping rule", got)
+	}
+
+	if _, err = a.FilterByName("subject", "alice"); err == nil {
+		t.Error("FilterByName() of an unknown name succeeded")
+	}
+	if _, err = a.FilterByName("sub", "alice", "role"); err == nil {
+		t.Error("FilterByName() of a name without a value succeeded")
+	}
+	if _, err = a.FilterByName("sub", "alice", "role", "data2_admin"); err == nil {
+		t.Error("FilterByName() of names of different ptypes succeeded")
+	}
+	if _, err = NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", FieldNames: map[string][]string{"p": {"sub", "sub"}}}); err == nil {
+		t.Error("NewAdapter() with a duplicate field name succeeded")
+	}
+}
@@ -1,8 +1,11 @@
 module github.com/casbin/redis-adapter/v3
 
-go 1.12
+go 1.17
 
 require (
 	github.com/casbin/casbin/v2 v2.60.0
 	github.com/gomodule/redigo v1.8.9
+	golang.org/x/time v0.3.0
 )
+
+require github.com/Knetic/govaluate v3.0.1-0.20171022003610-9aa49832a739+incompatible // indirect
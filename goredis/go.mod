@@ -0,0 +1,19 @@
+module github.com/casbin/redis-adapter/v3/goredis
+
+go 1.18
+
+require (
+	github.com/casbin/casbin/v2 v2.60.0
+	github.com/casbin/redis-adapter/v3 v3.0.0
+	github.com/gomodule/redigo v1.8.9
+	github.com/redis/go-redis/v9 v9.0.5
+)
+
+require (
+	github.com/Knetic/govaluate v3.0.1-0.20171022003610-9aa49832a739+incompatible // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	golang.org/x/time v0.3.0 // indirect
+)
+
+replace github.com/casbin/redis-adapter/v3 => ../
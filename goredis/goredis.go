@@ -0,0 +1,338 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package goredis backs a Redis adapter with the redis.UniversalClient of
+// github.com/redis/go-redis the application already uses, instead of a redigo
+// pool of the adapter. It is a separate module, so the adapter does not depend
+// on go-redis:
+//
+//	rdb := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{"127.0.0.1:6379"}})
+//	a, err := goredis.NewAdapter(rdb, redisadapter.WithKey("casbin_rules"))
+//
+// The adapter sends the same commands and scripts as with redigo, so adapters
+// of either client share the policy stored under a key.
+//
+// On a cluster or ring, the transactions and scripts of the adapter span its
+// auxiliary keys, so all of them must be on the node of the key: give the key a
+// hash tag, e.g. WithKey("{casbin_rules}"), which the auxiliary keys derived
+// from it keep.
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	redisadapter "github.com/casbin/redis-adapter/v3"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewAdapter creates an adapter running its commands through client, which it
+// shares with the application: closing the adapter leaves client open. Each
+// operation runs over a dedicated connection, which the transactions of the
+// adapter need: a connection of client for a *redis.Client or failover client,
+// of the master of the key for a *redis.ClusterClient, and of the shard of the
+// key for a *redis.Ring. Of the options, those dialing the server are ignored,
+// e.g. WithKey applies but WithAddress does not.
+func NewAdapter(client redis.UniversalClient, opts ...redisadapter.Option) (*redisadapter.Adapter, error) {
+	if client == nil {
+		return nil, errors.New("goredis: nil client")
+	}
+	switch client.(type) {
+	case connector, *redis.ClusterClient, *redis.Ring:
+	default:
+		return nil, fmt.Errorf("goredis: unsupported client %T", client)
+	}
+	shared := &sharedClient{client: client}
+	a, err := redisadapter.NewAdapterWithClient(shared, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// The adapter runs no command before NewAdapterWithClient returns, so the
+	// key locating the node of a cluster or ring is known before the first Get.
+	shared.key = a.Key()
+	return a, nil
+}
+
+// connector is a client with dedicated connections, i.e. *redis.Client.
+type connector interface {
+	Conn() *redis.Conn
+}
+
+// sharedClient is the redisadapter.Client of a go-redis client, which Close
+// leaves to the application.
+type sharedClient struct {
+	client redis.UniversalClient
+	// key locates the node of a cluster or ring.
+	key string
+}
+
+func (c *sharedClient) Get(ctx context.Context) (redigo.Conn, error) {
+	switch client := c.client.(type) {
+	case connector:
+		cn := client.Conn()
+		return &conn{pipe: cn, close: cn.Close}, nil
+	case *redis.ClusterClient:
+		node, err := client.MasterForKey(ctx, c.key)
+		if err != nil {
+			return nil, err
+		}
+		cn := node.Conn()
+		return &conn{pipe: cn, close: cn.Close}, nil
+	case *redis.Ring:
+		return c.ringConn(ctx, client)
+	}
+	return nil, fmt.Errorf("goredis: unsupported client %T", c.client)
+}
+
+// ringConn returns a connection of the shard of the key. A ring only lends one
+// to Watch, for the time of its callback, which therefore waits in a goroutine
+// until the connection is closed.
+func (c *sharedClient) ringConn(ctx context.Context, ring *redis.Ring) (redigo.Conn, error) {
+	txs := make(chan *redis.Tx)
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ring.Watch(context.Background(), func(tx *redis.Tx) error {
+			// The adapter watches keys itself where it needs to.
+			if err := tx.Unwatch(ctx).Err(); err != nil {
+				return err
+			}
+			select {
+			case txs <- tx:
+				<-done
+			case <-done:
+			}
+			return nil
+		}, c.key)
+	}()
+	select {
+	case tx := <-txs:
+		return &conn{pipe: tx, close: func() error {
+			close(done)
+			return <-errs
+		}}, nil
+	case err := <-errs:
+		return nil, err
+	case <-ctx.Done():
+		close(done)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *sharedClient) Put(conn redigo.Conn) {
+	conn.Close()
+}
+
+func (c *sharedClient) Close() error {
+	return nil
+}
+
+var errClosed = errors.New("goredis: connection closed")
+
+// pipeliner is a dedicated go-redis connection: *redis.Conn or *redis.Tx.
+type pipeliner interface {
+	Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+}
+
+// conn is a redigo.Conn over a dedicated go-redis connection. The commands
+// sent are run as a pipeline once flushed or followed by Do.
+type conn struct {
+	pipe    pipeliner
+	close   func() error
+	pending [][]interface{}
+	replies []interface{}
+	err     error
+}
+
+func (c *conn) Close() error {
+	if c.err == errClosed {
+		return nil
+	}
+	c.err = errClosed
+	return c.close()
+}
+
+func (c *conn) Err() error {
+	return c.err
+}
+
+func (c *conn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return c.DoContext(context.Background(), commandName, args...)
+}
+
+func (c *conn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.DoContext(ctx, commandName, args...)
+}
+
+// DoContext is Do of redigo: without a command it returns the replies of the
+// commands sent, otherwise the reply of the command, along with the first error
+// reply.
+func (c *conn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	if commandName == "" && len(c.pending) == 0 && len(c.replies) == 0 {
+		return nil, nil
+	}
+	if commandName != "" {
+		if err := c.Send(commandName, args...); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.flush(ctx); err != nil {
+		return nil, err
+	}
+
+	replies := c.replies
+	c.replies = nil
+	if commandName == "" {
+		return replies, nil
+	}
+	var err error
+	for _, reply := range replies {
+		if e, ok := reply.(redigo.Error); ok && err == nil {
+			err = e
+		}
+	}
+	return replies[len(replies)-1], err
+}
+
+func (c *conn) Send(commandName string, args ...interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	command := make([]interface{}, 0, len(args)+1)
+	command = append(command, commandName)
+	c.pending = append(c.pending, append(command, args...))
+	return nil
+}
+
+func (c *conn) Flush() error {
+	return c.flush(context.Background())
+}
+
+func (c *conn) Receive() (interface{}, error) {
+	return c.ReceiveContext(context.Background())
+}
+
+func (c *conn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.ReceiveContext(ctx)
+}
+
+func (c *conn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	if len(c.replies) == 0 {
+		if err := c.flush(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.replies) == 0 {
+		return nil, errors.New("goredis: no pending reply")
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	if e, ok := reply.(redigo.Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// flush runs the commands sent as a pipeline and queues their replies.
+func (c *conn) flush(ctx context.Context) error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.pending) == 0 {
+		return nil
+	}
+	pending := c.pending
+	c.pending = nil
+	cmds, _ := c.pipe.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, command := range pending {
+			pipe.Do(ctx, command...)
+		}
+		return nil
+	})
+	if len(cmds) != len(pending) {
+		return c.fatal(fmt.Errorf("goredis: %d replies for %d commands", len(cmds), len(pending)))
+	}
+	for _, cmd := range cmds {
+		reply, err := replyOf(cmd.(*redis.Cmd).Result())
+		if err != nil {
+			return c.fatal(err)
+		}
+		c.replies = append(c.replies, reply)
+	}
+	return nil
+}
+
+// fatal makes the connection unusable, like redigo does on I/O errors.
+func (c *conn) fatal(err error) error {
+	if c.err == nil {
+		c.err = err
+	}
+	return err
+}
+
+// replyOf converts the result of a go-redis command to the reply types of
+// redigo. Error replies become redigo.Error values, other errors are returned.
+func replyOf(value interface{}, err error) (interface{}, error) {
+	if err == redis.Nil {
+		return nil, nil
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		return redigo.Error(redisErr.Error()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return convert(value), nil
+}
+
+// convert converts a reply of go-redis to the types redigo returns for the
+// RESP2 reply of the command: strings as []byte, nested errors as
+// redigo.Error, and the RESP3 maps, doubles and booleans as RESP2 does.
+func convert(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return []byte(v)
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case error:
+		return redigo.Error(v.Error())
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = convert(item)
+		}
+		return values
+	case map[interface{}]interface{}:
+		values := make([]interface{}, 0, 2*len(v))
+		for key, item := range v {
+			values = append(values, convert(key), convert(item))
+		}
+		return values
+	}
+	return value
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goredis
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	redisadapter "github.com/casbin/redis-adapter/v3"
+	"github.com/casbin/redis-adapter/v3/adaptertest"
+	"github.com/redis/go-redis/v9"
+)
+
+func sortedPolicy(e *casbin.Enforcer) [][]string {
+	policy := e.GetPolicy()
+	sort.Slice(policy, func(i, j int) bool {
+		return strings.Join(policy[i], ",") < strings.Join(policy[j], ",")
+	})
+	return policy
+}
+
+func TestSuite(t *testing.T) {
+	tests := []struct {
+		name   string
+		client redis.UniversalClient
+	}{
+		{"Client", redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})},
+		{"ClusterClient", redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"127.0.0.1:6379"}})},
+		{"Ring", redis.NewRing(&redis.RingOptions{Addrs: map[string]string{"shard": "127.0.0.1:6379"}})},
+	}
+	for _, test := range tests {
+		test := test
+		defer test.client.Close()
+		t.Run(test.name, func(t *testing.T) {
+			adaptertest.Run(t, func(t *testing.T, key string, layout redisadapter.Layout) *redisadapter.Adapter {
+				// The hash tag keeps the auxiliary keys on the node of the key.
+				a, err := NewAdapter(test.client, redisadapter.WithKey("{"+key+"}"), redisadapter.WithLayout(layout))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return a
+			})
+		})
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer rdb.Close()
+	a, err := NewAdapter(rdb, redisadapter.WithKey("casbin_rules_goredis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	e, _ := casbin.NewEnforcer("../examples/rbac_model.conf", "../examples/rbac_policy.csv")
+	if err = a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	e, err = casbin.NewEnforcer("../examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The writes, including those running scripts.
+	if _, err = e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.AddPolicies([][]string{{"dave", "data4", "read"}, {"erin", "data5", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.UpdatePolicy([]string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemoveFilteredPolicy(0, "dave"); err != nil {
+		t.Fatal(err)
+	}
+	want := sortedPolicy(e)
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if got := sortedPolicy(e); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v, supposed to be %v", got, want)
+	}
+
+	// An adapter over redigo loads the same policy from the key.
+	b, err := redisadapter.NewAdapter(&redisadapter.Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_goredis"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	e2, err := casbin.NewEnforcer("../examples/rbac_model.conf", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sortedPolicy(e2); !reflect.DeepEqual(got, want) {
+		t.Errorf("redigo adapter loaded %v, supposed to be %v", got, want)
+	}
+
+	// Closing the adapter leaves the client of the application open.
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = rdb.Ping(context.Background()).Err(); err != nil {
+		t.Errorf("Ping() after closing the adapter = %v", err)
+	}
+}
+
+func TestStorageFormat(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer rdb.Close()
+	a, err := NewAdapter(rdb, redisadapter.WithKey("casbin_rules_goredis_format"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := redisadapter.NewAdapter(&redisadapter.Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_redigo_format"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	e, _ := casbin.NewEnforcer("../examples/rbac_model.conf", "../examples/rbac_policy.csv")
+	for _, adapter := range []*redisadapter.Adapter{a, b} {
+		if err = adapter.SavePolicy(e.GetModel()); err != nil {
+			t.Fatal(err)
+		}
+		if err = adapter.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	got, err := rdb.LRange(ctx, "casbin_rules_goredis_format", 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := rdb.LRange(ctx, "casbin_rules_redigo_format", 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("go-redis stored %d rules, redigo %d", len(got), len(want))
+	}
+	for i := range got {
+		if !bytes.Equal([]byte(got[i]), []byte(want[i])) {
+			t.Errorf("rule %d stored as %q, redigo stores %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ruleDigest returns the field of a serialized rule in HashLayout, the hex SHA-1
+// of the rule, which the scripts compute with redis.sha1hex as well.
+func ruleDigest(text []byte) string {
+	sum := sha1.Sum(text)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFields returns the arguments of HSET for texts, each digest followed by
+// its rule.
+func hashFields(texts [][]byte) redis.Args {
+	args := make(redis.Args, 0, 2*len(texts))
+	for _, text := range texts {
+		args = append(args, ruleDigest(text), text)
+	}
+	return args
+}
+
+// ruleDigests returns the fields of texts in HashLayout.
+func ruleDigests(texts [][]byte) []string {
+	digests := make([]string, len(texts))
+	for i, text := range texts {
+		digests[i] = ruleDigest(text)
+	}
+	return digests
+}
+
+// updateFieldsScript replaces each of the first ARGV[1] rules following it with
+// the rule at the same position after them, if it is stored.
+var updateFieldsScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local n = tonumber(ARGV[1])
+
+	for i=2, n+1 do
+		if redis.call('hdel', key, redis.sha1hex(ARGV[i])) == 1 then
+			redis.call('hset', key, redis.sha1hex(ARGV[i+n]), ARGV[i+n])
+		end
+	end
+	return
+`)
+
+// removeFilteredFieldsScript removes the rules matching the pattern ARGV[1].
+var removeFilteredFieldsScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local pattern = ARGV[1]
+
+	local r = redis.call('hgetall', key)
+	for i=2, #r, 2 do
+		if string.find(r[i], pattern) then
+			redis.call('hdel', key, r[i-1])
+		end
+	end
+	return
+`)
+
+// HashBackend is the StorageBackend of HashLayout, which stores the rules in a
+// hash keyed by their digest. It keeps neither their order nor duplicates. The
+// filtered operations match the rules by their Pattern within a script, or
+// decode them in the adapter without one.
+type HashBackend struct{}
+
+// LoadAll returns the rules of the hash, leaving out the tombstone of EnsureKey.
+func (b HashBackend) LoadAll(conn redis.Conn, key string) ([][]byte, error) {
+	values, err := redis.ByteSlices(conn.Do("HVALS", key))
+	return withoutTombstones(values), err
+}
+
+// LoadMatching returns the rules of the hash that match, decoding every rule.
+func (b HashBackend) LoadMatching(conn redis.Conn, key string, match RuleMatch) ([][]byte, error) {
+	rules, err := b.LoadAll(conn, key)
+	if err != nil {
+		return nil, err
+	}
+	return matchRules(rules, match)
+}
+
+// Append sets the rules in the hash.
+func (b HashBackend) Append(conn redis.Conn, key string, rules [][]byte) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err := conn.Do("HSET", redis.Args{}.Add(key).AddFlat(hashFields(rules))...)
+	return err
+}
+
+// Remove deletes the rules from the hash.
+func (b HashBackend) Remove(conn redis.Conn, key string, rules [][]byte) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err := conn.Do("HDEL", redis.Args{}.Add(key).AddFlat(ruleDigests(rules))...)
+	return err
+}
+
+// RemoveMatching deletes the rules of the hash that match.
+func (b HashBackend) RemoveMatching(conn redis.Conn, key string, match RuleMatch) error {
+	if match.Pattern != "" {
+		_, err := runScript(removeFilteredFieldsScript, conn, key, match.Pattern)
+		return err
+	}
+	matched, err := b.LoadMatching(conn, key, match)
+	if err != nil {
+		return err
+	}
+	return b.Remove(conn, key, matched)
+}
+
+// Replace replaces the stored rules of the hash.
+func (b HashBackend) Replace(conn redis.Conn, key string, oldRules, newRules [][]byte) error {
+	if len(oldRules) == 0 {
+		return nil
+	}
+	_, err := runScript(updateFieldsScript, conn, redis.Args{}.Add(key, len(oldRules)).AddFlat(oldRules).AddFlat(newRules)...)
+	return err
+}
+
+// ReplaceMatching deletes the rules of the hash that match and sets the new
+// rules, in a transaction.
+func (b HashBackend) ReplaceMatching(conn redis.Conn, key string, match RuleMatch, newRules [][]byte) ([][]byte, error) {
+	matched, err := b.LoadMatching(conn, key, match)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if len(matched) > 0 {
+		if err = conn.Send("HDEL", redis.Args{}.Add(key).AddFlat(ruleDigests(matched))...); err != nil {
+			return nil, err
+		}
+	}
+	if len(newRules) > 0 {
+		if err = conn.Send("HSET", redis.Args{}.Add(key).AddFlat(hashFields(newRules))...); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := conn.Do("EXEC")
+	if err == nil {
+		err = execError(reply)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// Clear deletes the hash.
+func (b HashBackend) Clear(conn redis.Conn, key string) error {
+	_, err := conn.Do("DEL", key)
+	return err
+}
+
+// MigrateToHash converts the policy list of the key into the hash of HashLayout,
+// e.g. before switching an existing deployment to HashLayout. Duplicate rules
+// are stored once, and the version is left as is. A key that is already a hash,
+// or does not exist, is left untouched. It is only supported by HashLayout.
+func (a *Adapter) MigrateToHash() error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if a.layout != HashLayout {
+		return errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	// The list is watched, so rules written meanwhile by a client still using
+	// ListLayout are not lost.
+	for i := 0; i < maxRewriteAttempts; i++ {
+		if _, err = conn.Do("WATCH", a.key); err != nil {
+			return err
+		}
+		typ, err := redis.String(conn.Do("TYPE", a.key))
+		if err != nil || typ != "list" {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+		values, err := redis.ByteSlices(conn.Do("LRANGE", a.key, 0, -1))
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+		texts := make([][]byte, 0, len(values))
+		for _, value := range values {
+			if string(value) != tombstone {
+				texts = append(texts, value)
+			}
+		}
+
+		if err = conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if err = conn.Send("DEL", a.key); err != nil {
+			return err
+		}
+		if len(texts) > 0 {
+			if err = conn.Send("HSET", redis.Args{}.Add(a.key).AddFlat(hashFields(texts))...); err != nil {
+				return err
+			}
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return execError(reply)
+		}
+	}
+	return errors.New("policy kept changing while it was migrated")
+}
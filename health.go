@@ -0,0 +1,118 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// tombstone is the placeholder the Lua scripts write over entries before removing
+// them, and EnsureKey stores in a key without rules.
+const tombstone = "__CASBIN_DELETED__"
+
+// withoutTombstones returns values without the tombstones, in place.
+func withoutTombstones(values [][]byte) [][]byte {
+	kept := values[:0]
+	for _, value := range values {
+		if string(value) != tombstone {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+// HealthReport describes the state of the stored policy list, so operators can
+// tell when it has degraded and should be rewritten.
+type HealthReport struct {
+	// Length is the number of entries in the list.
+	Length int64
+	// Duplicates is the number of entries repeating an earlier entry.
+	Duplicates int64
+	// Tombstones is the number of removal placeholders left in the list.
+	Tombstones int64
+	// SoftDeleted is the number of soft-deleted rules waiting for PurgeDeleted.
+	SoftDeleted int64
+	// MemoryBytes is the memory used by the policy keys, as estimated by MEMORY USAGE.
+	MemoryBytes int64
+}
+
+// healthReportScript scans the list once and returns its length, duplicate count,
+// tombstone count, soft-deleted count and memory usage.
+var healthReportScript = redis.NewScript(2, `
+	local key = KEYS[1]
+	local deleted = KEYS[2]
+
+	local seen = {}
+	local duplicates = 0
+	local tombstones = 0
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if r[i] == ARGV[1] then
+			tombstones = tombstones + 1
+		elseif seen[r[i]] then
+			duplicates = duplicates + 1
+		else
+			seen[r[i]] = true
+		end
+	end
+
+	local memory = 0
+	for _, k in ipairs(KEYS) do
+		local ok, usage = pcall(redis.call, 'memory', 'usage', k)
+		if ok and usage then
+			memory = memory + usage
+		end
+	end
+
+	return {#r, duplicates, tombstones, redis.call('zcard', deleted), memory}
+`)
+
+// HealthReport scans the policy list server-side and reports its length and the
+// number of duplicate, tombstone and soft-deleted entries, along with its memory usage.
+// It is only supported by ListLayout without Config.Backend.
+func (a *Adapter) HealthReport() (HealthReport, error) {
+	if err := a.begin(); err != nil {
+		return HealthReport{}, err
+	}
+	defer a.end()
+
+	if !a.plainList() {
+		return HealthReport{}, errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return HealthReport{}, err
+	}
+	defer a.release(conn)
+
+	values, err := redis.Int64s(a.doScript(healthReportScript, conn, a.key, a.deletedKey(), tombstone))
+	if err != nil {
+		return HealthReport{}, err
+	}
+	if len(values) != 5 {
+		return HealthReport{}, errors.New("unexpected health report reply")
+	}
+
+	return HealthReport{
+		Length:      values[0],
+		Duplicates:  values[1],
+		Tombstones:  values[2],
+		SoftDeleted: values[3],
+		MemoryBytes: values[4],
+	}, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestHealthReport(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_health", SoftDelete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	report, err := a.HealthReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Length != 5 || report.Duplicates != 0 || report.Tombstones != 0 || report.SoftDeleted != 0 {
+		t.Errorf("HealthReport() of a clean policy = %+v", report)
+	}
+	if report.MemoryBytes <= 0 {
+		t.Errorf("HealthReport().MemoryBytes = %d, supposed to be positive", report.MemoryBytes)
+	}
+
+	// Inject two duplicates, a tombstone and a soft-deleted rule.
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := a.marshal(CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("RPUSH", a.key, text, text, tombstone); err != nil {
+		t.Fatal(err)
+	}
+	a.release(conn)
+	if err = a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = a.HealthReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Length != 8 || report.Duplicates != 2 || report.Tombstones != 1 || report.SoftDeleted != 1 {
+		t.Errorf("HealthReport() = %+v, supposed to report 8 entries, 2 duplicates, 1 tombstone and 1 soft-deleted rule", report)
+	}
+
+	// Loading skips the tombstone, and casbin loads the duplicates once, which
+	// only the report tells apart.
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	report, err = a.HealthReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Duplicates != 2 {
+		t.Errorf("HealthReport().Duplicates after loading = %d, supposed to be 2", report.Duplicates)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// hookConn reports every command to Config.CommandHook before passing it on.
+// getConn only wraps connections in it when a hook is set.
+type hookConn struct {
+	redis.Conn
+	hook func(cmd string, args []interface{})
+}
+
+func (c hookConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	// Do without a command only flushes and receives pending replies.
+	if commandName != "" {
+		c.hook(commandName, args)
+	}
+	return c.Conn.Do(commandName, args...)
+}
+
+func (c hookConn) Send(commandName string, args ...interface{}) error {
+	c.hook(commandName, args)
+	return c.Conn.Send(commandName, args...)
+}
+
+func (c hookConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	if commandName != "" {
+		c.hook(commandName, args)
+	}
+	return redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+}
+
+func (c hookConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func (c hookConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	if commandName != "" {
+		c.hook(commandName, args)
+	}
+	return redis.DoContext(c.Conn, ctx, commandName, args...)
+}
+
+func (c hookConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return redis.ReceiveContext(c.Conn, ctx)
+}
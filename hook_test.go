@@ -0,0 +1,65 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestCommandHook(t *testing.T) {
+	var commands []string
+	renamed := false
+	hook := func(cmd string, args []interface{}) {
+		commands = append(commands, cmd)
+		switch cmd {
+		case "LRANGE":
+			if len(args) == 0 || args[0] != "casbin_rules_hook" {
+				t.Errorf("%s %v, supposed to address casbin_rules_hook", cmd, args)
+			}
+		case "RPUSH":
+			// SavePolicy writes to a temporary key renamed over the policy.
+			if key, _ := args[0].(string); key != "casbin_rules_hook" && !strings.HasPrefix(key, "casbin_rules_hook:saving:") {
+				t.Errorf("%s %v, supposed to address casbin_rules_hook or casbin_rules_hook:saving:", cmd, args)
+			}
+		case "RENAME":
+			if len(args) != 2 || args[1] != "casbin_rules_hook" {
+				t.Errorf("%s %v, supposed to rename to casbin_rules_hook", cmd, args)
+			}
+			renamed = true
+		}
+	}
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_hook", CommandHook: hook})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initPolicy(t, a)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	seen := map[string]bool{}
+	for _, cmd := range commands {
+		seen[cmd] = true
+	}
+	if !seen["RPUSH"] || !seen["LRANGE"] || !renamed {
+		t.Errorf("hooked commands = %v, supposed to include RPUSH, LRANGE and RENAME", commands)
+	}
+}
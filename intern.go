@@ -0,0 +1,50 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+// stringInterner makes equal field values of the loaded rules share one string,
+// so a policy repeating the same subjects, objects and actions keeps a single copy
+// of each. A nil interner leaves the rules untouched.
+type stringInterner map[string]string
+
+// newInterner returns an interner for one load if Config.InternStrings is set.
+func (a *Adapter) newInterner() stringInterner {
+	if !a.internStrings {
+		return nil
+	}
+	return make(stringInterner)
+}
+
+func (in stringInterner) intern(s string) string {
+	if interned, ok := in[s]; ok {
+		return interned
+	}
+	in[s] = s
+	return s
+}
+
+// line interns the fields of line.
+func (in stringInterner) line(line *CasbinRule) {
+	if in == nil {
+		return
+	}
+	line.PType = in.intern(line.PType)
+	line.V0 = in.intern(line.V0)
+	line.V1 = in.intern(line.V1)
+	line.V2 = in.intern(line.V2)
+	line.V3 = in.intern(line.V3)
+	line.V4 = in.intern(line.V4)
+	line.V5 = in.intern(line.V5)
+}
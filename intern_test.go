@@ -0,0 +1,119 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+// stringData returns the address of the bytes of s.
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+// repeatedRules returns n serialized rules drawn from a few subjects, objects and actions.
+func repeatedRules(a *Adapter, n int) ([][]byte, error) {
+	texts := make([][]byte, n)
+	for i := range texts {
+		var err error
+		texts[i], err = a.marshal(CasbinRule{PType: "p", V0: fmt.Sprintf("user%d", i%10), V1: fmt.Sprintf("data%d", i%20), V2: "read"})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return texts, nil
+}
+
+// decodeRules decodes texts like a load does.
+func decodeRules(a *Adapter, texts [][]byte) ([]CasbinRule, error) {
+	in := a.newInterner()
+	lines := make([]CasbinRule, len(texts))
+	for i, text := range texts {
+		if err := a.unmarshal(text, &lines[i]); err != nil {
+			return nil, err
+		}
+		in.line(&lines[i])
+	}
+	return lines, nil
+}
+
+func TestInternStrings(t *testing.T) {
+	a := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}, internStrings: true}
+	texts, err := repeatedRules(a, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := decodeRules(a, texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rules 0 and 20 have equal values, which must share their bytes.
+	if lines[0].V0 != lines[20].V0 || lines[0].V1 != lines[20].V1 {
+		t.Fatalf("rules %+v and %+v are supposed to be equal", lines[0], lines[20])
+	}
+	for _, pair := range [][2]string{{lines[0].V0, lines[20].V0}, {lines[0].V1, lines[20].V1}, {lines[0].V2, lines[99].V2}} {
+		if stringData(pair[0]) != stringData(pair[1]) {
+			t.Errorf("interned values %q do not share memory", pair[0])
+		}
+	}
+
+	a.internStrings = false
+	if lines, err = decodeRules(a, texts); err != nil {
+		t.Fatal(err)
+	}
+	if stringData(lines[0].V0) == stringData(lines[20].V0) {
+		t.Error("values share memory without InternStrings")
+	}
+}
+
+// BenchmarkInternStrings reports the memory retained by decoded rules with heavy
+// value repetition, with and without interning.
+func BenchmarkInternStrings(b *testing.B) {
+	for _, intern := range []bool{false, true} {
+		intern := intern
+		b.Run(fmt.Sprintf("intern=%v", intern), func(b *testing.B) {
+			a := &Adapter{jsonKeys: DefaultJSONKeys, codec: JSONCodec{}, internStrings: intern}
+			texts, err := repeatedRules(a, 10000)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			var retained uint64
+			var before, after runtime.MemStats
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				lines, err := decodeRules(a, texts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				if after.HeapAlloc > before.HeapAlloc {
+					retained += after.HeapAlloc - before.HeapAlloc
+				}
+				runtime.KeepAlive(lines)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// droppingServer answers PING on every connection it accepts and can drop all
+// of them at once, like a NAT forgetting idle flows.
+type droppingServer struct {
+	mu       sync.Mutex
+	conns    []net.Conn
+	accepted int
+}
+
+func (s *droppingServer) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.accepted++
+		s.mu.Unlock()
+		go func() {
+			r := bufio.NewReader(conn)
+			for {
+				if _, err := readCommand(r); err != nil {
+					return
+				}
+				if _, err = conn.Write([]byte("+PONG\r\n")); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (s *droppingServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func TestKeepAlive(t *testing.T) {
+	l := listen(t)
+	server := &droppingServer{}
+	go server.serve(l)
+
+	a, err := NewAdapter(&Config{Network: "tcp", Address: l.Addr().String(), KeepAlive: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if a.keepAlive != 20*time.Millisecond || a._pool.TestOnBorrow == nil {
+		t.Fatal("KeepAlive is not configured on the internal pool")
+	}
+	if err = a.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The idle connection is dropped: the pool pings it before reuse and
+	// redials instead of failing the operation.
+	server.drop()
+	time.Sleep(50 * time.Millisecond)
+	if err = a.Ping(); err != nil {
+		t.Fatalf("Ping after the idle connection was dropped: %v", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.accepted != 2 {
+		t.Errorf("accepted %d connections, want 2", server.accepted)
+	}
+}
+
+func TestKeepAliveNegative(t *testing.T) {
+	if _, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", KeepAlive: -time.Second}); err == nil {
+		t.Error("negative KeepAlive is accepted")
+	}
+}
+
+// TestKeepAliveRedis keeps a connection to Redis idle for REDIS_KEEPALIVE_IDLE,
+// e.g. longer than the idle timeout of a NAT in between, before using it again.
+func TestKeepAliveRedis(t *testing.T) {
+	idle, err := time.ParseDuration(os.Getenv("REDIS_KEEPALIVE_IDLE"))
+	if err != nil {
+		t.Skip("REDIS_KEEPALIVE_IDLE is not set")
+	}
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_keepalive", KeepAlive: 30 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	initPolicy(t, a)
+	time.Sleep(idle)
+	initPolicy(t, a)
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeyLayout returns the names of the Redis keys the adapter uses, with KeyPrefix
+// applied, so they can be monitored or backed up. The names are indexed by:
+//
+//   - "main": the policy key, or the snapshot with StreamLayout
+//   - "version": the version counter
+//   - "meta": the content hash of RepairVersion and the fencing tokens
+//   - "leader": the lease of AcquireLeadership
+//   - "audit": the stream of Config.AuditStream, if set
+//   - "stream": the stream of events of StreamLayout
+//   - "ptypes" and "ptype-prefix": the set of ptypes of PTypeSetLayout and
+//     Config.SplitSections, and the prefix of the keys of the rules of each ptype
+//   - "deleted": the rules soft-deleted with Config.SoftDelete
+//   - "seq" and "seq-counter": the sequence numbers of Config.TrackCreationOrder
+//   - "merged1", "merged2" and so on: the further Keys loaded with the policy
+//
+// The keys only exist once used. Short-lived keys, such as the temporary key of
+// SavePolicy, are left out.
+func (a *Adapter) KeyLayout() map[string]string {
+	layout := map[string]string{
+		"main":    a.key,
+		"version": a.versionKey(),
+		"meta":    a.metaKey(),
+		"leader":  a.leaderKey(),
+	}
+	if a.auditStream != "" {
+		layout["audit"] = a.auditStream
+	}
+	if a.layout == StreamLayout {
+		layout["stream"] = a.streamKey()
+	}
+	if a.perPType() {
+		layout["ptypes"] = a.ptypesKey()
+		layout["ptype-prefix"] = a.ptypeKey("")
+	}
+	if a.softDelete {
+		layout["deleted"] = a.deletedKey()
+	}
+	if a.trackOrder {
+		layout["seq"] = a.seqKey()
+		layout["seq-counter"] = a.seqCounterKey()
+	}
+	for i, key := range a.mergedKeys {
+		layout["merged"+strconv.Itoa(i+1)] = key
+	}
+	return layout
+}
+
+// prefixed returns name under Config.KeyPrefix. A name already starting with the
+// prefix, e.g. a Key spelled out in full, is returned as is rather than prefixed
+// twice. The auxiliary keys derived from the policy key, such as versionKey, need
+// not go through it as the policy key is prefixed already.
+func (a *Adapter) prefixed(name string) string {
+	if strings.HasPrefix(name, a.keyPrefix) {
+		return name
+	}
+	return a.keyPrefix + name
+}
+
+// Key returns the policy key of the adapter, with KeyPrefix applied.
+func (a *Adapter) Key() string {
+	a.keyMu.RLock()
+	defer a.keyMu.RUnlock()
+	return a.key
+}
+
+// SetKey switches the adapter to the policy key key, e.g. to move the enforcer
+// to a migrated policy without building another adapter. KeyPrefix applies to it
+// as to Config.Key, and the auxiliary keys, such as the version counter, follow.
+// SetKey waits for the operations in flight to finish on the old key, and the
+// operations started meanwhile wait to run on the new one, so that no operation
+// mixes both. The data under the old key is left untouched, and the adapter is
+// no longer filtered. Keys, AuditStream and a running WatchKeyspace are not
+// affected. SetKey must not be called from a CommandHook, which runs within an
+// operation.
+func (a *Adapter) SetKey(key string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("Key %q is empty or white space: %w", key, ErrInvalidKey)
+	}
+
+	a.keyMu.Lock()
+	defer a.keyMu.Unlock()
+	a.state.mu.Lock()
+	closed := a.state.closed
+	a.state.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	a.key = a.prefixed(key)
+	a.setFiltered(false)
+	if a.negativeCache != nil {
+		// The cached filters hold for versions of the old key.
+		a.negativeCache.reset()
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestKeyLayout(t *testing.T) {
+	newAdapter := func(config *Config) *Adapter {
+		config.Pool = &redis.Pool{Dial: func() (redis.Conn, error) { return noScriptConn{}, nil }}
+		a, err := NewAdapter(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	a := newAdapter(&Config{KeyPrefix: "prod:", Key: "rules"})
+	want := map[string]string{
+		"main":    "prod:rules",
+		"version": "prod:rules:version",
+		"meta":    "prod:rules:meta",
+		"leader":  "prod:rules:leader",
+	}
+	if layout := a.KeyLayout(); !reflect.DeepEqual(layout, want) {
+		t.Errorf("KeyLayout() = %v, supposed to be %v", layout, want)
+	}
+
+	a = newAdapter(&Config{KeyPrefix: "prod:", Key: "rules", Keys: []string{"shared"}, AuditStream: "audit", SoftDelete: true})
+	want["audit"] = "prod:audit"
+	want["deleted"] = "prod:rules:deleted"
+	want["merged1"] = "prod:shared"
+	if layout := a.KeyLayout(); !reflect.DeepEqual(layout, want) {
+		t.Errorf("KeyLayout() with audit, soft delete and merged keys = %v, supposed to be %v", layout, want)
+	}
+
+	a = newAdapter(&Config{Key: "rules", Layout: PTypeSetLayout})
+	if layout := a.KeyLayout(); layout["ptypes"] != "rules:ptypes" || layout["ptype-prefix"] != "rules:" {
+		t.Errorf("KeyLayout() with PTypeSetLayout = %v, supposed to name the ptype sets", layout)
+	}
+	a = newAdapter(&Config{Key: "rules", SplitSections: true})
+	if layout := a.KeyLayout(); layout["ptypes"] != "rules:ptypes" || layout["ptype-prefix"] != "rules:" {
+		t.Errorf("KeyLayout() with SplitSections = %v, supposed to name the ptype lists", layout)
+	}
+	a = newAdapter(&Config{Key: "rules", Layout: StreamLayout})
+	if layout := a.KeyLayout(); layout["stream"] != "rules:stream" {
+		t.Errorf("KeyLayout() with StreamLayout = %v, supposed to name the stream", layout)
+	}
+	a = newAdapter(&Config{Key: "rules", TrackCreationOrder: true})
+	if layout := a.KeyLayout(); layout["seq"] != "rules:seq" || layout["seq-counter"] != "rules:seq:next" {
+		t.Errorf("KeyLayout() with TrackCreationOrder = %v, supposed to name the sequence keys", layout)
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return noScriptConn{}, nil }}
+	tests := []struct {
+		prefix, key, want string
+	}{
+		{"", "", "casbin_rules"},
+		{"", "rules", "rules"},
+		{"prod:authz:", "", "prod:authz:casbin_rules"},
+		{"prod:authz:", "rules", "prod:authz:rules"},
+		// A Key spelled out with the prefix is not prefixed twice.
+		{"prod:authz:", "prod:authz:rules", "prod:authz:rules"},
+	}
+	for _, test := range tests {
+		a, err := NewAdapter(&Config{Pool: pool, KeyPrefix: test.prefix, Key: test.key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.key != test.want {
+			t.Errorf("key with KeyPrefix %q and Key %q = %q, supposed to be %q", test.prefix, test.key, a.key, test.want)
+		}
+		if key := a.versionKey(); key != test.want+":version" {
+			t.Errorf("versionKey() with KeyPrefix %q and Key %q = %q, supposed to derive from %q", test.prefix, test.key, key, test.want)
+		}
+	}
+}
+
+func TestKeyPrefixIsolation(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	newAdapter := func(prefix string) *Adapter {
+		a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", KeyPrefix: prefix})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	prod, staging := newAdapter("prod:authz:"), newAdapter("staging:authz:")
+
+	source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err := prod.SavePolicy(source.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	if err := staging.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	var keys []string
+	for key := range server.lists {
+		keys = append(keys, key)
+	}
+	for key := range server.strings {
+		keys = append(keys, key)
+	}
+	server.mu.Unlock()
+	sort.Strings(keys)
+	want := []string{"prod:authz:casbin_rules", "prod:authz:casbin_rules:version", "staging:authz:casbin_rules", "staging:authz:casbin_rules:version"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, supposed to be %v", keys, want)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", staging)
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}})
+	e, _ = casbin.NewEnforcer("examples/rbac_model.conf", prod)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestSetKey(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", KeyPrefix: "prod:", Key: "casbin_rules_v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err = a.SavePolicy(source.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err = e.LoadFilteredPolicy(&Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "  "} {
+		if err = a.SetKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("SetKey(%q) = %v, supposed to fail with ErrInvalidKey", key, err)
+		}
+	}
+	if err = a.SetKey("casbin_rules_v2"); err != nil {
+		t.Fatal(err)
+	}
+	if key := a.Key(); key != "prod:casbin_rules_v2" {
+		t.Errorf("Key() = %q, supposed to be prod:casbin_rules_v2", key)
+	}
+	if a.IsFiltered() {
+		t.Error("IsFiltered() after SetKey = true")
+	}
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}})
+
+	server.mu.Lock()
+	old := len(server.lists["prod:casbin_rules_v1"])
+	server.mu.Unlock()
+	if old != 5 {
+		t.Errorf("the old key holds %d rules after SetKey, supposed to be untouched with 5", old)
+	}
+
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.SetKey("casbin_rules_v1"); !errors.Is(err, ErrClosed) {
+		t.Errorf("SetKey() after Close = %v, supposed to fail with ErrClosed", err)
+	}
+}
+
+func TestSetKeyWaitsForOperations(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{"casbin_rules": {`{"PType":"p","V0":"alice"}`}}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The export reader is an operation in flight until closed, so SetKey
+	// waits for it and the reader keeps reading the old key.
+	r, err := a.ExportReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	switched := make(chan error, 1)
+	go func() {
+		switched <- a.SetKey("casbin_rules_v2")
+	}()
+	select {
+	case err = <-switched:
+		t.Fatalf("SetKey() = %v during an export, supposed to wait for it", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "alice") {
+		t.Errorf("export = %q, supposed to read the old key", data)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-switched; err != nil {
+		t.Fatal(err)
+	}
+	if key := a.Key(); key != "casbin_rules_v2" {
+		t.Errorf("Key() = %q, supposed to be casbin_rules_v2", key)
+	}
+}
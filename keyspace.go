@@ -0,0 +1,220 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrKeyspaceNotificationsDisabled is returned by WatchKeyspace when the server
+// does not publish keyspace notifications for the policy keys.
+var ErrKeyspaceNotificationsDisabled = errors.New(`keyspace notifications did not arrive, enable them with "CONFIG SET notify-keyspace-events Kglsz" or a superset such as "KA"`)
+
+// keyspaceProbeTimeout bounds how long WatchKeyspace waits for the notification
+// of its probe write.
+var keyspaceProbeTimeout = 5 * time.Second
+
+// probeKey returns the key WatchKeyspace writes to check that notifications arrive.
+func (a *Adapter) probeKey() string {
+	return a.key + ":keyspace-probe"
+}
+
+// isPolicyKey reports whether key holds policy rules, as opposed to keys of
+// helpers such as AcquireLeadership or SelfTest.
+func (a *Adapter) isPolicyKey(key string) bool {
+	if !a.perPType() {
+		return key == a.key || (a.softDelete && key == a.deletedKey()) || (a.layout == StreamLayout && key == a.streamKey())
+	}
+	if !strings.HasPrefix(key, a.key+":") {
+		return false
+	}
+	// Ptypes never contain a colon, unlike the keys of the helpers.
+	ptype := key[len(a.key)+1:]
+	return ptype != "" && ptype != "leader" && ptype != "keyspace-probe" && !strings.Contains(ptype, ":")
+}
+
+// escapeGlob escapes the special characters of a PSUBSCRIBE pattern.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// keyOfChannel returns the key a keyspace notification channel refers to.
+func keyOfChannel(channel string) string {
+	if i := strings.Index(channel, "__:"); i >= 0 {
+		return channel[i+3:]
+	}
+	return ""
+}
+
+// signal wakes up the receiver of ch without blocking, coalescing pending signals.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// dialSubscriber opens a dedicated connection for a subscription.
+func (a *Adapter) dialSubscriber(ctx context.Context) (redis.Conn, error) {
+	if a.overREST() {
+		return nil, errors.New("keyspace notifications are not available over REST")
+	}
+	if a._pool == nil {
+		return nil, errors.New("keyspace notifications are not available with NewAdapterWithClient")
+	}
+	if a._pool.DialContext != nil {
+		return a._pool.DialContext(ctx)
+	}
+	if a._pool.Dial != nil {
+		return a._pool.Dial()
+	}
+	conn := a._pool.Get()
+	return conn, conn.Err()
+}
+
+// WatchKeyspace subscribes to the keyspace notifications of the policy keys and
+// calls callback whenever any client modifies them, including changes made
+// directly in Redis rather than through an adapter. The server must be configured
+// with notify-keyspace-events; WatchKeyspace checks this with a probe write and
+// returns ErrKeyspaceNotificationsDisabled if its notification does not arrive.
+//
+// Calls of callback are sequential, and changes made while it runs are coalesced
+// into a single further call. WatchKeyspace blocks until ctx is done or the
+// subscription fails.
+func (a *Adapter) WatchKeyspace(ctx context.Context, callback func()) error {
+	// The subscription is not an operation Close waits for, it ends with Close.
+	if err := a.begin(); err != nil {
+		return err
+	}
+	conn, err := a.dialSubscriber(ctx)
+	a.end()
+	if err != nil {
+		return err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+
+	pattern := "__keyspace@*__:" + escapeGlob(a.key)
+	if err = psc.PSubscribe(pattern, pattern+":*"); err != nil {
+		return err
+	}
+
+	subscribed := make(chan struct{}, 1)
+	probed := make(chan struct{}, 1)
+	changed := make(chan struct{}, 1)
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			switch v := psc.Receive().(type) {
+			case redis.Subscription:
+				if v.Count == 2 {
+					signal(subscribed)
+				}
+			case redis.Message:
+				key := keyOfChannel(v.Channel)
+				if key == a.probeKey() {
+					signal(probed)
+				} else if a.isPolicyKey(key) {
+					signal(changed)
+				}
+			case error:
+				// Closing the connection on return also ends up here.
+				failed <- v
+				return
+			}
+		}
+	}()
+
+	timeout := time.NewTimer(keyspaceProbeTimeout)
+	defer timeout.Stop()
+	select {
+	case <-subscribed:
+	case err = <-failed:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-a.state.done:
+		return ErrClosed
+	case <-timeout.C:
+		return errors.New("keyspace notifications subscription was not confirmed")
+	}
+
+	if err = a.probeKeyspace(); err != nil {
+		return err
+	}
+	select {
+	case <-probed:
+	case err = <-failed:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-a.state.done:
+		return ErrClosed
+	case <-timeout.C:
+		return ErrKeyspaceNotificationsDisabled
+	}
+
+	for {
+		select {
+		case <-changed:
+			callback()
+		case err = <-failed:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.state.done:
+			return ErrClosed
+		}
+	}
+}
+
+// probeKeyspace writes the probe key with a command of the class the layout
+// uses, so its notification shows that the required event classes are enabled.
+func (a *Adapter) probeKeyspace() error {
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	args := redis.Args{}.Add(a.probeKey())
+	command := "RPUSH"
+	switch a.layout {
+	case PTypeSetLayout:
+		command = "SADD"
+	case HashLayout:
+		command, args = "HSET", args.Add("probe")
+	case ZSetLayout:
+		command, args = "ZADD", args.Add(1)
+	}
+	if _, err = conn.Do(command, args.Add(1)...); err != nil {
+		return err
+	}
+	_, err = conn.Do("DEL", a.probeKey())
+	return err
+}
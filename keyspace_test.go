@@ -0,0 +1,86 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestWatchKeyspace(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_keyspace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := redis.Dial("tcp", "127.0.0.1:6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	config, err := redis.Strings(conn.Do("CONFIG", "GET", "notify-keyspace-events"))
+	if err != nil || len(config) != 2 {
+		t.Skipf("cannot read notify-keyspace-events: %v", err)
+	}
+	if _, err = conn.Do("CONFIG", "SET", "notify-keyspace-events", "KA"); err != nil {
+		t.Skipf("cannot configure notify-keyspace-events: %v", err)
+	}
+	defer conn.Do("CONFIG", "SET", "notify-keyspace-events", config[1])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.WatchKeyspace(ctx, func() { signal(reloads) })
+	}()
+
+	// Modify the key outside of the adapter until the subscription is ready.
+	deadline := time.After(5 * time.Second)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-reloads:
+			break wait
+		case err = <-done:
+			t.Fatalf("WatchKeyspace() = %v", err)
+		case <-deadline:
+			t.Fatal("no reload was triggered by an external change")
+		case <-ticker.C:
+			if _, err = conn.Do("RPUSH", a.key, `{"PType":"p","V0":"eve","V1":"data1","V2":"read","V3":"","V4":"","V5":""}`); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	cancel()
+	if err = <-done; err != context.Canceled {
+		t.Errorf("WatchKeyspace() = %v after cancellation, supposed to be %v", err, context.Canceled)
+	}
+
+	// Without notifications the probe times out.
+	if _, err = conn.Do("CONFIG", "SET", "notify-keyspace-events", ""); err != nil {
+		t.Fatal(err)
+	}
+	keyspaceProbeTimeout = 200 * time.Millisecond
+	defer func() { keyspaceProbeTimeout = 5 * time.Second }()
+	if err = a.WatchKeyspace(context.Background(), func() {}); err != ErrKeyspaceNotificationsDisabled {
+		t.Errorf("WatchKeyspace() = %v without notifications, supposed to be %v", err, ErrKeyspaceNotificationsDisabled)
+	}
+}
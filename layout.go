@@ -0,0 +1,554 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/casbin/casbin/v2/constant"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Layout selects how rules are laid out in Redis keys.
+type Layout int
+
+const (
+	// ListLayout stores every rule, including its ptype, in a single list under
+	// the key. It is the default and preserves the order of the rules.
+	ListLayout Layout = iota
+	// PTypeSetLayout stores the rules of each ptype in a set under "<key>:<ptype>",
+	// e.g. "casbin_rules:p" and "casbin_rules:g". Members are the rule fields only,
+	// e.g. ["alice","data1","read"], and the ptype is derived from the key. The
+	// ptypes in use are tracked in the set "<key>:ptypes". Rules are deduplicated
+	// and their order is not preserved, so models with a priority effect are
+	// rejected with ErrUnordered.
+	PTypeSetLayout
+	// StreamLayout stores the policy as a list of rules under the key, like
+	// ListLayout, which is a snapshot followed by a stream of events under
+	// "<key>:stream". Writes append events adding, removing or updating rules,
+	// and loading the policy replays the events over the snapshot. Once the
+	// stream holds Config.StreamCompactThreshold events, they are folded into
+	// the snapshot. It needs Redis 5.0.
+	StreamLayout
+	// HashLayout stores every rule in a hash under the key, keyed by the hex
+	// SHA-1 of the serialized rule, so adding, removing and updating a rule take
+	// constant time however long the policy is. Rules are deduplicated and their
+	// order is not preserved, like with PTypeSetLayout. MigrateToHash converts
+	// the list of ListLayout. It needs Redis 4.0.
+	HashLayout
+	// ZSetLayout stores every rule as a member of a sorted set under the key,
+	// scored one past the highest score when the rule is added, so the policy
+	// loads in the order the rules were added. Updating a rule keeps its score,
+	// and SavePolicy renumbers the rules from 1. Rules are deduplicated.
+	ZSetLayout
+)
+
+// errLayoutUnsupported is returned by operations a layout other than ListLayout does not implement.
+var errLayoutUnsupported = errors.New("operation is not supported by the layout")
+
+// ErrUnordered is returned by LoadPolicy, LoadFilteredPolicy and SavePolicy of
+// PTypeSetLayout and HashLayout for a model whose effect depends on the order of
+// the rules, such as "priority(p_eft) || deny", as these layouts do not keep it.
+var ErrUnordered = errors.New("layout does not preserve the order of rules the model depends on")
+
+// checkOrdered returns ErrUnordered if the layout loses the order of the rules
+// and the effect of model depends on it.
+func (a *Adapter) checkOrdered(model model.Model) error {
+	if a.layout != PTypeSetLayout && a.layout != HashLayout {
+		return nil
+	}
+	if ast, ok := model["e"]["e"]; ok {
+		switch ast.Value {
+		case constant.PriorityEffect, constant.SubjectPriorityEffect:
+			return fmt.Errorf("effect %q: %w", ast.Value, ErrUnordered)
+		}
+	}
+	return nil
+}
+
+// updateMembersScript replaces each of the first ARGV[1] members following it with
+// the member at the same position after them, if it is stored.
+var updateMembersScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local n = tonumber(ARGV[1])
+
+	for i=2, n+1 do
+		if redis.call('srem', key, ARGV[i]) == 1 then
+			redis.call('sadd', key, ARGV[i+n])
+		end
+	end
+	return
+`)
+
+// perPType reports whether the rules of each ptype are stored under a key of
+// their own, with PTypeSetLayout or Config.SplitSections.
+func (a *Adapter) perPType() bool {
+	return a.layout == PTypeSetLayout || a.splitSections
+}
+
+// ptypeKey returns the key of the set, or the list with Config.SplitSections,
+// holding the rules of ptype.
+func (a *Adapter) ptypeKey(ptype string) string {
+	return a.key + ":" + ptype
+}
+
+// ptypesKey returns the key of the set tracking the ptypes in use.
+func (a *Adapter) ptypesKey() string {
+	return a.key + ":ptypes"
+}
+
+// marshalMember serializes the fields of a rule, without its ptype and trailing
+// empty fields, with the configured encoding. Config.Codec encodes the rule
+// without its ptype.
+func (a *Adapter) marshalMember(line CasbinRule) ([]byte, error) {
+	fields := []string{line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
+	for len(fields) > 0 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+
+	if a.customCodec {
+		return a.codec.Encode(CasbinRule{V0: line.V0, V1: line.V1, V2: line.V2, V3: line.V3, V4: line.V4, V5: line.V5})
+	}
+	if a.encoding == GobEncoding {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(fields); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if a.encoding == CSVEncoding {
+		return marshalCSV(fields), nil
+	}
+	return json.Marshal(fields)
+}
+
+// unmarshalMember deserializes the fields of a rule stored under ptype.
+func (a *Adapter) unmarshalMember(ptype string, text []byte, line *CasbinRule) error {
+	if a.customCodec {
+		decoded, err := a.codec.Decode(text)
+		if err != nil {
+			return err
+		}
+		decoded.PType = ptype
+		*line = decoded
+		return nil
+	}
+	var fields []string
+	var err error
+	switch a.encoding {
+	case GobEncoding:
+		err = gob.NewDecoder(bytes.NewReader(text)).Decode(&fields)
+	case CSVEncoding:
+		fields, err = unmarshalCSV(text)
+	default:
+		err = json.Unmarshal(text, &fields)
+	}
+	if err != nil {
+		return err
+	}
+	*line = savePolicyLine(ptype, fields)
+	return nil
+}
+
+// marshalStored serializes a rule the way it is stored, without its ptype in
+// PTypeSetLayout.
+func (a *Adapter) marshalStored(line CasbinRule) ([]byte, error) {
+	if a.layout == PTypeSetLayout {
+		return a.marshalMember(line)
+	}
+	return a.marshal(line)
+}
+
+// unmarshalStored deserializes a rule stored under the key of ptype.
+func (a *Adapter) unmarshalStored(ptype string, text []byte, line *CasbinRule) error {
+	if a.layout == PTypeSetLayout {
+		return a.unmarshalMember(ptype, text, line)
+	}
+	return a.unmarshal(text, line)
+}
+
+// storedPTypes returns the ptypes in use, sorted.
+func (a *Adapter) storedPTypes(conn redis.Conn) ([]string, error) {
+	members, err := redis.Strings(conn.Do("SMEMBERS", a.ptypesKey()))
+	if err != nil {
+		return nil, err
+	}
+	// Leave out the tombstone of EnsureKey.
+	ptypes := members[:0]
+	for _, ptype := range members {
+		if ptype != tombstone {
+			ptypes = append(ptypes, ptype)
+		}
+	}
+	sort.Strings(ptypes)
+	return ptypes, nil
+}
+
+// loadMembers returns the stored rules of the given ptypes, or of all ptypes if
+// ptypes is empty.
+func (a *Adapter) loadMembers(conn redis.Conn, ptypes []string) ([]CasbinRule, error) {
+	var err error
+	if len(ptypes) == 0 {
+		if ptypes, err = a.storedPTypes(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	var lines []CasbinRule
+	for _, ptype := range ptypes {
+		texts, err := a.backend.LoadAll(conn, a.ptypeKey(ptype))
+		if err != nil {
+			return nil, err
+		}
+		for _, text := range texts {
+			var line CasbinRule
+			if err = a.unmarshalStored(ptype, text, &line); err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// setLoadPolicy is LoadPolicy and LoadFilteredPolicy for PTypeSetLayout and
+// Config.SplitSections. Only the keys of the ptypes in the filter are read.
+func (a *Adapter) setLoadPolicy(ctx context.Context, model model.Model, filter *Filter) error {
+	var ptypes []string
+	if filter != nil {
+		ptypes = filter.PType
+	}
+	var lines []CasbinRule
+	err := a.read(ctx, func(conn redis.Conn) (err error) {
+		lines, err = a.loadMembers(conn, ptypes)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var set filterSet
+	if filter != nil {
+		set = newFilterSet(filter)
+	}
+	in := a.newInterner()
+	for i := range lines {
+		if filter != nil && !set.match(&lines[i]) {
+			continue
+		}
+		in.line(&lines[i])
+		if err = loadPolicyLine(lines[i], model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setGetAllGrouped is GetAllGrouped for PTypeSetLayout and Config.SplitSections.
+func (a *Adapter) setGetAllGrouped() (map[string][][]string, error) {
+	conn, err := a.getConn()
+	if err != nil {
+		return nil, err
+	}
+	defer a.release(conn)
+
+	lines, err := a.loadMembers(conn, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[string][][]string)
+	for _, line := range lines {
+		grouped[line.PType] = append(grouped[line.PType], line.toStringPolicy()[1:])
+	}
+	return grouped, nil
+}
+
+// setPolicyKeys returns the keys holding the policy in PTypeSetLayout and with
+// Config.SplitSections.
+func (a *Adapter) setPolicyKeys(conn redis.Conn) ([]interface{}, error) {
+	ptypes, err := a.storedPTypes(conn)
+	if err != nil {
+		return nil, err
+	}
+	keys := []interface{}{a.ptypesKey()}
+	for _, ptype := range ptypes {
+		keys = append(keys, a.ptypeKey(ptype))
+	}
+	return keys, nil
+}
+
+// marshalMembers serializes rules of ptype the way they are stored under the key
+// of ptype.
+func (a *Adapter) marshalMembers(ptype string, rules [][]string) ([][]byte, error) {
+	texts := make([][]byte, 0, len(rules))
+	for _, rule := range rules {
+		text, err := a.marshalStored(savePolicyLine(ptype, rule))
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
+
+// setAddPolicies is AddPolicy and AddPolicies for PTypeSetLayout and
+// Config.SplitSections.
+func (a *Adapter) setAddPolicies(ptype string, rules [][]string) error {
+	texts, err := a.marshalMembers(ptype, rules)
+	if err != nil || len(texts) == 0 {
+		return err
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	// The ptype is recorded first, so the rules are never stored under an
+	// unrecorded key.
+	if _, err = conn.Do("SADD", a.ptypesKey(), ptype); err != nil {
+		return err
+	}
+	return a.backend.Append(conn, a.ptypeKey(ptype), texts)
+}
+
+// setRemovePolicies is RemovePolicy and RemovePolicies for PTypeSetLayout and
+// Config.SplitSections.
+func (a *Adapter) setRemovePolicies(ptype string, rules [][]string) error {
+	texts, err := a.marshalMembers(ptype, rules)
+	if err != nil || len(texts) == 0 {
+		return err
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	return a.backend.Remove(conn, a.ptypeKey(ptype), texts)
+}
+
+// setRemoveFilteredPolicy is RemoveFilteredPolicy for PTypeSetLayout and
+// Config.SplitSections. Only the rules of ptype are scanned.
+func (a *Adapter) setRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	if a.cjsonMatching {
+		cond, err := filterFieldConditions(a.jsonKeys, ptype, fieldIndex, fieldValues...)
+		if err != nil {
+			return err
+		}
+		_, err = a.doScript(removeFilteredCJSONScript, conn, a.ptypeKey(ptype), cond)
+		return err
+	}
+	return a.backend.RemoveMatching(conn, a.ptypeKey(ptype), a.ruleMatch(sec, ptype, fieldIndex, fieldValues...))
+}
+
+// setUpdatePolicies is UpdatePolicy and UpdatePolicies for PTypeSetLayout and
+// Config.SplitSections.
+func (a *Adapter) setUpdatePolicies(ptype string, oldRules, newRules [][]string) error {
+	oldTexts, err := a.marshalMembers(ptype, oldRules)
+	if err != nil {
+		return err
+	}
+	newTexts, err := a.marshalMembers(ptype, newRules)
+	if err != nil {
+		return err
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	return a.backend.Replace(conn, a.ptypeKey(ptype), oldTexts, newTexts)
+}
+
+// setUpdateFilteredPolicies is UpdateFilteredPolicies for PTypeSetLayout and
+// Config.SplitSections. It returns the replaced rules.
+func (a *Adapter) setUpdateFilteredPolicies(sec, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	newTexts, err := a.marshalMembers(ptype, newRules)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return nil, err
+	}
+	defer a.release(conn)
+
+	// The ptype is recorded first, so the new rules are never stored under an
+	// unrecorded key.
+	if len(newTexts) > 0 {
+		if _, err = conn.Do("SADD", a.ptypesKey(), ptype); err != nil {
+			return nil, err
+		}
+	}
+	var oldTexts [][]byte
+	if a.cjsonMatching {
+		cond, err := filterFieldConditions(a.jsonKeys, ptype, fieldIndex, fieldValues...)
+		if err != nil {
+			return nil, err
+		}
+		args := redis.Args{}.Add(a.ptypeKey(ptype)).Add(cond).AddFlat(newTexts)
+		oldTexts, err = redis.ByteSlices(a.doScript(updateFilteredCJSONScript, conn, args...))
+	} else {
+		match := a.ruleMatch(sec, ptype, fieldIndex, fieldValues...)
+		oldTexts, err = a.backend.ReplaceMatching(conn, a.ptypeKey(ptype), match, newTexts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([][]string, 0, len(oldTexts))
+	for _, text := range oldTexts {
+		var line CasbinRule
+		if err := a.unmarshalStored(ptype, text, &line); err != nil {
+			return nil, err
+		}
+		ret = append(ret, line.toStringPolicy())
+	}
+	return ret, nil
+}
+
+// SetBackend is the StorageBackend of PTypeSetLayout, which stores the rules in
+// a set. It keeps neither their order nor duplicates. The filtered operations
+// decode the rules in the adapter.
+type SetBackend struct{}
+
+// LoadAll returns the members of the set.
+func (b SetBackend) LoadAll(conn redis.Conn, key string) ([][]byte, error) {
+	return redis.ByteSlices(conn.Do("SMEMBERS", key))
+}
+
+// LoadMatching returns the members of the set that match, decoding every
+// member.
+func (b SetBackend) LoadMatching(conn redis.Conn, key string, match RuleMatch) ([][]byte, error) {
+	rules, err := b.LoadAll(conn, key)
+	if err != nil {
+		return nil, err
+	}
+	return matchRules(rules, match)
+}
+
+// Append adds the rules to the set.
+func (b SetBackend) Append(conn redis.Conn, key string, rules [][]byte) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err := conn.Do("SADD", redis.Args{}.Add(key).AddFlat(rules)...)
+	return err
+}
+
+// Remove removes the rules from the set.
+func (b SetBackend) Remove(conn redis.Conn, key string, rules [][]byte) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err := conn.Do("SREM", redis.Args{}.Add(key).AddFlat(rules)...)
+	return err
+}
+
+// RemoveMatching removes the members of the set that match.
+func (b SetBackend) RemoveMatching(conn redis.Conn, key string, match RuleMatch) error {
+	matched, err := b.LoadMatching(conn, key, match)
+	if err != nil {
+		return err
+	}
+	return b.Remove(conn, key, matched)
+}
+
+// Replace replaces the stored members of the set.
+func (b SetBackend) Replace(conn redis.Conn, key string, oldRules, newRules [][]byte) error {
+	if len(oldRules) == 0 {
+		return nil
+	}
+	_, err := runScript(updateMembersScript, conn, redis.Args{}.Add(key, len(oldRules)).AddFlat(oldRules).AddFlat(newRules)...)
+	return err
+}
+
+// ReplaceMatching removes the members of the set that match and adds the new
+// rules, in a transaction.
+func (b SetBackend) ReplaceMatching(conn redis.Conn, key string, match RuleMatch, newRules [][]byte) ([][]byte, error) {
+	matched, err := b.LoadMatching(conn, key, match)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if len(matched) > 0 {
+		if err = conn.Send("SREM", redis.Args{}.Add(key).AddFlat(matched)...); err != nil {
+			return nil, err
+		}
+	}
+	if len(newRules) > 0 {
+		if err = conn.Send("SADD", redis.Args{}.Add(key).AddFlat(newRules)...); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := conn.Do("EXEC")
+	if err == nil {
+		err = execError(reply)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// Clear deletes the set.
+func (b SetBackend) Clear(conn redis.Conn, key string) error {
+	_, err := conn.Do("DEL", key)
+	return err
+}
+
+// validateLayout checks Layout and the options each layout cannot be combined with.
+func (c *Config) validateLayout(v *configCheck) {
+	switch c.Layout {
+	case ListLayout:
+	case PTypeSetLayout:
+		v.exclude("PTypeSetLayout", configOption{"SoftDelete", c.SoftDelete}, configOption{"TrackCreationOrder", c.TrackCreationOrder},
+			configOption{"DisableLua", c.DisableLua}, configOption{"Keys", len(c.Keys) > 0})
+	case StreamLayout:
+		v.exclude("StreamLayout", configOption{"SoftDelete", c.SoftDelete}, configOption{"TrackCreationOrder", c.TrackCreationOrder},
+			configOption{"Keys", len(c.Keys) > 0}, configOption{"Backend", c.Backend != nil})
+	case HashLayout:
+		v.exclude("HashLayout", configOption{"SoftDelete", c.SoftDelete}, configOption{"TrackCreationOrder", c.TrackCreationOrder},
+			configOption{"DisableLua", c.DisableLua})
+	case ZSetLayout:
+		v.exclude("ZSetLayout", configOption{"SoftDelete", c.SoftDelete}, configOption{"TrackCreationOrder", c.TrackCreationOrder},
+			configOption{"DisableLua", c.DisableLua})
+	default:
+		v.report(ErrInvalidValue, "unknown layout %d", c.Layout)
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestPTypeSetLayout(t *testing.T) {
+	for _, encoding := range []Encoding{JSONEncoding, GobEncoding} {
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_layout", Layout: PTypeSetLayout, Encoding: encoding})
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.dropTable()
+
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+		if err = a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatal(err)
+		}
+
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ptypes, err := redis.Strings(conn.Do("SMEMBERS", a.ptypesKey()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(ptypes)
+		if len(ptypes) != 2 || ptypes[0] != "g" || ptypes[1] != "p" {
+			t.Errorf("stored ptypes = %v, supposed to be [g p]", ptypes)
+		}
+		if encoding == JSONEncoding {
+			members, err := redis.Strings(conn.Do("SMEMBERS", a.ptypeKey("g")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(members) != 1 || members[0] != `["alice","data2_admin"]` {
+				t.Errorf("members of %s = %v, supposed to be the fields without the ptype", a.ptypeKey("g"), members)
+			}
+		}
+		a.release(conn)
+
+		// The ptype is derived from the key.
+		e, _ = casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+		if roles, _ := e.GetRolesForUser("alice"); len(roles) != 1 || roles[0] != "data2_admin" {
+			t.Errorf("GetRolesForUser(alice) = %v, supposed to be [data2_admin]", roles)
+		}
+
+		// A filter on the ptype only reads its set.
+		if err = e.LoadFilteredPolicy(&Filter{PType: []string{"p"}, V0: []string{"data2_admin"}}); err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicyWithoutOrder(t, e, [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+		if roles, _ := e.GetRolesForUser("alice"); len(roles) != 0 {
+			t.Errorf("GetRolesForUser(alice) = %v after filtering on p, supposed to be empty", roles)
+		}
+
+		e, _ = casbin.NewEnforcer("examples/rbac_model.conf", a)
+		if _, err = e.AddPolicy("carol", "data3", "read"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = e.UpdatePolicy([]string{"bob", "data2", "write"}, []string{"bob", "data3", "write"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = e.RemoveFilteredPolicy(0, "data2_admin"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = e.RemovePolicy("alice", "data1", "read"); err != nil {
+			t.Fatal(err)
+		}
+		if err = e.LoadPolicy(); err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicyWithoutOrder(t, e, [][]string{{"bob", "data3", "write"}, {"carol", "data3", "read"}})
+
+		grouped, err := a.GetAllGrouped()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(grouped["p"]) != 2 || len(grouped["g"]) != 1 {
+			t.Errorf("GetAllGrouped() = %v", grouped)
+		}
+	}
+
+	if _, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Layout: PTypeSetLayout, SoftDelete: true}); err == nil {
+		t.Error("NewAdapter() with PTypeSetLayout and SoftDelete succeeded, supposed to fail")
+	}
+}
+
+func TestHashLayout(t *testing.T) {
+	for _, encoding := range []Encoding{JSONEncoding, GobEncoding} {
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_hash", Layout: HashLayout, Encoding: encoding})
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.dropTable()
+
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+		if err = a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatal(err)
+		}
+
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := a.marshal(savePolicyLine("g", []string{"alice", "data2_admin"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, err := redis.Bytes(conn.Do("HGET", a.key, ruleDigest(text)))
+		if err != nil || string(stored) != string(text) {
+			t.Errorf("HGET of the digest of the g rule = %q, %v, supposed to be the rule", stored, err)
+		}
+		a.release(conn)
+
+		e, _ = casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+		if err = e.LoadFilteredPolicy(&Filter{PType: []string{"p"}, V0: []string{"data2_admin"}}); err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicyWithoutOrder(t, e, [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+		// Adding a stored rule again does not duplicate it.
+		if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+			t.Fatal(err)
+		}
+		e, _ = casbin.NewEnforcer("examples/rbac_model.conf", a)
+		if _, err = e.AddPolicy("carol", "data3", "read"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = e.UpdatePolicy([]string{"bob", "data2", "write"}, []string{"bob", "data3", "write"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = e.RemoveFilteredPolicy(0, "data2_admin"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = e.RemovePolicy("alice", "data1", "read"); err != nil {
+			t.Fatal(err)
+		}
+		if err = e.LoadPolicy(); err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicyWithoutOrder(t, e, [][]string{{"bob", "data3", "write"}, {"carol", "data3", "read"}})
+
+		if _, err = e.UpdateFilteredPolicies([][]string{{"carol", "data4", "read"}}, 0, "carol"); err != nil {
+			t.Fatal(err)
+		}
+		grouped, err := a.GetAllGrouped()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(grouped["p"]) != 2 || len(grouped["g"]) != 1 {
+			t.Errorf("GetAllGrouped() = %v", grouped)
+		}
+	}
+
+	if _, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Layout: HashLayout, SoftDelete: true}); err == nil {
+		t.Error("NewAdapter() with HashLayout and SoftDelete succeeded, supposed to fail")
+	}
+}
+
+func TestHashLayoutRest(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", Layout: HashLayout})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err = a.SavePolicy(source.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"carol", "data3", "read"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	hash := server.hashes["casbin_rules"]
+	if len(hash) != 5 || len(server.lists) != 0 {
+		t.Errorf("stored %d rules in the hash and %d lists, supposed to be 5 deduplicated rules and no list", len(hash), len(server.lists))
+	}
+	for field, text := range hash {
+		if field != ruleDigest([]byte(text)) {
+			t.Errorf("rule %s stored under %s, supposed to be its digest", text, field)
+		}
+	}
+	server.mu.Unlock()
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"carol", "data3", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestRuleDigest(t *testing.T) {
+	// The scripts compute the same digest with redis.sha1hex.
+	if digest := ruleDigest([]byte("abc")); digest != "a9993e364706816aba3e25717850c26c9cd0d89d" {
+		t.Errorf("ruleDigest(abc) = %s, supposed to be the hex SHA-1", digest)
+	}
+}
+
+func TestMigrateToHash(t *testing.T) {
+	list, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_migrate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list.dropTable()
+	source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err = list.SavePolicy(source.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	if err = list.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_migrate", Layout: HashLayout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.MigrateToHash(); err != nil {
+		t.Fatal(err)
+	}
+	// Migrating a hash again leaves it untouched.
+	if err = a.MigrateToHash(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	if n, err := redis.Int(conn.Do("HLEN", a.key)); err != nil || n != 5 {
+		t.Errorf("HLEN after MigrateToHash = %d, %v, supposed to be 5 deduplicated rules", n, err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	if err = list.MigrateToHash(); err != errLayoutUnsupported {
+		t.Errorf("MigrateToHash() with ListLayout = %v, supposed to be unsupported", err)
+	}
+}
+
+func TestZSetLayout(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_zset", Layout: ZSetLayout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+
+	// denseScores fails unless the rules are scored 1, 2, ...
+	denseScores := func() {
+		t.Helper()
+		// The reply alternates the rules and their scores.
+		reply, err := redis.Strings(conn.Do("ZRANGE", a.key, 0, -1, "WITHSCORES"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < len(reply); i += 2 {
+			if score, err := strconv.Atoi(reply[i]); err != nil || score != i/2+1 {
+				t.Errorf("score of rule %d = %s, supposed to be %d", i/2+1, reply[i], i/2+1)
+			}
+		}
+	}
+
+	source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err = a.SavePolicy(source.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	denseScores()
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	// Adding a stored rule again neither duplicates nor moves it.
+	if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemovePolicy("bob", "data2", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.UpdatePolicy([]string{"data2_admin", "data2", "read"}, []string{"data2_admin", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data3", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+
+	// A rule removed and added again comes last, and updated rules keep their
+	// position.
+	if _, err = e.AddPolicy("bob", "data2", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.UpdateFilteredPolicies([][]string{{"carol", "data4", "read"}}, 0, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.UpdatePolicies([][]string{{"alice", "data1", "read"}}, [][]string{{"alice", "data1", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemoveFilteredPolicy(1, "data2"); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "write"}, {"data2_admin", "data3", "read"}, {"carol", "data4", "read"}})
+
+	if err = e.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	denseScores()
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "write"}, {"data2_admin", "data3", "read"}, {"carol", "data4", "read"}})
+
+	if _, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Layout: ZSetLayout, TrackCreationOrder: true}); err == nil {
+		t.Error("NewAdapter() with ZSetLayout and TrackCreationOrder succeeded, supposed to fail")
+	}
+}
+
+func TestPTypeSetLayoutDuplicates(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_layout_dup", Layout: PTypeSetLayout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+
+	rule := []string{"alice", "data1", "read"}
+	for i := 0; i < 3; i++ {
+		if err = a.AddPolicy("p", "p", rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = a.AddPolicies("p", "p", [][]string{rule, rule}); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	if n, err := redis.Int(conn.Do("SCARD", a.ptypeKey("p"))); err != nil || n != 1 {
+		t.Errorf("SCARD after adding a rule 5 times = %d, %v, supposed to store it once", n, err)
+	}
+
+	// A single removal removes the rule for good.
+	if err = a.RemovePolicy("p", "p", rule); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{})
+}
+
+func TestUnorderedLayouts(t *testing.T) {
+	m, err := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = priority(p.eft) || deny
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return noScriptConn{}, nil }}
+	for _, layout := range []Layout{PTypeSetLayout, HashLayout} {
+		a, err := NewAdapter(&Config{Pool: pool, Layout: layout})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = a.LoadPolicy(m); !errors.Is(err, ErrUnordered) {
+			t.Errorf("LoadPolicy() of a priority model with layout %d = %v, supposed to fail with ErrUnordered", layout, err)
+		}
+		if err = a.LoadFilteredPolicy(m, &Filter{V0: []string{"alice"}}); !errors.Is(err, ErrUnordered) {
+			t.Errorf("LoadFilteredPolicy() of a priority model with layout %d = %v, supposed to fail with ErrUnordered", layout, err)
+		}
+		if err = a.SavePolicy(m); !errors.Is(err, ErrUnordered) {
+			t.Errorf("SavePolicy() of a priority model with layout %d = %v, supposed to fail with ErrUnordered", layout, err)
+		}
+	}
+
+	a, err := NewAdapter(&Config{Pool: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.LoadPolicy(m); errors.Is(err, ErrUnordered) {
+		t.Errorf("LoadPolicy() of a priority model with ListLayout = %v", err)
+	}
+}
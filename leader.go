@@ -0,0 +1,117 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// renewLeadershipScript extends the lease only if it is still held by ARGV[1].
+var renewLeadershipScript = redis.NewScript(1, `
+	if redis.call('get', KEYS[1]) == ARGV[1] then
+		return redis.call('pexpire', KEYS[1], ARGV[2])
+	end
+	return 0
+`)
+
+// releaseLeadershipScript deletes the lease only if it is still held by ARGV[1].
+var releaseLeadershipScript = redis.NewScript(1, `
+	if redis.call('get', KEYS[1]) == ARGV[1] then
+		return redis.call('del', KEYS[1])
+	end
+	return 0
+`)
+
+func (a *Adapter) leaderKey() string {
+	return a.key + ":leader"
+}
+
+// AcquireLeadership tries to become the single writer for the policy key by
+// taking a lease that expires after ttl. If another instance holds the lease,
+// isLeader is false. The leader must call renew more often than ttl to keep the
+// lease, and release to give it up; both are no-ops once the lease is lost.
+// With Config.Fencing, the new leader takes the next fencing token.
+func (a *Adapter) AcquireLeadership(ctx context.Context, ttl time.Duration) (isLeader bool, renew func(), release func(), err error) {
+	noop := func() {}
+	if err := a.begin(); err != nil {
+		return false, noop, noop, err
+	}
+	defer a.end()
+
+	millis := int64(ttl / time.Millisecond)
+	if millis <= 0 {
+		millis = 1
+	}
+
+	token := make([]byte, 16)
+	if _, err = rand.Read(token); err != nil {
+		return false, noop, noop, err
+	}
+	id := hex.EncodeToString(token)
+
+	conn, err := a.getConn()
+	if err != nil {
+		return false, noop, noop, err
+	}
+	defer a.release(conn)
+
+	if a.fenceToken != nil {
+		fence, err := redis.Int64(a.doScript(acquireFencedScript, conn, a.leaderKey(), a.metaKey(), id, millis))
+		if err != nil || fence == 0 {
+			return false, noop, noop, err
+		}
+		atomic.StoreInt64(a.fenceToken, fence)
+	} else {
+		_, err = redis.String(redis.DoContext(conn, ctx, "SET", a.leaderKey(), id, "PX", millis, "NX"))
+		if err == redis.ErrNil {
+			return false, noop, noop, nil
+		}
+		if err != nil {
+			return false, noop, noop, err
+		}
+	}
+
+	renew = func() {
+		if a.begin() != nil {
+			return
+		}
+		defer a.end()
+		conn, err := a.getConn()
+		if err != nil {
+			return
+		}
+		defer a.release(conn)
+		_, _ = a.doScript(renewLeadershipScript, conn, a.leaderKey(), id, millis)
+	}
+	release = func() {
+		if a.begin() != nil {
+			return
+		}
+		defer a.end()
+		conn, err := a.getConn()
+		if err != nil {
+			return
+		}
+		defer a.release(conn)
+		_, _ = a.doScript(releaseLeadershipScript, conn, a.leaderKey(), id)
+	}
+	return true, renew, release, nil
+}
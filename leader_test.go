@@ -0,0 +1,92 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireLeadership(t *testing.T) {
+	config := &Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_leader"}
+	a1, err := NewAdapter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := NewAdapter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a1.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("DEL", a1.leaderKey()); err != nil {
+		t.Fatal(err)
+	}
+	a1.release(conn)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	leaders := make([]bool, 2)
+	releases := make([]func(), 2)
+	for i, a := range []*Adapter{a1, a2} {
+		wg.Add(1)
+		go func(i int, a *Adapter) {
+			defer wg.Done()
+			isLeader, _, release, err := a.AcquireLeadership(ctx, 10*time.Second)
+			if err != nil {
+				t.Error(err)
+			}
+			leaders[i] = isLeader
+			releases[i] = release
+		}(i, a)
+	}
+	wg.Wait()
+	if leaders[0] == leaders[1] {
+		t.Fatalf("AcquireLeadership() leaders = %v, supposed to elect exactly one", leaders)
+	}
+
+	// The follower takes over once the leader releases.
+	leader, follower := 0, 1
+	if leaders[1] {
+		leader, follower = 1, 0
+	}
+	followerAdapter := []*Adapter{a1, a2}[follower]
+	releases[follower]()
+	if isLeader, _, _, err := followerAdapter.AcquireLeadership(ctx, 10*time.Second); err != nil || isLeader {
+		t.Fatalf("AcquireLeadership() = %v, %v while the lease is held, supposed to be false", isLeader, err)
+	}
+	releases[leader]()
+	isLeader, _, release, err := followerAdapter.AcquireLeadership(ctx, 10*time.Second)
+	if err != nil || !isLeader {
+		t.Fatalf("AcquireLeadership() = %v, %v after release, supposed to be true", isLeader, err)
+	}
+	release()
+
+	// An unrenewed lease expires.
+	isLeader, renew, _, err := a1.AcquireLeadership(ctx, 200*time.Millisecond)
+	if err != nil || !isLeader {
+		t.Fatalf("AcquireLeadership() = %v, %v, supposed to be true", isLeader, err)
+	}
+	renew()
+	time.Sleep(400 * time.Millisecond)
+	if isLeader, _, release, err = a2.AcquireLeadership(ctx, time.Second); err != nil || !isLeader {
+		t.Fatalf("AcquireLeadership() = %v, %v after expiry, supposed to be true", isLeader, err)
+	}
+	release()
+}
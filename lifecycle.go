@@ -0,0 +1,116 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by operations started after Close.
+var ErrClosed = errors.New("redis adapter is closed")
+
+// ErrAdapterClosed is ErrClosed.
+var ErrAdapterClosed = ErrClosed
+
+// defaultCloseTimeout is the default value of Config.CloseTimeout.
+const defaultCloseTimeout = 10 * time.Second
+
+// lifecycle tracks the operations in flight, so that Close can wait for them.
+// Copies of the adapter, such as the one of SelfTest, share it.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	inflight int
+	idle     chan struct{}
+	// done is closed by Close, to stop long-running operations such as WatchKeyspace.
+	done chan struct{}
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{done: make(chan struct{})}
+}
+
+// begin registers an operation, unless the adapter is closed. Every successful
+// call must be followed by a call to end. The operation keeps the key of the
+// adapter until then, see SetKey.
+func (a *Adapter) begin() error {
+	a.state.mu.Lock()
+	if a.state.closed {
+		a.state.mu.Unlock()
+		return ErrClosed
+	}
+	a.state.inflight++
+	a.state.mu.Unlock()
+
+	a.keyMu.RLock()
+	return nil
+}
+
+// end unregisters an operation, waking up Close after the last one.
+func (a *Adapter) end() {
+	a.keyMu.RUnlock()
+
+	a.state.mu.Lock()
+	defer a.state.mu.Unlock()
+
+	a.state.inflight--
+	if a.state.inflight == 0 && a.state.idle != nil {
+		close(a.state.idle)
+		a.state.idle = nil
+	}
+}
+
+// Close rejects new operations with ErrClosed, waits up to Config.CloseTimeout
+// for the operations in flight to finish, then closes the connection or the pool
+// of the adapter. Config.Pool is left open unless Config.ClosePoolOnShutdown.
+// If operations are still in flight after the timeout, they may fail, and Close
+// returns an error after closing anyway. WatchKeyspace returns ErrClosed right away.
+// Closing a closed adapter does nothing and returns nil.
+func (a *Adapter) Close() error {
+	a.state.mu.Lock()
+	if a.state.closed {
+		a.state.mu.Unlock()
+		return nil
+	}
+	a.state.closed = true
+	close(a.state.done)
+	var idle chan struct{}
+	if a.state.inflight > 0 {
+		idle = make(chan struct{})
+		a.state.idle = idle
+	}
+	a.state.mu.Unlock()
+
+	var err error
+	if idle != nil {
+		timer := time.NewTimer(a.closeTimeout)
+		select {
+		case <-idle:
+			timer.Stop()
+		case <-timer.C:
+			a.state.mu.Lock()
+			err = fmt.Errorf("closed with %d operations still in flight after %v", a.state.inflight, a.closeTimeout)
+			a.state.mu.Unlock()
+		}
+	}
+
+	a.close()
+	runtime.SetFinalizer(a, nil)
+	return err
+}
@@ -0,0 +1,270 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Run with -race.
+func TestCloseWaitsForOperations(t *testing.T) {
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", "127.0.0.1:6379")
+		},
+	}
+	a, err := NewAdapter(&Config{Pool: pool, Key: "casbin_rules_close"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				rule := []string{fmt.Sprintf("user%d", i), fmt.Sprintf("data%d", j), "read"}
+				err := a.AddPolicy("p", "p", rule)
+				if err == nil {
+					_, err = a.GetAllGrouped()
+				}
+				if err == nil && j%2 == 0 {
+					err = a.RemovePolicy("p", "p", rule)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(i)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err = a.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != ErrClosed {
+			t.Errorf("operation failed with %v during Close, supposed to be %v", err, ErrClosed)
+		}
+	}
+
+	if err = a.AddPolicy("p", "p", []string{"late", "data", "read"}); err != ErrClosed {
+		t.Errorf("AddPolicy() after Close() = %v, supposed to be %v", err, ErrClosed)
+	}
+	if err = a.Close(); err != nil {
+		t.Errorf("second Close() = %v, supposed to be nil", err)
+	}
+
+	// Every stored rule is intact.
+	b, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_close"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = casbin.NewEnforcer("examples/rbac_model.conf", b); err != nil {
+		t.Errorf("loading the policy written during Close() failed: %v", err)
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", CloseTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An operation that never finishes.
+	if err = a.begin(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err = a.Close(); err == nil {
+		t.Error("Close() with an operation in flight after the timeout succeeded, supposed to fail")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Close() returned after %v, supposed to wait for the timeout", elapsed)
+	}
+	a.end()
+}
+
+func TestClosedOperations(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_closed", AuditStream: "casbin_audit_closed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	m := e.GetModel()
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	rule := []string{"alice", "data1", "read"}
+	operations := map[string]func() error{
+		"LoadPolicy":         func() error { return a.LoadPolicy(m) },
+		"LoadFilteredPolicy": func() error { return a.LoadFilteredPolicy(m, &Filter{PType: []string{"p"}}) },
+		"SavePolicy":         func() error { return a.SavePolicy(m) },
+		"AddPolicy":          func() error { return a.AddPolicy("p", "p", rule) },
+		"AddPolicyCtx":       func() error { return a.AddPolicyCtx(ctx, "p", "p", rule) },
+		"AddPolicies":        func() error { return a.AddPolicies("p", "p", [][]string{rule}) },
+		"RemovePolicy":       func() error { return a.RemovePolicy("p", "p", rule) },
+		"RemovePolicies":     func() error { return a.RemovePolicies("p", "p", [][]string{rule}) },
+		"RemoveFilteredPolicy": func() error {
+			return a.RemoveFilteredPolicy("p", "p", 0, "alice")
+		},
+		"UpdatePolicy":   func() error { return a.UpdatePolicy("p", "p", rule, rule) },
+		"UpdatePolicies": func() error { return a.UpdatePolicies("p", "p", [][]string{rule}, [][]string{rule}) },
+		"UpdateFilteredPolicies": func() error {
+			_, err := a.UpdateFilteredPolicies("p", "p", [][]string{rule}, 0, "alice")
+			return err
+		},
+		"GetAllGrouped": func() error {
+			_, err := a.GetAllGrouped()
+			return err
+		},
+		"HealthReport": func() error {
+			_, err := a.HealthReport()
+			return err
+		},
+		"AcquireLeadership": func() error {
+			_, _, _, err := a.AcquireLeadership(ctx, time.Second)
+			return err
+		},
+		"WatchKeyspace": func() error { return a.WatchKeyspace(ctx, func() {}) },
+		"Version": func() error {
+			_, err := a.Version()
+			return err
+		},
+		"RepairVersion": a.RepairVersion,
+		"ReadAudit": func() error {
+			_, err := a.ReadAudit("", 10)
+			return err
+		},
+		"PurgeDeleted": func() error { return a.PurgeDeleted(time.Hour) },
+		"SelfTest":     func() error { return a.SelfTest(ctx) },
+	}
+	for name, operation := range operations {
+		if err := operation(); err != ErrClosed {
+			t.Errorf("%s() after Close() = %v, supposed to be %v", name, err, ErrClosed)
+		}
+	}
+}
+
+func TestCloseStopsWatchKeyspace(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_closed_watch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- a.WatchKeyspace(context.Background(), func() {})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-done:
+		if err != ErrClosed && err != ErrKeyspaceNotificationsDisabled {
+			t.Errorf("WatchKeyspace() = %v after Close(), supposed to be %v", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Error("WatchKeyspace() did not return after Close()")
+	}
+}
+
+func TestUserPoolLeftOpen(t *testing.T) {
+	newPool := func() *redis.Pool {
+		return &redis.Pool{Dial: func() (redis.Conn, error) { return noScriptConn{}, nil }}
+	}
+	pool := newPool()
+	a, err := NewAdapter(&Config{Pool: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewAdapterWithPool(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalizer(b)
+	conn := pool.Get()
+	if err = conn.Err(); err != nil {
+		t.Errorf("pool after closing and finalizing its adapters: %v, supposed to be open", err)
+	}
+	conn.Close()
+
+	pool = newPool()
+	a, err = NewAdapter(&Config{Pool: pool, ClosePoolOnShutdown: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pool.Get().Err(); err == nil {
+		t.Error("pool open after closing its adapter with ClosePoolOnShutdown")
+	}
+}
+
+// closeCountingConn counts how many times it is closed.
+type closeCountingConn struct {
+	noScriptConn
+	closes *int32
+}
+
+func (c closeCountingConn) Close() error {
+	atomic.AddInt32(c.closes, 1)
+	return nil
+}
+
+func TestCloseOnce(t *testing.T) {
+	var closes int32
+	pool := &redis.Pool{MaxIdle: 1, Dial: func() (redis.Conn, error) { return closeCountingConn{closes: &closes}, nil }}
+	a, err := NewAdapter(&Config{Pool: pool, ClosePoolOnShutdown: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Version(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Close(); err != nil {
+		t.Errorf("second Close() = %v, supposed to be nil", err)
+	}
+	finalizer(a)
+	if n := atomic.LoadInt32(&closes); n != 1 {
+		t.Errorf("connection closed %d times, supposed to be closed once", n)
+	}
+	if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != ErrAdapterClosed {
+		t.Errorf("AddPolicy() after Close() = %v, supposed to be %v", err, ErrAdapterClosed)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsafeFieldValue is returned with Config.StrictFieldValidation for field
+// values that JSON escapes, which the raw matching of filters could miss or
+// match across fields.
+var ErrUnsafeFieldValue = errors.New("field value is unsafe for raw matching")
+
+// cjsonMatcher is the Lua function of the filtered scripts matching a stored
+// rule, decoded by cjson, against the JSON object of conditions, from JSON keys
+// to values.
+const cjsonMatcher = `
+	local function matches(text, cond)
+		local ok, rule = pcall(cjson.decode, text)
+		if not ok or type(rule) ~= 'table' then
+			return false
+		end
+		for k, v in pairs(cond) do
+			if rule[k] ~= v then
+				return false
+			end
+		end
+		return true
+	end
+`
+
+// removeFilteredCJSONScript is the RemoveFilteredPolicy script matching the
+// decoded rules. It removes the rules matching the conditions in ARGV[1].
+var removeFilteredCJSONScript = newWriteScript(1, cjsonMatcher+`
+	local key = KEYS[1]
+	local cond = cjson.decode(ARGV[1])
+
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if matches(r[i], cond) then
+			redis.call('lset', key, i-1, '`+tombstone+`')
+		end
+	end
+	redis.call('lrem', key, 0, '`+tombstone+`')
+	return
+`)
+
+// updateFilteredCJSONScript is the UpdateFilteredPolicies script matching the
+// decoded rules. The rules matching the conditions in ARGV[1] are
+// replaced in place by the new rules following it, in order. Matching rules
+// left over are removed, and new rules left over are appended.
+var updateFilteredCJSONScript = newWriteScript(1, cjsonMatcher+`
+	local key = KEYS[1]
+	local cond = cjson.decode(ARGV[1])
+	local n = #ARGV - 1
+
+	local ret = {}
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if matches(r[i], cond) then
+			table.insert(ret, r[i])
+			if #ret <= n then
+				redis.call('lset', key, i-1, ARGV[#ret+1])
+			else
+				redis.call('lset', key, i-1, '`+tombstone+`')
+			end
+		end
+	end
+	if #ret > n then
+		redis.call('lrem', key, 0, '`+tombstone+`')
+	end
+	for i=#ret+1, n do
+		redis.call('rpush', key, ARGV[i+1])
+	end
+
+	return ret
+`)
+
+// filterFieldConditions returns the JSON object of the conditions on the JSON
+// keys of the fields matched by RemoveFilteredPolicy, for the scripts matching
+// the decoded rules. Empty values are wildcards and left out.
+func filterFieldConditions(keys JSONKeys, ptype string, fieldIndex int, fieldValues ...string) ([]byte, error) {
+	cond := map[string]string{keys[0]: ptype}
+	for i, v := range fieldValues {
+		idx := fieldIndex + i
+		if idx < 0 || idx >= maxRuleFields || v == "" {
+			continue
+		}
+		cond[keys[idx+1]] = v
+	}
+	return json.Marshal(cond)
+}
+
+// unsafeForRawMatching reports whether JSON escapes r, e.g. quotes, backslashes,
+// control characters and the HTML characters encoding/json escapes.
+func unsafeForRawMatching(r rune) bool {
+	return r < 0x20 || strings.ContainsRune("\"\\<>&\u2028\u2029", r)
+}
+
+// checkFieldValues rejects, with Config.StrictFieldValidation, the values that
+// raw matching cannot handle.
+func (a *Adapter) checkFieldValues(values ...string) error {
+	if !a.strictFields {
+		return nil
+	}
+	for _, v := range values {
+		if strings.IndexFunc(v, unsafeForRawMatching) >= 0 {
+			return fmt.Errorf("%w: %q", ErrUnsafeFieldValue, v)
+		}
+	}
+	return nil
+}
+
+// validateMatching checks the options RawPatternMatching depends on.
+func (c *Config) validateMatching(v *configCheck) {
+	if c.RawPatternMatching && (c.Encoding != JSONEncoding || c.Codec != nil) {
+		v.report(ErrIgnoredOption, "RawPatternMatching is ignored without JSON encoding")
+	}
+	if c.StrictFieldValidation && !c.RawPatternMatching {
+		v.report(ErrIgnoredOption, "StrictFieldValidation is ignored without RawPatternMatching")
+	}
+}
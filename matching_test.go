@@ -0,0 +1,155 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// foreignRows are rules written by other clients, with their keys in another
+// order and padded with whitespace.
+var foreignRows = []string{
+	`{"PType":"p","V0":"alice","V1":"data1","V2":"read"}`,
+	`{"V1":"data1","V0":"bob","PType":"p","V2":"write"}`,
+	` { "PType" : "p", "V0" : "carol", "V1" : "data2", "V2" : "read" } `,
+	`{"V2":"write","PType":"p","V1":"data2","V0":"carol"}`,
+}
+
+func TestStrictFieldValidation(t *testing.T) {
+	adversarial := []string{`alice","V1":"data9`, `data\1`, "a<b", "tab\there"}
+
+	strict, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_strict", StrictFieldValidation: true, RawPatternMatching: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict.dropTable()
+	if err = strict.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy() of a safe rule = %v", err)
+	}
+	for _, value := range adversarial {
+		if err = strict.AddPolicy("p", "p", []string{value, "data1", "read"}); !errors.Is(err, ErrUnsafeFieldValue) {
+			t.Errorf("AddPolicy() with %q = %v, supposed to be ErrUnsafeFieldValue", value, err)
+		}
+		if err = strict.RemoveFilteredPolicy("p", "p", 0, value); !errors.Is(err, ErrUnsafeFieldValue) {
+			t.Errorf("RemoveFilteredPolicy() with %q = %v, supposed to be ErrUnsafeFieldValue", value, err)
+		}
+	}
+	if rules, _ := strict.GetAllPolicies(); len(rules) != 1 {
+		t.Errorf("GetAllPolicies() = %v, supposed to hold only the safe rule", rules)
+	}
+
+	// Without RawPatternMatching the decoded rules are matched, so the values
+	// are accepted and filters match them exactly.
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_cjson"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	for _, value := range adversarial {
+		if err = a.AddPolicy("p", "p", []string{value, "data1", "read"}); err != nil {
+			t.Fatalf("AddPolicy() with %q = %v", value, err)
+		}
+	}
+	if err = a.AddPolicy("p", "p", []string{"alice", "data9", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err = e.LoadFilteredPolicy(&Filter{V0: []string{adversarial[0]}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{adversarial[0], "data1", "read"}})
+
+	if err = a.RemoveFilteredPolicy("p", "p", 0, adversarial[0]); err != nil {
+		t.Fatal(err)
+	}
+	old, err := a.UpdateFilteredPolicies("p", "p", [][]string{{"a<b", "data2", "write"}}, 0, "a<b")
+	if err != nil || len(old) != 1 || old[0][1] != "a<b" {
+		t.Fatalf("UpdateFilteredPolicies() = %v, %v, supposed to replace the rule of a<b", old, err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{`data\1`, "data1", "read"}, {"a<b", "data2", "write"}, {"tab\there", "data1", "read"}, {"alice", "data9", "read"}})
+}
+
+func TestStructuralMatching(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{"casbin_rules": append([]string{}, foreignRows...)}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	for _, test := range []struct {
+		filter *Filter
+		want   [][]string
+	}{
+		{&Filter{V1: []string{"data1"}}, [][]string{{"alice", "data1", "read"}, {"bob", "data1", "write"}}},
+		{&Filter{V0: []string{"carol"}}, [][]string{{"carol", "data2", "read"}, {"carol", "data2", "write"}}},
+		{&Filter{PType: []string{"p"}, V2: []string{"write"}}, [][]string{{"bob", "data1", "write"}, {"carol", "data2", "write"}}},
+	} {
+		e.ClearPolicy()
+		if err = a.LoadFilteredPolicy(e.GetModel(), test.filter); err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicy(t, e, test.want)
+	}
+
+	// RawPatternMatching only matches the rules in the form this adapter writes.
+	raw, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", RawPatternMatching: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.ClearPolicy()
+	if err = raw.LoadFilteredPolicy(e.GetModel(), &Filter{V0: []string{"carol"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{})
+}
+
+func TestStructuralMatchingRedis(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_structural"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	if _, err = conn.Do("RPUSH", redis.Args{}.Add(a.key).AddFlat(foreignRows)...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.RemoveFilteredPolicy("p", "p", 0, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	old, err := a.UpdateFilteredPolicies("p", "p", [][]string{{"bob", "data3", "write"}}, 1, "data1", "write")
+	if err != nil || len(old) != 1 || old[0][1] != "bob" {
+		t.Fatalf("UpdateFilteredPolicies() = %v, %v, supposed to replace the rule of bob", old, err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data3", "write"}})
+}
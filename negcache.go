@@ -0,0 +1,120 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+// negativeCacheSize bounds the number of filters negativeCache remembers.
+const negativeCacheSize = 1024
+
+// negativeCache remembers the filters that loaded no rule, e.g. the subjects
+// without policies, for Config.NegativeCacheTTL. An entry only holds for the
+// policy version it was loaded at, so any write invalidates it.
+type negativeCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]negativeEntry
+}
+
+type negativeEntry struct {
+	version int64
+	expires time.Time
+}
+
+func newNegativeCache(ttl time.Duration) *negativeCache {
+	return &negativeCache{ttl: ttl, entries: make(map[string]negativeEntry)}
+}
+
+// hit reports whether the filter with key loaded no rule at version.
+func (c *negativeCache) hit(key string, version int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if entry.version != version || !time.Now().Before(entry.expires) {
+		delete(c.entries, key)
+		return false
+	}
+	return true
+}
+
+// add records that the filter with key loaded no rule at version.
+func (c *negativeCache) add(key string, version int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= negativeCacheSize {
+		for k, entry := range c.entries {
+			if entry.version != version || !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= negativeCacheSize {
+			c.entries = make(map[string]negativeEntry)
+		}
+	}
+	c.entries[key] = negativeEntry{version: version, expires: now.Add(c.ttl)}
+}
+
+// reset forgets every filter.
+func (c *negativeCache) reset() {
+	c.mu.Lock()
+	c.entries = make(map[string]negativeEntry)
+	c.mu.Unlock()
+}
+
+// loadFilteredCached is loadFilteredPolicy skipping the filters the negative
+// cache knows to load no rule at the current policy version.
+func (a *Adapter) loadFilteredCached(ctx context.Context, model model.Model, filter *Filter) error {
+	if a.negativeCache == nil {
+		return a.loadFilteredPolicy(ctx, model, filter)
+	}
+
+	key, err := json.Marshal(filter)
+	if err != nil {
+		return err
+	}
+	// The version is read before the rules, so a write in between invalidates the entry.
+	var version int64
+	err = a.read(ctx, func(conn redis.Conn) (err error) {
+		version, err = a.readVersion(conn)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if a.negativeCache.hit(string(key), version) {
+		return nil
+	}
+
+	before := countRules(model)
+	if err = a.loadFilteredPolicy(ctx, model, filter); err != nil {
+		return err
+	}
+	if countRules(model) == before {
+		a.negativeCache.add(string(key), version)
+	}
+	return nil
+}
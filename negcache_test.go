@@ -0,0 +1,73 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestNegativeCache(t *testing.T) {
+	var reads int
+	config := &Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_negative", NegativeCacheTTL: time.Minute,
+		CommandHook: func(cmd string, args []interface{}) {
+			if cmd == "LRANGE" {
+				reads++
+			}
+		}}
+	a, err := NewAdapter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+	writer, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_negative"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	load := func() [][]string {
+		t.Helper()
+		m, err := model.NewModelFromFile("examples/rbac_model.conf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = a.LoadFilteredPolicy(m, &Filter{V0: []string{"carol"}}); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetPolicy("p", "p")
+	}
+
+	reads = 0
+	if policy := load(); len(policy) != 0 {
+		t.Fatalf("LoadFilteredPolicy() loaded %v, supposed to be empty", policy)
+	}
+	if reads != 1 {
+		t.Fatalf("first LoadFilteredPolicy() read the policy %d times, supposed to be once", reads)
+	}
+	load()
+	if reads != 1 {
+		t.Errorf("second LoadFilteredPolicy() read the policy, supposed to skip Redis")
+	}
+
+	// A write from another adapter bumps the version, which invalidates the cache.
+	if err = writer.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if policy := load(); len(policy) != 1 || reads != 2 {
+		t.Errorf("LoadFilteredPolicy() after a write = %v, read the policy %d times, supposed to reload carol's rule", policy, reads)
+	}
+}
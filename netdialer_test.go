@@ -0,0 +1,104 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sync"
+	"testing"
+)
+
+// tunnel is a NetDialer reaching every address through l, counting the dials.
+type tunnel struct {
+	mu    sync.Mutex
+	l     net.Listener
+	addrs []string
+}
+
+func (d *tunnel) dial(network, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	d.addrs = append(d.addrs, addr)
+	d.mu.Unlock()
+	return net.Dial(network, d.l.Addr().String())
+}
+
+func (d *tunnel) dials() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.addrs...)
+}
+
+func TestNetDialer(t *testing.T) {
+	l := listen(t)
+	var mu sync.Mutex
+	var commands []string
+	go serveNil(l, func(args []string) {
+		mu.Lock()
+		commands = append(commands, args[0])
+		mu.Unlock()
+	})
+
+	// The address is only reachable through the tunnel, and the adapter still
+	// authenticates over the tunneled connection.
+	d := &tunnel{l: l}
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "redis.internal:6379", Password: "secret", NetDialer: d.dial})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if _, err = a.Version(); err != nil {
+		t.Fatalf("Version() through the tunnel = %v", err)
+	}
+	if dials := d.dials(); len(dials) != 1 || dials[0] != "redis.internal:6379" {
+		t.Errorf("NetDialer dialed %v, want [redis.internal:6379]", dials)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(commands) == 0 || commands[0] != "AUTH" {
+		t.Errorf("commands through the tunnel = %v, supposed to start with AUTH", commands)
+	}
+}
+
+func TestNetDialerTLS(t *testing.T) {
+	ca := newTestCert(t, nil, true)
+	serverCert := newTestCert(t, ca, false, "redis.internal")
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.der}, PrivateKey: serverCert.key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveNil(l, nil)
+
+	// TLS runs over the tunneled connection and verifies the server by the
+	// name of the address, not of the tunnel.
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca.cert)
+	d := &tunnel{l: l}
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "redis.internal:6379", TLSConfig: &tls.Config{RootCAs: caPool}, NetDialer: d.dial})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if _, err = a.Version(); err != nil {
+		t.Fatalf("Version() over TLS through the tunnel = %v", err)
+	}
+	if dials := d.dials(); len(dials) != 1 {
+		t.Errorf("NetDialer dialed %v, want one connection", dials)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrScriptingUnavailable is returned, possibly wrapped, by operations that need
+// Lua scripting when the server does not allow it, e.g. managed offerings that
+// disable EVAL. Config.DisableLua makes the policy operations work without it.
+var ErrScriptingUnavailable = errors.New("lua scripting is unavailable")
+
+// errLuaDisabled is returned by the operations that need Lua scripting when
+// Config.DisableLua is set.
+var errLuaDisabled = fmt.Errorf("%w: disabled by Config.DisableLua", ErrScriptingUnavailable)
+
+// maxRewriteAttempts bounds how many times rewriteList retries when the list
+// changes while it is rewritten.
+const maxRewriteAttempts = 3
+
+// scriptingError converts the errors of a server refusing to run scripts to
+// ErrScriptingUnavailable, and returns other errors as they are.
+func scriptingError(err error) error {
+	if _, ok := err.(redis.Error); !ok {
+		return err
+	}
+	msg := strings.ToLower(err.Error())
+	refused := (strings.Contains(msg, "unknown command") || strings.HasPrefix(msg, "noperm")) && strings.Contains(msg, "eval")
+	if refused || (strings.Contains(msg, "scripting") && strings.Contains(msg, "disabled")) {
+		return fmt.Errorf("%w, set Config.DisableLua to work without it: %v", ErrScriptingUnavailable, err)
+	}
+	return err
+}
+
+// doScript runs script on conn, unless Config.DisableLua is set.
+func (a *Adapter) doScript(script *redis.Script, conn redis.Conn, keysAndArgs ...interface{}) (interface{}, error) {
+	if a.disableLua {
+		return nil, errLuaDisabled
+	}
+	return runScript(script, conn, keysAndArgs...)
+}
+
+// runScript runs script on conn, converting the errors of a server refusing to
+// run it with scriptingError.
+func runScript(script *redis.Script, conn redis.Conn, keysAndArgs ...interface{}) (interface{}, error) {
+	reply, err := script.Do(conn, keysAndArgs...)
+	return reply, scriptingError(err)
+}
+
+// rewriteList replaces the policy list with edit applied to its entries, which
+// Config.DisableLua uses instead of the scripts editing the list in place. The
+// list is watched, so the rewrite is retried if another client changes it.
+func (a *Adapter) rewriteList(conn redis.Conn, edit func(values [][]byte) [][]byte) error {
+	for i := 0; i < maxRewriteAttempts; i++ {
+		if _, err := conn.Do("WATCH", a.key); err != nil {
+			return err
+		}
+		values, err := redis.ByteSlices(conn.Do("LRANGE", a.key, 0, -1))
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+		values = edit(values)
+
+		if err = conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if err = conn.Send("DEL", a.key); err != nil {
+			return err
+		}
+		if len(values) > 0 {
+			if err = conn.Send("RPUSH", redis.Args{}.Add(a.key).AddFlat(values)...); err != nil {
+				return err
+			}
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return nil
+		}
+	}
+	return errors.New("policy kept changing while it was rewritten")
+}
+
+// rewriteRemove is removeValuesScript for Config.DisableLua.
+func (a *Adapter) rewriteRemove(conn redis.Conn, texts [][]byte) error {
+	set := make(map[string]struct{}, len(texts))
+	for _, text := range texts {
+		set[string(text)] = struct{}{}
+	}
+	return a.rewriteList(conn, func(values [][]byte) [][]byte {
+		kept := values[:0]
+		for _, value := range values {
+			if _, ok := set[string(value)]; !ok {
+				kept = append(kept, value)
+			}
+		}
+		return kept
+	})
+}
+
+// rewriteReplace is replaceValuesScript for Config.DisableLua.
+func (a *Adapter) rewriteReplace(conn redis.Conn, oldTexts [][]byte, newTexts []string) error {
+	set := make(map[string]struct{}, len(oldTexts))
+	for _, text := range oldTexts {
+		set[string(text)] = struct{}{}
+	}
+	return a.rewriteList(conn, func(values [][]byte) [][]byte {
+		replaced := 0
+		kept := values[:0]
+		for _, value := range values {
+			if _, ok := set[string(value)]; !ok {
+				kept = append(kept, value)
+				continue
+			}
+			if replaced < len(newTexts) {
+				kept = append(kept, []byte(newTexts[replaced]))
+			}
+			replaced++
+		}
+		for ; replaced < len(newTexts); replaced++ {
+			kept = append(kept, []byte(newTexts[replaced]))
+		}
+		return kept
+	})
+}
+
+// rewriteUpdate replaces the occurrences of each old text by the new text at the
+// same index, only the first occurrence if once is set. It is the update script
+// of UpdatePolicy and UpdatePolicies for Config.DisableLua.
+func (a *Adapter) rewriteUpdate(conn redis.Conn, oldTexts, newTexts []string, once bool) error {
+	updates := make(map[string]string, len(oldTexts))
+	for i, text := range oldTexts {
+		updates[text] = newTexts[i]
+	}
+	return a.rewriteList(conn, func(values [][]byte) [][]byte {
+		for i, value := range values {
+			if text, ok := updates[string(value)]; ok {
+				values[i] = []byte(text)
+				if once {
+					break
+				}
+			}
+		}
+		return values
+	})
+}
+
+// validateLua checks the options DisableLua cannot be combined with.
+func (c *Config) validateLua(v *configCheck) {
+	if !c.DisableLua {
+		return
+	}
+	v.exclude("DisableLua", configOption{"SoftDelete", c.SoftDelete}, configOption{"RepairVersionOnStart", c.RepairVersionOnStart},
+		configOption{"Fencing", c.Fencing})
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// noScriptConn is a connection to a server refusing scripts with refusal.
+type noScriptConn struct {
+	refusal redis.Error
+}
+
+func (c noScriptConn) Close() error { return nil }
+func (c noScriptConn) Err() error   { return nil }
+func (c noScriptConn) Send(commandName string, args ...interface{}) error {
+	return nil
+}
+func (c noScriptConn) Flush() error                            { return nil }
+func (c noScriptConn) Receive() (reply interface{}, err error) { return nil, nil }
+
+func (c noScriptConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	switch strings.ToUpper(commandName) {
+	case "EVAL", "EVALSHA":
+		return nil, c.refusal
+	}
+	return nil, nil
+}
+
+func TestScriptingUnavailable(t *testing.T) {
+	for _, refusal := range []redis.Error{
+		"ERR unknown command 'EVALSHA', with args beginning with: ",
+		"ERR unknown command `EVAL`, with args beginning with: ",
+		"NOPERM this user has no permissions to run the 'evalsha' command",
+		"ERR Lua scripting is disabled",
+	} {
+		conn := noScriptConn{refusal: refusal}
+		pool := &redis.Pool{Dial: func() (redis.Conn, error) { return conn, nil }}
+		a, err := NewAdapter(&Config{Pool: pool})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = a.RemoveFilteredPolicy("p", "p", 0, "alice")
+		if !errors.Is(err, ErrScriptingUnavailable) {
+			t.Errorf("RemoveFilteredPolicy() refused with %q = %v, supposed to be ErrScriptingUnavailable", refusal, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "DisableLua") {
+			t.Errorf("error %q does not suggest Config.DisableLua", err)
+		}
+	}
+
+	// Other errors are returned as they are.
+	err := scriptingError(redis.Error("ERR wrong number of arguments for 'lrange' command"))
+	if errors.Is(err, ErrScriptingUnavailable) {
+		t.Errorf("scriptingError() of an unrelated error = %v", err)
+	}
+}
+
+func TestDisableLua(t *testing.T) {
+	// The fake REST server does not know EVAL, so any script fails.
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", DisableLua: true, SingleScanRemoval: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	if err = a.UpdatePolicy("p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.UpdatePolicies("p", "p", [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}, [][]string{{"data2_admin", "data3", "read"}, {"data2_admin", "data3", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicies("p", "p", [][]string{{"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "write"}, {"data2_admin", "data3", "read"}, {"data2_admin", "data3", "write"}})
+
+	old, err := a.UpdateFilteredPolicies("p", "p", [][]string{{"carol", "data3", "read"}}, 0, "data2_admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old) != 2 {
+		t.Errorf("UpdateFilteredPolicies() replaced %v, supposed to replace both data2_admin rules", old)
+	}
+	if err = a.RemoveFilteredPolicy("p", "p", 0, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}})
+
+	if _, err = a.HealthReport(); !errors.Is(err, ErrScriptingUnavailable) {
+		t.Errorf("HealthReport() with DisableLua = %v, supposed to be ErrScriptingUnavailable", err)
+	}
+	for _, command := range server.commands {
+		if strings.HasPrefix(command, "EVAL") {
+			t.Fatalf("%s sent with DisableLua", command)
+		}
+	}
+
+	if _, err = NewAdapter(&Config{RestURL: ts.URL, DisableLua: true, SoftDelete: true}); err == nil {
+		t.Error("NewAdapter() with DisableLua and SoftDelete succeeded, supposed to fail")
+	}
+}
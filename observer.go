@@ -0,0 +1,48 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// Observer is notified of the operations of an adapter, e.g. to record metrics.
+// Its methods are called synchronously and must be safe for concurrent use.
+type Observer interface {
+	// ObserveOperation is called after every policy operation with the name of
+	// the method, e.g. "AddPolicy", how long it took and the error it returned.
+	ObserveOperation(op string, duration time.Duration, err error)
+	// ObserveRuleCount is called with the number of rules in the model after
+	// LoadPolicy and SavePolicy succeed.
+	ObserveRuleCount(count int)
+}
+
+// observe reports an operation started at start to the observer. It is deferred
+// by the policy operations with their error result.
+func (a *Adapter) observe(op string, start time.Time, err *error) {
+	if a.observer != nil {
+		a.observer.ObserveOperation(op, time.Since(start), *err)
+	}
+}
+
+// observeRules reports the number of rules in model to the observer after a
+// successful load or save.
+func (a *Adapter) observeRules(model model.Model, err *error) {
+	if a.observer != nil && *err == nil {
+		a.observer.ObserveRuleCount(countRules(model))
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// recordingObserver records the operations and rule counts it is notified of.
+type recordingObserver struct {
+	mu     sync.Mutex
+	ops    []string
+	failed []string
+	counts []int
+}
+
+func (o *recordingObserver) ObserveOperation(op string, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ops = append(o.ops, op)
+	if err != nil {
+		o.failed = append(o.failed, op)
+	}
+}
+
+func (o *recordingObserver) ObserveRuleCount(count int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.counts = append(o.counts, count)
+}
+
+func TestObserver(t *testing.T) {
+	o := &recordingObserver{}
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_observer", Observer: o})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.LoadFilteredPolicy(e.GetModel(), "not a filter"); err == nil {
+		t.Fatal("LoadFilteredPolicy() with an invalid filter succeeded")
+	}
+
+	// initPolicy saves and loads, NewEnforcer loads once more.
+	if got, want := len(o.ops), 5; got != want {
+		t.Fatalf("observed operations %v, supposed to be %d", o.ops, want)
+	}
+	for i, op := range []string{"SavePolicy", "LoadPolicy", "LoadPolicy", "AddPolicy", "LoadFilteredPolicy"} {
+		if o.ops[i] != op {
+			t.Errorf("operation %d = %s, supposed to be %s", i, o.ops[i], op)
+		}
+	}
+	if len(o.failed) != 1 || o.failed[0] != "LoadFilteredPolicy" {
+		t.Errorf("failed operations = %v, supposed to be the invalid filtered load", o.failed)
+	}
+	if len(o.counts) != 3 || o.counts[0] != 5 || o.counts[2] != 5 {
+		t.Errorf("rule counts = %v, supposed to be 5 after each load and save", o.counts)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	// ErrUnreachable is wrapped by the errors of Ping for a server that cannot
+	// be reached, e.g. one refusing connections or not answering in time.
+	ErrUnreachable = errors.New("redis unreachable")
+	// ErrAuthFailed is wrapped by the errors of Ping for a server refusing the
+	// credentials, or requiring some that were not given.
+	ErrAuthFailed = errors.New("redis authentication failed")
+)
+
+// Ping checks that the server is reachable and accepts the adapter, e.g. for a
+// readiness probe, without reading the policy: it takes a connection, dialing
+// one if needed, and sends PING. The errors wrap ErrUnreachable or
+// ErrAuthFailed when the failure is one of those.
+func (a *Adapter) Ping() error {
+	return a.PingContext(context.Background())
+}
+
+// PingContext is Ping bounded by ctx.
+func (a *Adapter) PingContext(ctx context.Context) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	conn, err := a.getConnCtx(ctx)
+	if err == nil {
+		defer a.release(conn)
+		if cwc, ok := conn.(redis.ConnWithContext); ok {
+			_, err = cwc.DoContext(ctx, "PING")
+		} else {
+			_, err = conn.Do("PING")
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("ping: %w", pingError(err))
+}
+
+// pingError wraps err in ErrUnreachable or ErrAuthFailed if it is one of those.
+func pingError(err error) error {
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		msg := string(redisErr)
+		if strings.HasPrefix(msg, "NOAUTH") || strings.HasPrefix(msg, "WRONGPASS") ||
+			strings.HasPrefix(msg, "ERR invalid password") || strings.HasPrefix(msg, "ERR AUTH") {
+			return &classifiedError{kind: ErrAuthFailed, err: err}
+		}
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, context.DeadlineExceeded) {
+		return &classifiedError{kind: ErrUnreachable, err: err}
+	}
+	return err
+}
+
+// classifiedError is an error matching its kind with errors.Is besides the
+// error itself.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.kind
+}
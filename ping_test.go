@@ -0,0 +1,82 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestPing(t *testing.T) {
+	l := listen(t)
+	defer l.Close()
+	go serveNil(l, nil)
+	a, err := NewAdapter(&Config{Network: "tcp", Address: l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if err = a.Ping(); err != nil {
+		t.Errorf("Ping() = %v", err)
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	addr := closedAddr(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", addr) }}
+	a, err := NewAdapter(&Config{Pool: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	start := time.Now()
+	err = a.Ping()
+	if !errors.Is(err, ErrUnreachable) || errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Ping() of a stopped server = %v, supposed to wrap ErrUnreachable", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Ping() of a stopped server took %v", elapsed)
+	}
+}
+
+func TestPingAuthFailed(t *testing.T) {
+	for _, refusal := range []string{
+		"-NOAUTH Authentication required.\r\n",
+		"-WRONGPASS invalid username-password pair or user is disabled.\r\n",
+	} {
+		l := listen(t)
+		defer l.Close()
+		refusal := refusal
+		go serve(l, func(args []string) string { return refusal })
+		pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", l.Addr().String()) }}
+		a, err := NewAdapter(&Config{Pool: pool})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = a.Ping()
+		if !errors.Is(err, ErrAuthFailed) || errors.Is(err, ErrUnreachable) {
+			t.Errorf("Ping() refused with %q = %v, supposed to wrap ErrAuthFailed", refusal, err)
+		}
+		var redisErr redis.Error
+		if !errors.As(err, &redisErr) {
+			t.Errorf("Ping() = %v, supposed to wrap the error reply", err)
+		}
+		a.Close()
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+// LoadFilteredPolicyFunc loads the stored rules for which pred returns true, for
+// selections a Filter cannot express, e.g. a field matching a glob while another
+// does not equal a value. Every rule is read and decoded to be passed to pred, so
+// it is slower than LoadFilteredPolicy on large policies. Like
+// LoadFilteredPolicy, it leaves the adapter filtered.
+func (a *Adapter) LoadFilteredPolicyFunc(model model.Model, pred func(CasbinRule) bool) (err error) {
+	defer a.observe("LoadFilteredPolicyFunc", time.Now(), &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	rules, err := a.readRules(context.Background())
+	if err != nil {
+		return err
+	}
+	in := a.newInterner()
+	for i := range rules {
+		if !pred(rules[i]) {
+			continue
+		}
+		in.line(&rules[i])
+		if err = loadPolicyLine(rules[i], model); err != nil {
+			return err
+		}
+	}
+	a.setFiltered(true)
+	return nil
+}
+
+// readRules returns the stored rules, including those of Config.Keys, decoded.
+func (a *Adapter) readRules(ctx context.Context) ([]CasbinRule, error) {
+	var rules []CasbinRule
+	var texts [][]byte
+	err := a.read(ctx, func(conn redis.Conn) (err error) {
+		switch {
+		case a.perPType():
+			rules, err = a.loadMembers(conn, nil)
+		case a.layout == StreamLayout:
+			rules, err = a.streamRules(conn, false)
+		default:
+			texts, err = a.loadMergedValues(conn)
+		}
+		return err
+	})
+	if err != nil || a.perPType() || a.layout == StreamLayout {
+		return rules, err
+	}
+	rules = make([]CasbinRule, len(texts))
+	for i, text := range texts {
+		if err = a.unmarshal(text, &rules[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
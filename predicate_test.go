@@ -0,0 +1,48 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"path"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestLoadFilteredPolicyFunc(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_predicate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	e.SetAdapter(a)
+	// The rules of p on data2 objects other than read.
+	err = a.LoadFilteredPolicyFunc(e.GetModel(), func(rule CasbinRule) bool {
+		matched, _ := path.Match("data2*", rule.V1)
+		return rule.PType == "p" && matched && rule.V2 != "read"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "write"}})
+	if g := e.GetGroupingPolicy(); len(g) != 0 {
+		t.Errorf("grouping policy = %v, supposed to be left out by the predicate", g)
+	}
+	if !a.IsFiltered() {
+		t.Error("IsFiltered() = false after LoadFilteredPolicyFunc(), supposed to be true")
+	}
+}
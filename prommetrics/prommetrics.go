@@ -0,0 +1,97 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prommetrics exports the operations of a Redis adapter as Prometheus
+// metrics. It is a separate module, so the adapter does not depend on Prometheus:
+//
+//	a, err := redisadapter.NewAdapter(&redisadapter.Config{
+//		Network:  "tcp",
+//		Address:  "127.0.0.1:6379",
+//		Observer: prommetrics.MustRegister(prometheus.DefaultRegisterer),
+//	})
+package prommetrics
+
+import (
+	"time"
+
+	redisadapter "github.com/casbin/redis-adapter/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is a redisadapter.Observer recording the following metrics:
+//
+//	casbin_redis_adapter_operations_total{operation}             counter
+//	casbin_redis_adapter_errors_total{operation}                 counter
+//	casbin_redis_adapter_operation_duration_seconds{operation}   histogram
+//	casbin_redis_adapter_rules                                   gauge
+type Metrics struct {
+	operations *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	rules      prometheus.Gauge
+}
+
+var _ redisadapter.Observer = (*Metrics)(nil)
+
+// New returns metrics registered with reg.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "casbin_redis_adapter_operations_total",
+			Help: "Number of policy operations of the adapter.",
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "casbin_redis_adapter_errors_total",
+			Help: "Number of policy operations of the adapter that failed.",
+		}, []string{"operation"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "casbin_redis_adapter_operation_duration_seconds",
+			Help:    "Duration of the policy operations of the adapter.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		rules: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "casbin_redis_adapter_rules",
+			Help: "Number of rules after the last load or save of the whole policy.",
+		}),
+	}
+	for _, c := range []prometheus.Collector{m.operations, m.errors, m.duration, m.rules} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// MustRegister is New that panics if the metrics cannot be registered.
+func MustRegister(reg prometheus.Registerer) *Metrics {
+	m, err := New(reg)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// ObserveOperation implements redisadapter.Observer.
+func (m *Metrics) ObserveOperation(op string, duration time.Duration, err error) {
+	m.operations.WithLabelValues(op).Inc()
+	if err != nil {
+		m.errors.WithLabelValues(op).Inc()
+	}
+	m.duration.WithLabelValues(op).Observe(duration.Seconds())
+}
+
+// ObserveRuleCount implements redisadapter.Observer.
+func (m *Metrics) ObserveRuleCount(count int) {
+	m.rules.Set(float64(count))
+}
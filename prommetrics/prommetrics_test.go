@@ -0,0 +1,93 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prommetrics
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	redisadapter "github.com/casbin/redis-adapter/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	a, err := redisadapter.NewAdapter(&redisadapter.Config{
+		Network:  "tcp",
+		Address:  "127.0.0.1:6379",
+		Key:      "casbin_rules_prometheus",
+		Observer: MustRegister(reg),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := casbin.NewEnforcer("../examples/rbac_model.conf", "../examples/rbac_policy.csv")
+	if err = a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	e.ClearPolicy()
+	if err = a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.LoadFilteredPolicy(e.GetModel(), "not a filter"); err == nil {
+		t.Fatal("LoadFilteredPolicy() with an invalid filter succeeded")
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// values maps "name{operation}" to the value of a counter or gauge, or the
+	// sample count of a histogram.
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range metric.GetLabel() {
+				name += "{" + label.GetValue() + "}"
+			}
+			switch {
+			case metric.Counter != nil:
+				values[name] = metric.GetCounter().GetValue()
+			case metric.Gauge != nil:
+				values[name] = metric.GetGauge().GetValue()
+			case metric.Histogram != nil:
+				values[name] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	for name, want := range map[string]float64{
+		"casbin_redis_adapter_operations_total{SavePolicy}":                   1,
+		"casbin_redis_adapter_operations_total{AddPolicy}":                    1,
+		"casbin_redis_adapter_operations_total{LoadPolicy}":                   1,
+		"casbin_redis_adapter_operations_total{LoadFilteredPolicy}":           1,
+		"casbin_redis_adapter_errors_total{LoadFilteredPolicy}":               1,
+		"casbin_redis_adapter_operation_duration_seconds{AddPolicy}":          1,
+		"casbin_redis_adapter_operation_duration_seconds{LoadFilteredPolicy}": 1,
+		"casbin_redis_adapter_rules":                                          6,
+	} {
+		if values[name] != want {
+			t.Errorf("%s = %v, supposed to be %v", name, values[name], want)
+		}
+	}
+	if _, ok := values["casbin_redis_adapter_errors_total{AddPolicy}"]; ok {
+		t.Error("errors were recorded for AddPolicy, which succeeded")
+	}
+}
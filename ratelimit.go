@@ -0,0 +1,58 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrWriteRateLimited is returned by mutating operations when the write rate
+// limit is exceeded and Config.WriteRateLimitNoWait is set.
+var ErrWriteRateLimited = errors.New("write rate limit exceeded")
+
+// RateLimiter limits the rate of mutating operations. *rate.Limiter from
+// golang.org/x/time/rate satisfies it.
+type RateLimiter interface {
+	// Wait blocks until an operation is allowed or ctx is done.
+	Wait(ctx context.Context) error
+	// Allow reports whether an operation may happen now.
+	Allow() bool
+}
+
+// newWriteLimiter returns the RateLimiter of Config.WriteRateLimit, allowing
+// burst operations at once.
+func newWriteLimiter(limit float64, burst int) *rate.Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(limit), burst)
+}
+
+// waitWrite applies the write rate limit before a mutating operation.
+func (a *Adapter) waitWrite(ctx context.Context) error {
+	if a.writeLimiter == nil {
+		return nil
+	}
+	if a.writeNoWait {
+		if !a.writeLimiter.Allow() {
+			return ErrWriteRateLimited
+		}
+		return nil
+	}
+	return a.writeLimiter.Wait(ctx)
+}
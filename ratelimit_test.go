@@ -0,0 +1,51 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWriteRateLimit(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_rate_limit", WriteRateLimit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	// initPolicy used up the burst, so every write waits for its token.
+	start := time.Now()
+	for i := 0; i <= 10; i++ {
+		if err := a.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data1", "read"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("11 writes at 20/s took %v, supposed to take about 550ms", elapsed)
+	}
+
+	a, err = NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_rate_limit", WriteRateLimit: 1, WriteRateLimitNoWait: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"alice", "data2", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicy("p", "p", []string{"alice", "data2", "read"}); err != ErrWriteRateLimited {
+		t.Errorf("RemovePolicy over the rate limit returned %v, supposed to be ErrWriteRateLimited", err)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+)
+
+// regexCacheSize is the number of compiled filter patterns an adapter keeps.
+const regexCacheSize = 64
+
+// regexCache is a least recently used cache of the regular expressions compiled
+// from filters, keyed by the pattern, so repeated filtered loads with the same
+// filter do not compile it again. Only Config.RawPatternMatching compiles
+// filters, the decoded rules are matched by set membership.
+type regexCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *regexCacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type regexCacheEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexCache(size int) *regexCache {
+	return &regexCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// compile returns the compiled pattern, compiling it on a miss.
+func (c *regexCache) compile(pattern string) *regexp.Regexp {
+	c.mu.Lock()
+	if e, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*regexCacheEntry).re
+	}
+	c.mu.Unlock()
+
+	// Compile without holding the lock, a concurrent miss compiles it twice at worst.
+	re := regexp.MustCompile(pattern)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*regexCacheEntry).re
+	}
+	c.entries[pattern] = c.order.PushFront(&regexCacheEntry{pattern: pattern, re: re})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*regexCacheEntry).pattern)
+	}
+	return re
+}
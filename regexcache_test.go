@@ -0,0 +1,71 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+)
+
+func TestRegexCache(t *testing.T) {
+	c := newRegexCache(2)
+	a := c.compile("a")
+	if c.compile("a") != a {
+		t.Fatal("expected the cached regexp to be reused")
+	}
+	c.compile("b")
+	c.compile("a") // a is now the most recently used
+	c.compile("c") // evicts b
+	if _, ok := c.entries["b"]; ok {
+		t.Error("expected b to be evicted")
+	}
+	if c.compile("a") != a {
+		t.Error("expected a to survive eviction")
+	}
+	if c.order.Len() != 2 {
+		t.Errorf("cache holds %d entries, want 2", c.order.Len())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.compile([]string{"a", "b", "c", "d"}[j%4])
+			}
+		}()
+	}
+	wg.Wait()
+	if c.order.Len() != 2 || len(c.entries) != 2 {
+		t.Errorf("cache holds %d entries and %d keys, want 2", c.order.Len(), len(c.entries))
+	}
+}
+
+func BenchmarkFilterRegex(b *testing.B) {
+	filter := &Filter{PType: []string{"p"}, V0: []string{"alice", "bob"}, V1: []string{"data1"}}
+	b.Run("compile", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			regexp.MustCompile(filterToRegexPattern(filter, DefaultJSONKeys))
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		c := newRegexCache(regexCacheSize)
+		for i := 0; i < b.N; i++ {
+			c.compile(filterToRegexPattern(filter, DefaultJSONKeys))
+		}
+	})
+}
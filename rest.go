@@ -0,0 +1,390 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// restConn is a redis.Conn speaking the REST API of Upstash and compatible
+// serverless Redis services, for deployments where the RESP protocol is not
+// reachable. Commands are posted as JSON arrays; pipelined commands are posted
+// together, and MULTI/EXEC blocks are posted as a single transaction.
+//
+// The REST API has no optimistic locking, so WATCH and UNWATCH are accepted but
+// do nothing. Arguments are sent as JSON strings, so binary values such as
+// GobEncoding rules are not supported. The requests are bound by the timeout of
+// the HTTP client, and by the context of DoContext and ReceiveContext, which
+// carries Config.OperationTimeout.
+type restConn struct {
+	client *http.Client
+	url    string
+	token  string
+	ctx    context.Context // of the requests, see DoContext
+
+	pending [][]interface{}
+	replies []interface{}
+	// multi holds the commands queued since MULTI, nil outside of a transaction.
+	multi [][]interface{}
+	err   error
+}
+
+// restResult is the reply to a command in the REST API.
+type restResult struct {
+	Result interface{} `json:"result"`
+	Error  *string     `json:"error"`
+}
+
+// defaultRESTTimeout is the timeout of the HTTP client over REST, unless
+// Config.HTTPClient is set.
+const defaultRESTTimeout = 30 * time.Second
+
+// restClient is the Client over REST. A restConn buffers the commands of one
+// operation, so each operation gets a connection of its own.
+type restClient struct {
+	url, token string
+	http       *http.Client
+}
+
+// overREST reports whether the adapter runs its commands over REST.
+func (a *Adapter) overREST() bool {
+	_, ok := a.client.(restClient)
+	return ok
+}
+
+func newRestClient(url, token string, client *http.Client) restClient {
+	if client == nil {
+		client = &http.Client{Timeout: defaultRESTTimeout}
+	}
+	return restClient{url: url, token: token, http: client}
+}
+
+func (c restClient) Get(context.Context) (redis.Conn, error) {
+	return newRestConn(c.http, c.url, c.token), nil
+}
+
+func (c restClient) Put(conn redis.Conn) {
+	conn.Close()
+}
+
+func (c restClient) Close() error {
+	return nil
+}
+
+func newRestConn(client *http.Client, url, token string) *restConn {
+	return &restConn{client: client, url: strings.TrimSuffix(url, "/"), token: token, ctx: context.Background()}
+}
+
+func (c *restConn) Close() error {
+	c.err = errors.New("redisadapter: REST connection closed")
+	return nil
+}
+
+func (c *restConn) Err() error {
+	return c.err
+}
+
+func (c *restConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if commandName != "" {
+		if err := c.Send(commandName, args...); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	// Like redigo, return the last reply along with the first error reply.
+	var reply interface{}
+	var err error
+	for len(c.replies) > 0 {
+		reply = c.replies[0]
+		c.replies = c.replies[1:]
+		if e, ok := reply.(redis.Error); ok && err == nil {
+			err = e
+		}
+	}
+	return reply, err
+}
+
+// DoContext is Do with the requests bound by ctx.
+func (c *restConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	defer c.withContext(ctx)()
+	return c.Do(commandName, args...)
+}
+
+// ReceiveContext is Receive with the requests bound by ctx.
+func (c *restConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	defer c.withContext(ctx)()
+	return c.Receive()
+}
+
+// withContext binds the requests to ctx until the returned function is called.
+func (c *restConn) withContext(ctx context.Context) func() {
+	previous := c.ctx
+	c.ctx = ctx
+	return func() { c.ctx = previous }
+}
+
+func (c *restConn) Send(commandName string, args ...interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	command := make([]interface{}, 0, len(args)+1)
+	command = append(command, commandName)
+	c.pending = append(c.pending, append(command, args...))
+	return nil
+}
+
+func (c *restConn) Flush() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	var batch []restCommand
+	for _, command := range c.pending {
+		name := strings.ToUpper(fmt.Sprint(command[0]))
+		switch {
+		case name == "WATCH" || name == "UNWATCH":
+			batch = append(batch, restCommand{reply: "OK"})
+		case name == "MULTI" && c.multi == nil:
+			c.multi = [][]interface{}{}
+			batch = append(batch, restCommand{reply: "OK"})
+		case name == "DISCARD" && c.multi != nil:
+			c.multi = nil
+			batch = append(batch, restCommand{reply: "OK"})
+		case name == "EXEC" && c.multi != nil:
+			// The commands before the transaction run first.
+			if err := c.post(batch); err != nil {
+				return err
+			}
+			batch = nil
+			if err := c.exec(); err != nil {
+				return err
+			}
+		case c.multi != nil:
+			c.multi = append(c.multi, command)
+			batch = append(batch, restCommand{reply: "QUEUED"})
+		default:
+			batch = append(batch, restCommand{args: command})
+		}
+	}
+	c.pending = nil
+	return c.post(batch)
+}
+
+func (c *restConn) Receive() (interface{}, error) {
+	if len(c.replies) == 0 {
+		if err := c.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.replies) == 0 {
+		return nil, errors.New("redisadapter: no pending reply")
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	if e, ok := reply.(redis.Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// restCommand is a command of a pipeline. Commands without args are replied
+// locally with reply.
+type restCommand struct {
+	args  []interface{}
+	reply interface{}
+}
+
+// post runs the commands of batch in a pipeline and queues their replies.
+func (c *restConn) post(batch []restCommand) error {
+	var commands [][]interface{}
+	for _, command := range batch {
+		if command.args != nil {
+			commands = append(commands, command.args)
+		}
+	}
+	var results []restResult
+	if len(commands) > 0 {
+		if err := c.request("/pipeline", commands, &results); err != nil {
+			return err
+		}
+		if len(results) != len(commands) {
+			return c.fatal(fmt.Errorf("redisadapter: %d REST results for %d commands", len(results), len(commands)))
+		}
+	}
+
+	for _, command := range batch {
+		if command.args == nil {
+			c.replies = append(c.replies, command.reply)
+			continue
+		}
+		c.replies = append(c.replies, results[0].reply())
+		results = results[1:]
+	}
+	return nil
+}
+
+// exec runs the queued transaction and queues the reply of EXEC.
+func (c *restConn) exec() error {
+	commands := c.multi
+	c.multi = nil
+	if len(commands) == 0 {
+		c.replies = append(c.replies, []interface{}{})
+		return nil
+	}
+
+	var results []restResult
+	if err := c.request("/multi-exec", commands, &results); err != nil {
+		return err
+	}
+	replies := make([]interface{}, len(results))
+	for i, result := range results {
+		replies[i] = result.reply()
+	}
+	c.replies = append(c.replies, replies)
+	return nil
+}
+
+// request posts the commands and decodes the response into results.
+func (c *restConn) request(path string, commands [][]interface{}, results *[]restResult) error {
+	body := make([][]string, len(commands))
+	for i, command := range commands {
+		body[i] = make([]string, len(command))
+		for j, arg := range command {
+			body[i][j] = restArg(arg)
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.url+path, bytes.NewReader(data))
+	if err != nil {
+		return c.fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return c.fatal(err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if resp.StatusCode != http.StatusOK {
+		var result restResult
+		if err = decoder.Decode(&result); err == nil && result.Error != nil {
+			return c.fatal(fmt.Errorf("redisadapter: REST request failed: %s: %s", resp.Status, *result.Error))
+		}
+		return c.fatal(fmt.Errorf("redisadapter: REST request failed: %s", resp.Status))
+	}
+	if err = decoder.Decode(results); err != nil {
+		return c.fatal(err)
+	}
+	return nil
+}
+
+// fatal makes the connection unusable, like redigo does on I/O errors.
+func (c *restConn) fatal(err error) error {
+	if c.err == nil {
+		c.err = err
+	}
+	return err
+}
+
+// reply converts the result to the reply types of redigo.
+func (r restResult) reply() interface{} {
+	if r.Error != nil {
+		return redis.Error(*r.Error)
+	}
+	return restReply(r.Result)
+}
+
+func restReply(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return []byte(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		return []byte(v.String())
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = restReply(item)
+		}
+		return values
+	}
+	return value
+}
+
+// restArg converts a command argument to a string, like redigo does when
+// writing it to the connection.
+func restArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case nil:
+		return ""
+	case redis.Argument:
+		return restArg(v.RedisArg())
+	}
+	return fmt.Sprint(arg)
+}
+
+// validateRest checks the options the REST proxy does not support.
+func (c *Config) validateRest(v *configCheck) {
+	if c.RestURL == "" {
+		if c.HTTPClient != nil {
+			v.report(ErrIgnoredOption, "HTTPClient is ignored without RestURL")
+		}
+		return
+	}
+	v.exclude("RestURL", configOption{"GobEncoding", c.Encoding == GobEncoding},
+		configOption{"RepairVersionOnStart", c.RepairVersionOnStart})
+}
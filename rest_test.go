@@ -0,0 +1,346 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// fakeRestServer implements the REST API for the list, hash, set and key commands
+// used by the adapter outside of Lua scripts. Expiries are ignored.
+type fakeRestServer struct {
+	mu       sync.Mutex
+	lists    map[string][]string
+	strings  map[string]string
+	hashes   map[string]map[string]string // created on first use
+	sets     map[string]map[string]bool   // created on first use
+	commands []string
+}
+
+func (s *fakeRestServer) run(command []string) map[string]interface{} {
+	s.commands = append(s.commands, strings.ToUpper(command[0]))
+	args := command[1:]
+	switch strings.ToUpper(command[0]) {
+	case "LLEN":
+		return map[string]interface{}{"result": len(s.lists[args[0]])}
+	case "LRANGE":
+		list := s.lists[args[0]]
+		start, _ := strconv.Atoi(args[1])
+		stop, _ := strconv.Atoi(args[2])
+		if stop < 0 || stop >= len(list) {
+			stop = len(list) - 1
+		}
+		if start > stop {
+			return map[string]interface{}{"result": []string{}}
+		}
+		return map[string]interface{}{"result": list[start : stop+1]}
+	case "RPUSH":
+		s.lists[args[0]] = append(s.lists[args[0]], args[1:]...)
+		return map[string]interface{}{"result": len(s.lists[args[0]])}
+	case "LREM":
+		list := s.lists[args[0]]
+		for i, value := range list {
+			if value == args[2] {
+				s.lists[args[0]] = append(list[:i:i], list[i+1:]...)
+				return map[string]interface{}{"result": 1}
+			}
+		}
+		return map[string]interface{}{"result": 0}
+	case "HSET":
+		if s.hashes == nil {
+			s.hashes = map[string]map[string]string{}
+		}
+		hash := s.hashes[args[0]]
+		if hash == nil {
+			hash = map[string]string{}
+			s.hashes[args[0]] = hash
+		}
+		added := 0
+		for i := 1; i+1 < len(args); i += 2 {
+			if _, ok := hash[args[i]]; !ok {
+				added++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		return map[string]interface{}{"result": added}
+	case "HDEL":
+		hash := s.hashes[args[0]]
+		removed := 0
+		for _, field := range args[1:] {
+			if _, ok := hash[field]; ok {
+				delete(hash, field)
+				removed++
+			}
+		}
+		if len(hash) == 0 {
+			delete(s.hashes, args[0])
+		}
+		return map[string]interface{}{"result": removed}
+	case "HVALS":
+		values := []string{}
+		for _, value := range s.hashes[args[0]] {
+			values = append(values, value)
+		}
+		return map[string]interface{}{"result": values}
+	case "SADD":
+		if s.sets == nil {
+			s.sets = map[string]map[string]bool{}
+		}
+		set := s.sets[args[0]]
+		if set == nil {
+			set = map[string]bool{}
+			s.sets[args[0]] = set
+		}
+		added := 0
+		for _, member := range args[1:] {
+			if !set[member] {
+				set[member] = true
+				added++
+			}
+		}
+		return map[string]interface{}{"result": added}
+	case "SMEMBERS":
+		members := []string{}
+		for member := range s.sets[args[0]] {
+			members = append(members, member)
+		}
+		return map[string]interface{}{"result": members}
+	case "DEL":
+		for _, key := range args {
+			delete(s.lists, key)
+			delete(s.strings, key)
+			delete(s.hashes, key)
+			delete(s.sets, key)
+		}
+		return map[string]interface{}{"result": len(args)}
+	case "GET":
+		if value, ok := s.strings[args[0]]; ok {
+			return map[string]interface{}{"result": value}
+		}
+		return map[string]interface{}{"result": nil}
+	case "RENAME":
+		if list, ok := s.lists[args[0]]; ok {
+			delete(s.lists, args[0])
+			s.lists[args[1]] = list
+			return map[string]interface{}{"result": "OK"}
+		}
+		if hash, ok := s.hashes[args[0]]; ok {
+			delete(s.hashes, args[0])
+			s.hashes[args[1]] = hash
+			return map[string]interface{}{"result": "OK"}
+		}
+		return map[string]interface{}{"error": "ERR no such key"}
+	case "PEXPIRE", "PERSIST":
+		_, ok := s.lists[args[0]]
+		if !ok {
+			_, ok = s.strings[args[0]]
+		}
+		if !ok {
+			_, ok = s.hashes[args[0]]
+		}
+		if ok {
+			return map[string]interface{}{"result": 1}
+		}
+		return map[string]interface{}{"result": 0}
+	case "INCR":
+		n, _ := strconv.Atoi(s.strings[args[0]])
+		s.strings[args[0]] = strconv.Itoa(n + 1)
+		return map[string]interface{}{"result": n + 1}
+	}
+	return map[string]interface{}{"error": "ERR unknown command '" + command[0] + "'"}
+}
+
+func (s *fakeRestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+	var commands [][]string
+	if err := json.NewDecoder(r.Body).Decode(&commands); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/multi-exec" {
+		s.commands = append(s.commands, "MULTI")
+	}
+	results := make([]map[string]interface{}, len(commands))
+	for i, command := range commands {
+		results[i] = s.run(command)
+	}
+	if r.URL.Path == "/multi-exec" {
+		s.commands = append(s.commands, "EXEC")
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+func TestRestConn(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+	if commands := strings.Join(server.commands, " "); !strings.Contains(commands, "MULTI DEL RENAME PERSIST") || !strings.Contains(commands, "EXEC") {
+		t.Errorf("commands = %s, supposed to rename the saved policy over the key inside MULTI/EXEC", commands)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if _, err = e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+	if v, err := a.Version(); err != nil || v == 0 {
+		t.Errorf("Version() = %d, %v, supposed to be bumped", v, err)
+	}
+
+	unauthorized, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = unauthorized.LoadPolicy(e.GetModel()); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("LoadPolicy() with a wrong token = %v, supposed to fail as unauthorized", err)
+	}
+
+	// Without WATCH, the compare-and-save could not be atomic.
+	if err = a.SavePolicyIfVersion(e.GetModel(), 0); !errors.Is(err, ErrWatchUnavailable) {
+		t.Errorf("SavePolicyIfVersion() over REST = %v, supposed to fail with ErrWatchUnavailable", err)
+	}
+	if err = a.RepairVersion(); !errors.Is(err, ErrWatchUnavailable) {
+		t.Errorf("RepairVersion() over REST = %v, supposed to fail with ErrWatchUnavailable", err)
+	}
+
+	if _, err = NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", Encoding: GobEncoding}); err == nil {
+		t.Error("NewAdapter() with REST and gob encoding succeeded, supposed to fail")
+	}
+}
+
+// Run with -race.
+func TestRestConcurrentOperations(t *testing.T) {
+	server := httptest.NewServer(&fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}})
+	defer server.Close()
+	a, err := NewAdapter(&Config{RestURL: server.URL, RestToken: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m, err := model.NewModelFromFile("examples/rbac_model.conf")
+			if err != nil {
+				errs <- err
+				return
+			}
+			switch i % 3 {
+			case 0:
+				err = a.AddPolicy("p", "p", []string{"user" + strconv.Itoa(i), "data", "read"})
+			case 1:
+				err = a.LoadPolicy(m)
+			default:
+				err = a.LoadFilteredPolicy(m, &Filter{V0: []string{"user0"}})
+				_ = a.IsFiltered()
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.LoadPolicy(m); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(m.GetPolicy("p", "p")); n != 34 {
+		t.Errorf("loaded %d rules, supposed to load the 34 added concurrently", n)
+	}
+}
+
+func TestRestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer stalled.Close()
+	defer close(release)
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OperationTimeout bounds the requests of an operation.
+	a, err := NewAdapter(&Config{RestURL: stalled.URL, RestToken: "secret", OperationTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err = a.LoadPolicy(m); !errors.Is(err, ErrOperationTimeout) {
+		t.Errorf("LoadPolicy() of a stalled endpoint = %v, supposed to fail with ErrOperationTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("LoadPolicy() of a stalled endpoint returned after %v, supposed to time out after 50ms", elapsed)
+	}
+
+	// So does the timeout of HTTPClient.
+	a, err = NewAdapter(&Config{RestURL: stalled.URL, RestToken: "secret", HTTPClient: &http.Client{Timeout: 50 * time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start = time.Now()
+	if err = a.SavePolicy(m); err == nil {
+		t.Error("SavePolicy() of a stalled endpoint succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SavePolicy() of a stalled endpoint returned after %v, supposed to time out after 50ms", elapsed)
+	}
+}
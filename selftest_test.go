@@ -0,0 +1,81 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSelfTest(t *testing.T) {
+	configs := []*Config{
+		{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_self_test"},
+		{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_self_test", SoftDelete: true},
+		{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_self_test", Encoding: GobEncoding},
+	}
+
+	for _, config := range configs {
+		a, err := NewAdapter(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		initPolicy(t, a)
+
+		if err = a.SelfTest(context.Background()); err != nil {
+			t.Errorf("SelfTest failed with %+v: %v", config, err)
+		}
+
+		// The policy is untouched and no scratch key is left behind.
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := redis.Int(conn.Do("LLEN", a.key))
+		if err != nil || n != 5 {
+			t.Errorf("policy has %d rules after SelfTest, supposed to be 5 (err: %v)", n, err)
+		}
+		keys, err := redis.Strings(conn.Do("KEYS", a.key+":selftest:*"))
+		if err != nil || len(keys) != 0 {
+			t.Errorf("SelfTest left keys %q behind (err: %v)", keys, err)
+		}
+		a.release(conn)
+	}
+}
+
+func TestSelfTestBrokenConfig(t *testing.T) {
+	a, err := NewAdapter(&Config{Pool: &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", "127.0.0.1:1")
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.SelfTest(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "self test: add:") {
+		t.Errorf("SelfTest against an unreachable server returned %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = a.SelfTest(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("SelfTest with a canceled context returned %v", err)
+	}
+}
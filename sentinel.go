@@ -0,0 +1,155 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// errStaleMaster is the error of the connections to a master that was demoted
+// to a replica, so that the pool drops them.
+var errStaleMaster = errors.New("redis-adapter: master demoted to replica")
+
+// sentinel resolves the address of the master of Config.SentinelMasterName
+// through the sentinels of Config.SentinelAddrs.
+type sentinel struct {
+	masterName string
+	password   string
+
+	mu    sync.Mutex
+	addrs []string // the last sentinel that answered first
+}
+
+func newSentinel(addrs []string, masterName, password string) *sentinel {
+	return &sentinel{masterName: masterName, password: password, addrs: append([]string(nil), addrs...)}
+}
+
+// master asks the sentinels in turn for the address of the master, dialing
+// them with options, and returns the first answer.
+func (s *sentinel) master(network string, options []redis.DialOption) (string, error) {
+	if s.password != "" {
+		options = append(options[:len(options):len(options)], redis.DialPassword(s.password))
+	}
+	s.mu.Lock()
+	addrs := append([]string(nil), s.addrs...)
+	s.mu.Unlock()
+
+	var err error
+	for i, addr := range addrs {
+		var master string
+		if master, err = s.ask(network, addr, options); err != nil {
+			continue
+		}
+		if i > 0 {
+			// Ask the sentinel that answered first next time.
+			s.mu.Lock()
+			for j, a := range s.addrs {
+				if a == addr {
+					copy(s.addrs[1:j+1], s.addrs[:j])
+					s.addrs[0] = addr
+					break
+				}
+			}
+			s.mu.Unlock()
+		}
+		return master, nil
+	}
+	return "", fmt.Errorf("resolving master %q through sentinel: %w", s.masterName, err)
+}
+
+// ask asks the sentinel at addr for the address of the master.
+func (s *sentinel) ask(network, addr string, options []redis.DialOption) (string, error) {
+	conn, err := redis.Dial(network, addr, options...)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	reply, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.masterName))
+	if err == redis.ErrNil {
+		return "", fmt.Errorf("sentinel %s does not monitor %q", addr, s.masterName)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("sentinel %s answered %q for the address of %q", addr, reply, s.masterName)
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+// masterConn is a connection to the master resolved through sentinel. Once
+// the server answers READONLY, it was demoted by a failover and the connection
+// reports errStaleMaster, so the pool drops it and dials the new master.
+type masterConn struct {
+	redis.Conn
+	stale bool
+}
+
+func (c *masterConn) Err() error {
+	if c.stale {
+		return errStaleMaster
+	}
+	return c.Conn.Err()
+}
+
+func (c *masterConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := c.Conn.Do(commandName, args...)
+	c.check(err)
+	return reply, err
+}
+
+func (c *masterConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	c.check(err)
+	return reply, err
+}
+
+func (c *masterConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+	c.check(err)
+	return reply, err
+}
+
+func (c *masterConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	reply, err := redis.ReceiveWithTimeout(c.Conn, timeout)
+	c.check(err)
+	return reply, err
+}
+
+func (c *masterConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := redis.DoContext(c.Conn, ctx, commandName, args...)
+	c.check(err)
+	return reply, err
+}
+
+func (c *masterConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	reply, err := redis.ReceiveContext(c.Conn, ctx)
+	c.check(err)
+	return reply, err
+}
+
+func (c *masterConn) check(err error) {
+	if e, ok := err.(redis.Error); ok && strings.HasPrefix(string(e), "READONLY") {
+		c.stale = true
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeMaster is a server answering the commands of AddPolicy, or READONLY to
+// them once demoted to a replica.
+type fakeMaster struct {
+	mu      sync.Mutex
+	replica bool
+	pushes  int
+}
+
+func (m *fakeMaster) reply(args []string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch args[0] {
+	case "RPUSH", "INCR":
+		if m.replica {
+			return "-READONLY You can't write against a read only replica.\r\n"
+		}
+		if args[0] == "RPUSH" {
+			m.pushes++
+		}
+		return ":1\r\n"
+	}
+	return "$-1\r\n"
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestSentinelFailover(t *testing.T) {
+	var masters [2]*fakeMaster
+	var addrs [2]string
+	for i := range masters {
+		l := listen(t)
+		defer l.Close()
+		masters[i] = &fakeMaster{}
+		addrs[i] = l.Addr().String()
+		go serve(l, masters[i].reply)
+	}
+
+	var mu sync.Mutex
+	current := addrs[0]
+	l := listen(t)
+	defer l.Close()
+	go serve(l, func(args []string) string {
+		if len(args) != 3 || args[0] != "SENTINEL" || args[1] != "get-master-addr-by-name" || args[2] != "mymaster" {
+			return "$-1\r\n"
+		}
+		mu.Lock()
+		host, port, _ := net.SplitHostPort(current)
+		mu.Unlock()
+		return fmt.Sprintf("*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(host), host, len(port), port)
+	})
+
+	a, err := NewAdapter(&Config{
+		Network: "tcp",
+		// The first sentinel is down, the adapter asks the next one.
+		SentinelAddrs:      []string{closedAddr(t), l.Addr().String()},
+		SentinelMasterName: "mymaster",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Failover: the master is demoted and the sentinel advertises the replica.
+	masters[0].mu.Lock()
+	masters[0].replica = true
+	masters[0].mu.Unlock()
+	mu.Lock()
+	current = addrs[1]
+	mu.Unlock()
+
+	// The write on the connection to the former master fails and drops it.
+	err = a.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	if err == nil || !strings.HasPrefix(err.Error(), "READONLY") {
+		t.Fatalf("AddPolicy() on the former master = %v, supposed to fail with READONLY", err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("AddPolicy() after the failover = %v", err)
+	}
+	if masters[0].pushes != 1 || masters[1].pushes != 1 {
+		t.Errorf("pushes to the masters = %d and %d, supposed to be 1 and 1", masters[0].pushes, masters[1].pushes)
+	}
+}
+
+func TestSentinelUnknownMaster(t *testing.T) {
+	l := listen(t)
+	defer l.Close()
+	go serveNil(l, nil)
+
+	_, err := NewAdapter(&Config{Network: "tcp", SentinelAddrs: []string{l.Addr().String()}, SentinelMasterName: "mymaster"})
+	if err == nil || !strings.Contains(err.Error(), `does not monitor "mymaster"`) {
+		t.Errorf("NewAdapter() = %v, supposed to report the unknown master", err)
+	}
+}
+
+// closedAddr returns an address nothing listens on.
+func closedAddr(t *testing.T) string {
+	l := listen(t)
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
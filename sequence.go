@@ -0,0 +1,256 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// With Config.TrackCreationOrder, every stored rule is recorded in a sorted set
+// ("<key>:seq") scored by a sequence number drawn from a counter ("<key>:seq:next")
+// when the rule is added. An updated rule inherits the number of the rule it
+// replaces, so GetAllPolicies returns the rules in creation order whatever their
+// position in the policy list.
+
+// seqKey returns the key of the sorted set recording the sequence number of each rule.
+func (a *Adapter) seqKey() string {
+	return a.key + ":seq"
+}
+
+// seqCounterKey returns the key of the counter drawing sequence numbers.
+func (a *Adapter) seqCounterKey() string {
+	return a.key + ":seq:next"
+}
+
+// nextSeqs draws n consecutive sequence numbers and returns the first one.
+func (a *Adapter) nextSeqs(conn redis.Conn, n int) (int64, error) {
+	last, err := redis.Int64(conn.Do("INCRBY", a.seqCounterKey(), n))
+	if err != nil {
+		return 0, err
+	}
+	return last - int64(n) + 1, nil
+}
+
+// recordCreated records texts as created now. Rules already recorded keep their number.
+func (a *Adapter) recordCreated(conn redis.Conn, texts [][]byte) error {
+	if !a.trackOrder || len(texts) == 0 {
+		return nil
+	}
+	seq, err := a.nextSeqs(conn, len(texts))
+	if err != nil {
+		return err
+	}
+	args := redis.Args{}.Add(a.seqKey(), "NX")
+	for i, text := range texts {
+		args = args.Add(seq+int64(i), text)
+	}
+	_, err = conn.Do("ZADD", args...)
+	return err
+}
+
+// forgetCreated removes the records of texts.
+func (a *Adapter) forgetCreated(conn redis.Conn, texts [][]byte) error {
+	if !a.trackOrder || len(texts) == 0 {
+		return nil
+	}
+	_, err := conn.Do("ZREM", redis.Args{}.Add(a.seqKey()).AddFlat(texts)...)
+	return err
+}
+
+// carryCreated moves the record of each old text to the new text at the same
+// index, so updated rules keep their place in the creation order. Old texts left
+// over are forgotten and new texts left over are recorded as created now.
+func (a *Adapter) carryCreated(conn redis.Conn, oldTexts, newTexts [][]byte) error {
+	if !a.trackOrder {
+		return nil
+	}
+	n := len(oldTexts)
+	if len(newTexts) < n {
+		n = len(newTexts)
+	}
+	for i := 0; i < n; i++ {
+		if err := conn.Send("ZSCORE", a.seqKey(), oldTexts[i]); err != nil {
+			return err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	args := redis.Args{}.Add(a.seqKey())
+	var fresh [][]byte
+	for i := 0; i < n; i++ {
+		seq, err := redis.Int64(conn.Receive())
+		if err == redis.ErrNil {
+			fresh = append(fresh, newTexts[i])
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		args = args.Add(seq, newTexts[i])
+	}
+
+	if err := a.forgetCreated(conn, oldTexts); err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		if _, err := conn.Do("ZADD", args...); err != nil {
+			return err
+		}
+	}
+	return a.recordCreated(conn, append(fresh, newTexts[n:]...))
+}
+
+// syncCreated records the rules of the policy list after it was replaced as a
+// whole. Rules that were already stored keep their number, the others are
+// recorded as created now in list order, and records of rules no longer stored
+// are dropped.
+func (a *Adapter) syncCreated(conn redis.Conn) error {
+	if !a.trackOrder {
+		return nil
+	}
+	texts, err := a.loadValues(conn)
+	if err != nil {
+		return err
+	}
+	seqs, err := a.loadSeqs(conn)
+	if err != nil {
+		return err
+	}
+
+	stored := make(map[string]struct{}, len(texts))
+	var fresh [][]byte
+	for _, text := range texts {
+		if _, ok := stored[string(text)]; ok {
+			continue
+		}
+		stored[string(text)] = struct{}{}
+		if _, ok := seqs[string(text)]; !ok {
+			fresh = append(fresh, text)
+		}
+	}
+	var gone []string
+	for text := range seqs {
+		if _, ok := stored[text]; !ok {
+			gone = append(gone, text)
+		}
+	}
+	if len(gone) > 0 {
+		if _, err = conn.Do("ZREM", redis.Args{}.Add(a.seqKey()).AddFlat(gone)...); err != nil {
+			return err
+		}
+	}
+	return a.recordCreated(conn, fresh)
+}
+
+// loadSeqs returns the sequence number of each recorded rule.
+func (a *Adapter) loadSeqs(conn redis.Conn) (map[string]int64, error) {
+	values, err := redis.Values(conn.Do("ZRANGE", a.seqKey(), 0, -1, "WITHSCORES"))
+	if err != nil {
+		return nil, err
+	}
+	seqs := make(map[string]int64, len(values)/2)
+	for len(values) > 0 {
+		var text string
+		var seq int64
+		if values, err = redis.Scan(values, &text, &seq); err != nil {
+			return nil, err
+		}
+		seqs[text] = seq
+	}
+	return seqs, nil
+}
+
+// GetAllPolicies returns the stored rules, each starting with its ptype. With
+// Config.TrackCreationOrder they are sorted in creation order, rules without a
+// record, e.g. written before the option was enabled, coming last in list order.
+// Otherwise they are in list order, in the order StreamLayout replays them, or
+// in score order with ZSetLayout.
+// It is not supported by PTypeSetLayout and HashLayout.
+func (a *Adapter) GetAllPolicies() ([][]string, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	if a.layout == PTypeSetLayout || a.layout == HashLayout {
+		return nil, errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return nil, err
+	}
+	defer a.release(conn)
+
+	if a.layout == StreamLayout {
+		lines, err := a.streamRules(conn, false)
+		if err != nil {
+			return nil, err
+		}
+		rules := make([][]string, 0, len(lines))
+		for _, line := range lines {
+			rules = append(rules, line.toStringPolicy())
+		}
+		return rules, nil
+	}
+
+	texts, err := a.loadValues(conn)
+	if err != nil {
+		return nil, err
+	}
+	if a.trackOrder {
+		seqs, err := a.loadSeqs(conn)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(texts, func(i, j int) bool {
+			si, iok := seqs[string(texts[i])]
+			sj, jok := seqs[string(texts[j])]
+			if iok != jok {
+				return iok
+			}
+			return si < sj
+		})
+	}
+
+	rules := make([][]string, 0, len(texts))
+	var line CasbinRule
+	for _, text := range texts {
+		if err = a.unmarshal(text, &line); err != nil {
+			return nil, err
+		}
+		rules = append(rules, line.toStringPolicy())
+	}
+	return rules, nil
+}
+
+func stringsToBytes(texts []string) [][]byte {
+	b := make([][]byte, len(texts))
+	for i, text := range texts {
+		b[i] = []byte(text)
+	}
+	return b
+}
+
+// validateCreationOrder checks the options TrackCreationOrder cannot be combined with.
+func (c *Config) validateCreationOrder(v *configCheck) {
+	if !c.TrackCreationOrder {
+		return
+	}
+	v.exclude("TrackCreationOrder", configOption{"SoftDelete", c.SoftDelete})
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrackCreationOrder(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_seq", TrackCreationOrder: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	initPolicy(t, a)
+
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.UpdatePolicy("p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.UpdateFilteredPolicies("p", "p", [][]string{{"bob", "data3", "write"}}, 0, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicy("p", "p", []string{"data2_admin", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"dave", "data4", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the list, moving the two newest rules to its head.
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = conn.Do("RPOPLPUSH", a.key, a.key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.release(conn)
+
+	rules, err := a.GetAllPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"p", "alice", "data1", "write"},
+		{"p", "bob", "data3", "write"},
+		{"p", "data2_admin", "data2", "read"},
+		{"g", "alice", "data2_admin"},
+		{"p", "carol", "data3", "read"},
+		{"p", "dave", "data4", "read"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("GetAllPolicies() = %v, supposed to be in creation order %v", rules, want)
+	}
+
+	if _, err = NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", TrackCreationOrder: true, SoftDelete: true}); err == nil {
+		t.Error("NewAdapter() with TrackCreationOrder and SoftDelete succeeded, supposed to fail")
+	}
+}
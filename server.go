@@ -0,0 +1,114 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrUnsupportedServer is returned by NewAdapter with CheckServerVersion when
+// the server is too old for a configured feature.
+var ErrUnsupportedServer = errors.New("feature is not supported by the Redis server")
+
+// serverFeatures are the features of the adapter that need more than the
+// oldest Redis versions, with the first version supporting them.
+var serverFeatures = []struct {
+	name       string
+	minVersion string
+	used       func(config *Config) bool
+}{
+	{"Lua scripting", "2.6.0", func(config *Config) bool { return !config.DisableLua }},
+	{"TrackCreationOrder", "3.0.2", func(config *Config) bool { return config.TrackCreationOrder }},
+	{"AuditStream", "5.0.0", func(config *Config) bool { return config.AuditStream != "" }},
+	{"StreamLayout", "5.0.0", func(config *Config) bool { return config.Layout == StreamLayout }},
+	{"Username", "6.0.0", func(config *Config) bool { return config.Username != "" }},
+}
+
+// checkServerFeatures returns an error for the first feature configured in
+// config that the server of the given version does not support.
+func checkServerFeatures(config *Config, version string) error {
+	for _, f := range serverFeatures {
+		if f.used(config) && compareVersions(version, f.minVersion) < 0 {
+			return fmt.Errorf("%w: %s needs Redis %s, the server runs %s", ErrUnsupportedServer, f.name, f.minVersion, version)
+		}
+	}
+	return nil
+}
+
+// compareVersions compares dotted versions such as "7.2.4" numerically,
+// returning -1, 0 or 1. Missing or malformed parts count as 0.
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// serverVersion reads the version of the server from INFO server.
+func serverVersion(conn redis.Conn) (string, error) {
+	info, err := redis.String(conn.Do("INFO", "server"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if strings.HasPrefix(line, "redis_version:") {
+			return strings.TrimSpace(line[len("redis_version:"):]), nil
+		}
+	}
+	return "", errors.New("INFO server does not report redis_version")
+}
+
+// ServerVersion returns the version of the Redis server, e.g. "7.2.4".
+func (a *Adapter) ServerVersion() (string, error) {
+	if err := a.begin(); err != nil {
+		return "", err
+	}
+	defer a.end()
+
+	conn, err := a.getConn()
+	if err != nil {
+		return "", err
+	}
+	defer a.release(conn)
+
+	return serverVersion(conn)
+}
+
+// checkServer fails if the server is too old for the features of config.
+func (a *Adapter) checkServer(config *Config) error {
+	version, err := a.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("server version: %w", err)
+	}
+	return checkServerFeatures(config, version)
+}
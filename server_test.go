@@ -0,0 +1,61 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckServerFeatures(t *testing.T) {
+	tests := []struct {
+		config  Config
+		version string
+		ok      bool
+	}{
+		{Config{}, "7.2.4", true},
+		{Config{}, "2.4.18", false},
+		{Config{DisableLua: true}, "2.4.18", true},
+		{Config{AuditStream: "audit"}, "5.0.0", true},
+		{Config{AuditStream: "audit"}, "4.0.14", false},
+		{Config{Username: "casbin"}, "6.2.1", true},
+		{Config{Username: "casbin"}, "5.0.14", false},
+		{Config{TrackCreationOrder: true}, "3.0.10", true},
+		{Config{TrackCreationOrder: true}, "3.0.1", false},
+	}
+	for _, test := range tests {
+		err := checkServerFeatures(&test.config, test.version)
+		if test.ok && err != nil {
+			t.Errorf("checkServerFeatures(%+v, %s) = %v, supposed to succeed", test.config, test.version, err)
+		}
+		if !test.ok && !errors.Is(err, ErrUnsupportedServer) {
+			t.Errorf("checkServerFeatures(%+v, %s) = %v, supposed to be ErrUnsupportedServer", test.config, test.version, err)
+		}
+	}
+}
+
+func TestServerVersion(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", CheckServerVersion: true})
+	if err != nil {
+		t.Skipf("no Redis server supporting the default features: %v", err)
+	}
+	version, err := a.ServerVersion()
+	if err != nil {
+		t.Fatalf("ServerVersion() = %v", err)
+	}
+	if compareVersions(version, "2.6.0") < 0 {
+		t.Errorf("ServerVersion() = %q, supposed to be a version of at least 2.6.0", version)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The snappy block format, as written by github.com/golang/snappy Encode: the
+// uvarint length of the data, then literals and copies of earlier data, each
+// starting with a tag whose low two bits give the kind of element.
+const (
+	snappyLiteral = 0
+	snappyCopy1   = 1
+	snappyCopy2   = 2
+	snappyCopy4   = 3
+)
+
+var errCorruptSnappy = errors.New("corrupt snappy data")
+
+// snappyEncode compresses src. Matches are looked up in a table of the last
+// position of every hashed 4 bytes, which is enough for the short values of
+// rules and keeps the output deterministic.
+func snappyEncode(src []byte) []byte {
+	dst := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(src)+len(src)/6+8)
+	dst = dst[:binary.PutUvarint(dst, uint64(len(src)))]
+
+	var table [1 << 12]int32 // position + 1 of the last 4 bytes with the hash
+	lit := 0
+	for i := 0; i+4 <= len(src); {
+		cur := binary.LittleEndian.Uint32(src[i:])
+		h := cur * 0x1e35a7bd >> 20
+		cand := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if cand < 0 || i-cand > 0xffff || binary.LittleEndian.Uint32(src[cand:]) != cur {
+			i++
+			continue
+		}
+		n := 4
+		for i+n < len(src) && src[cand+n] == src[i+n] {
+			n++
+		}
+		dst = appendSnappyLiteral(dst, src[lit:i])
+		dst = appendSnappyCopy(dst, i-cand, n)
+		i += n
+		lit = i
+	}
+	return appendSnappyLiteral(dst, src[lit:])
+}
+
+// appendSnappyLiteral appends the literal element of lit, if any.
+func appendSnappyLiteral(dst, lit []byte) []byte {
+	if len(lit) == 0 {
+		return dst
+	}
+	n := uint32(len(lit) - 1)
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2|snappyLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|snappyLiteral, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2|snappyLiteral, byte(n), byte(n>>8))
+	case n < 1<<24:
+		dst = append(dst, 62<<2|snappyLiteral, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		dst = append(dst, 63<<2|snappyLiteral, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	}
+	return append(dst, lit...)
+}
+
+// appendSnappyCopy appends the copies of length bytes from offset bytes back,
+// at most 64 bytes each.
+func appendSnappyCopy(dst []byte, offset, length int) []byte {
+	for length > 0 {
+		n := length
+		if n > 64 {
+			n = 64
+		}
+		dst = append(dst, byte(n-1)<<2|snappyCopy2, byte(offset), byte(offset>>8))
+		length -= n
+	}
+	return dst
+}
+
+// snappyDecode decompresses src, which was compressed with snappyEncode or any
+// other writer of the snappy block format.
+func snappyDecode(src []byte) ([]byte, error) {
+	size, k := binary.Uvarint(src)
+	// A copy element expands at most 3 bytes to 64.
+	if k <= 0 || size > uint64(len(src))*22 {
+		return nil, errCorruptSnappy
+	}
+	src = src[k:]
+	dst := make([]byte, 0, size)
+	for len(src) > 0 {
+		tag := src[0]
+		var offset, length int
+		switch tag & 3 {
+		case snappyLiteral:
+			length = int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				n := length - 59
+				if len(src) < n {
+					return nil, errCorruptSnappy
+				}
+				length = 0
+				for i := n - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				src = src[n:]
+			}
+			length++
+			if length <= 0 || length > len(src) || uint64(len(dst)+length) > size {
+				return nil, errCorruptSnappy
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case snappyCopy1:
+			if len(src) < 2 {
+				return nil, errCorruptSnappy
+			}
+			length = 4 + int(tag>>2&7)
+			offset = int(tag>>5)<<8 | int(src[1])
+			src = src[2:]
+		case snappyCopy2:
+			if len(src) < 3 {
+				return nil, errCorruptSnappy
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case snappyCopy4:
+			if len(src) < 5 {
+				return nil, errCorruptSnappy
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+length) > size {
+			return nil, errCorruptSnappy
+		}
+		// Copies may overlap the bytes they append, e.g. to repeat a byte.
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if uint64(len(dst)) != size {
+		return nil, errCorruptSnappy
+	}
+	return dst, nil
+}
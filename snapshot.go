@@ -0,0 +1,80 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// saveSnapshot writes the policy of the model to the snapshot file, each rule
+// being a JSON array starting with its ptype. The file is replaced atomically,
+// so a crash never leaves a truncated snapshot behind.
+func (a *Adapter) saveSnapshot(model model.Model) error {
+	var rules [][]string
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			for _, rule := range ast.Policy {
+				rules = append(rules, append([]string{ptype}, rule...))
+			}
+		}
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(a.snapshotPath), filepath.Base(a.snapshotPath)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), a.snapshotPath); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// loadSnapshot replaces the policy of the model with the snapshot file.
+func (a *Adapter) loadSnapshot(model model.Model) error {
+	data, err := ioutil.ReadFile(a.snapshotPath)
+	if err != nil {
+		return err
+	}
+	var rules [][]string
+	if err = json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+
+	model.ClearPolicy()
+	for _, rule := range rules {
+		persist.LoadPolicyArray(rule, model)
+	}
+	return nil
+}
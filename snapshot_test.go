@@ -0,0 +1,88 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func TestSnapshotFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redis-adapter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.snapshot")
+
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_snapshot", SnapshotPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+	if _, err = os.Stat(path); err != nil {
+		t.Fatalf("snapshot was not written: %v", err)
+	}
+
+	// Redis is down.
+	logger := &recordingLogger{}
+	down, err := NewAdapter(&Config{
+		Pool: &redis.Pool{Dial: func() (redis.Conn, error) {
+			return nil, errors.New("connection refused")
+		}},
+		Key:          "casbin_rules_snapshot",
+		SnapshotPath: path,
+		Logger:       logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", down)
+	if err != nil {
+		t.Fatalf("NewEnforcer() with a snapshot failed while Redis is down: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if roles, _ := e.GetRolesForUser("alice"); len(roles) != 1 || roles[0] != "data2_admin" {
+		t.Errorf("GetRolesForUser(alice) = %v, supposed to be [data2_admin]", roles)
+	}
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "snapshot") {
+		t.Errorf("logged %q, supposed to warn about the snapshot", logger.messages)
+	}
+
+	// Without a snapshot the error is returned.
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err = down.LoadPolicy(e.GetModel()); err == nil {
+		t.Error("LoadPolicy() without a snapshot succeeded while Redis is down")
+	}
+}
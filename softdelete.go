@@ -0,0 +1,236 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// In soft-delete mode removed rules stay in the policy list and are recorded in a
+// sorted set ("<key>:deleted") scored by their deletion time in milliseconds.
+// Loads skip recorded rules, PurgeDeleted physically removes them once expired.
+// Since a rule is recorded by its value, each rule must be stored only once:
+// adding a stored rule and deleting a rule stored twice fail with ErrDuplicateRule.
+
+// ErrDuplicateRule is returned in soft-delete mode when a rule would be stored,
+// or is already stored, more than once.
+var ErrDuplicateRule = errors.New("rule stored more than once with SoftDelete")
+
+// duplicatePrefix starts the error replies of the soft-delete scripts for duplicated rules.
+const duplicatePrefix = "DUPLICATE"
+
+// countStored is the Lua code counting the copies of the rules in the list KEYS[1]
+// into the table stored.
+const countStored = `
+	local stored = {}
+	local r = redis.call('lrange', KEYS[1], 0, -1)
+	for i=1, #r do
+		stored[r[i]] = (stored[r[i]] or 0) + 1
+	end
+`
+
+// softDeleteScript records the rules in ARGV[2:] as deleted at ARGV[1], if they are stored.
+var softDeleteScript = newWriteScript(2, countStored+`
+	local deleted = KEYS[2]
+
+	for i=2, #ARGV do
+		if (stored[ARGV[i]] or 0) > 1 then
+			return redis.error_reply('`+duplicatePrefix+` ' .. ARGV[i])
+		end
+	end
+	for i=2, #ARGV do
+		if stored[ARGV[i]] then
+			redis.call('zadd', deleted, 'NX', ARGV[1], ARGV[i])
+		end
+	end
+	return
+`)
+
+// softDeleteFilteredScript records the rules matching the pattern in ARGV[2] as deleted at ARGV[1].
+var softDeleteFilteredScript = newWriteScript(2, countStored+`
+	local deleted = KEYS[2]
+	local pattern = ARGV[2]
+
+	local matched = {}
+	for i=1, #r do
+		if string.find(r[i], pattern) then
+			if stored[r[i]] > 1 then
+				return redis.error_reply('`+duplicatePrefix+` ' .. r[i])
+			end
+			table.insert(matched, r[i])
+		end
+	end
+	for i=1, #matched do
+		redis.call('zadd', deleted, 'NX', ARGV[1], matched[i])
+	end
+	return
+`)
+
+// softAddScript adds the rules in ARGV, restoring soft-deleted rules instead of
+// appending a second copy. It adds nothing if one of the rules is stored and not
+// deleted, or given twice.
+var softAddScript = newWriteScript(2, countStored+`
+	local deleted = KEYS[2]
+
+	local live = {}
+	for i=1, #ARGV do
+		if live[ARGV[i]] or (stored[ARGV[i]] and not redis.call('zscore', deleted, ARGV[i])) then
+			return redis.error_reply('`+duplicatePrefix+` ' .. ARGV[i])
+		end
+		live[ARGV[i]] = true
+	end
+	for i=1, #ARGV do
+		if redis.call('zrem', deleted, ARGV[i]) == 0 then
+			redis.call('rpush', KEYS[1], ARGV[i])
+		end
+	end
+	return
+`)
+
+// purgeDeletedScript physically removes rules deleted at or before ARGV[1].
+var purgeDeletedScript = newWriteScript(2, `
+	local key = KEYS[1]
+	local deleted = KEYS[2]
+
+	local expired = redis.call('zrangebyscore', deleted, '-inf', ARGV[1])
+	if #expired == 0 then
+		return 0
+	end
+
+	local set = {}
+	for i=1, #expired do
+		set[expired[i]] = true
+	end
+	local r = redis.call('lrange', key, 0, -1)
+	for i=1, #r do
+		if set[r[i]] then
+			redis.call('lset', key, i-1, '__CASBIN_DELETED__')
+		end
+	end
+	redis.call('lrem', key, 0, '__CASBIN_DELETED__')
+	for i=1, #expired do
+		redis.call('zrem', deleted, expired[i])
+	end
+	return #expired
+`)
+
+// deletedKey returns the key of the sorted set recording soft-deleted rules.
+func (a *Adapter) deletedKey() string {
+	return deletedKeyOf(a.key)
+}
+
+// deletedKeyOf returns the key of the sorted set recording the rules soft-deleted from key.
+func deletedKeyOf(key string) string {
+	return key + ":deleted"
+}
+
+// deletionTime returns t as a deletion score in milliseconds.
+func deletionTime(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// loadDeleted returns the set of soft-deleted rules recorded in the sorted set deletedKey.
+func (a *Adapter) loadDeleted(conn redis.Conn, deletedKey string) (map[string]struct{}, error) {
+	members, err := redis.Strings(conn.Do("ZRANGE", deletedKey, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		deleted[member] = struct{}{}
+	}
+	return deleted, nil
+}
+
+func (a *Adapter) softAdd(conn redis.Conn, texts [][]byte) error {
+	args := redis.Args{}.Add(a.key, a.deletedKey()).AddFlat(texts)
+	_, err := a.doScript(softAddScript, conn, args...)
+	return duplicateError(err)
+}
+
+func (a *Adapter) markDeleted(conn redis.Conn, texts [][]byte) error {
+	args := redis.Args{}.Add(a.key, a.deletedKey(), deletionTime(time.Now())).AddFlat(texts)
+	_, err := a.doScript(softDeleteScript, conn, args...)
+	return duplicateError(err)
+}
+
+func (a *Adapter) markDeletedFiltered(conn redis.Conn, pattern string) error {
+	_, err := a.doScript(softDeleteFilteredScript, conn, a.key, a.deletedKey(), deletionTime(time.Now()), pattern)
+	return duplicateError(err)
+}
+
+// duplicateError returns the error reply of a soft-delete script for a duplicated
+// rule as ErrDuplicateRule, and other errors as is.
+func duplicateError(err error) error {
+	if e, ok := err.(redis.Error); ok && strings.HasPrefix(string(e), duplicatePrefix) {
+		return fmt.Errorf("%w: %s", ErrDuplicateRule, strings.TrimPrefix(string(e), duplicatePrefix+" "))
+	}
+	return err
+}
+
+// checkDistinct returns ErrDuplicateRule in soft-delete mode if model holds a rule twice.
+func (a *Adapter) checkDistinct(model model.Model) error {
+	if !a.softDelete {
+		return nil
+	}
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range model[sec] {
+			seen := make(map[string]struct{}, len(ast.Policy))
+			for _, rule := range ast.Policy {
+				k := strings.Join(rule, "\x00")
+				if _, ok := seen[k]; ok {
+					return fmt.Errorf("%w: %s, %v", ErrDuplicateRule, ptype, rule)
+				}
+				seen[k] = struct{}{}
+			}
+		}
+	}
+	return nil
+}
+
+// PurgeDeleted physically removes the rules that were soft-deleted more than
+// olderThan ago. It is a no-op unless Config.SoftDelete is enabled.
+func (a *Adapter) PurgeDeleted(olderThan time.Duration) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if !a.softDelete {
+		return nil
+	}
+	if err := a.waitWrite(context.Background()); err != nil {
+		return err
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	cutoff := deletionTime(time.Now().Add(-olderThan))
+	_, err = a.doScript(purgeDeletedScript, conn, a.key, a.deletedKey(), cutoff)
+	return err
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func testStoredCounts(t *testing.T, a *Adapter, rules int, deleted int) {
+	t.Helper()
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+
+	n, err := redis.Int(conn.Do("LLEN", a.key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != rules {
+		t.Errorf("stored rules: %d, supposed to be %d", n, rules)
+	}
+	n, err = redis.Int(conn.Do("ZCARD", a.deletedKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != deleted {
+		t.Errorf("soft-deleted rules: %d, supposed to be %d", n, deleted)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_soft_delete", SoftDelete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Initialize some policy in DB.
+	initPolicy(t, a)
+	testStoredCounts(t, a, 5, 0)
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	logErr := func(action string) {
+		if err != nil {
+			t.Fatalf("test action[%s] failed, err: %v", action, err)
+		}
+	}
+
+	// The removed rule is kept in storage but no longer loaded.
+	_, err = e.RemovePolicy("alice", "data1", "read")
+	logErr("RemovePolicy")
+	err = e.LoadPolicy()
+	logErr("LoadPolicy")
+	testGetPolicyWithoutOrder(t, e, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testStoredCounts(t, a, 5, 1)
+
+	// Filtered loads exclude soft-deleted rules too.
+	err = e.LoadFilteredPolicy(Filter{V0: []string{"alice", "bob"}})
+	logErr("LoadFilteredPolicy")
+	testGetPolicyWithoutOrder(t, e, [][]string{{"bob", "data2", "write"}})
+
+	// Filtered and batch removals are soft as well.
+	err = e.LoadPolicy()
+	logErr("LoadPolicy2")
+	_, err = e.RemoveFilteredPolicy(0, "data2_admin")
+	logErr("RemoveFilteredPolicy")
+	err = a.RemovePolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"nobody", "data1", "read"}})
+	logErr("RemovePolicies")
+	err = e.LoadPolicy()
+	logErr("LoadPolicy3")
+	testGetPolicyWithoutOrder(t, e, [][]string{})
+	testStoredCounts(t, a, 5, 4)
+
+	// Adding a soft-deleted rule again restores it without storing a second copy.
+	_, err = e.AddPolicy("alice", "data1", "read")
+	logErr("AddPolicy")
+	err = e.LoadPolicy()
+	logErr("LoadPolicy4")
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}})
+	testStoredCounts(t, a, 5, 3)
+
+	// Nothing was deleted an hour ago, so nothing is purged.
+	err = a.PurgeDeleted(time.Hour)
+	logErr("PurgeDeleted")
+	testStoredCounts(t, a, 5, 3)
+
+	time.Sleep(10 * time.Millisecond)
+	err = a.PurgeDeleted(time.Millisecond)
+	logErr("PurgeDeleted2")
+	testStoredCounts(t, a, 2, 0)
+
+	err = e.LoadPolicy()
+	logErr("LoadPolicy5")
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}})
+}
+
+func TestSoftDeleteDuplicate(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_soft_delete_duplicate", SoftDelete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initPolicy(t, a)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	// A rule stored twice, e.g. before SoftDelete was enabled, cannot be soft-deleted
+	// since its tombstone would hide both copies.
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := a.marshal(savePolicyLine("p", []string{"alice", "data1", "read"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("RPUSH", a.key, text); err != nil {
+		t.Fatal(err)
+	}
+	a.release(conn)
+	testStoredCounts(t, a, 6, 0)
+
+	tests := []struct {
+		name string
+		do   func() error
+	}{
+		{"RemovePolicy", func() error { return a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}) }},
+		{"RemovePolicies", func() error {
+			return a.RemovePolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"alice", "data1", "read"}})
+		}},
+		{"RemoveFilteredPolicy", func() error { return a.RemoveFilteredPolicy("p", "p", 0, "alice") }},
+		{"RemovePoliciesChecked", func() error {
+			_, err := a.RemovePoliciesChecked("p", "p", [][]string{{"alice", "data1", "read"}})
+			return err
+		}},
+		{"AddPolicy", func() error { return a.AddPolicy("p", "p", []string{"bob", "data2", "write"}) }},
+		{"AddPolicies", func() error {
+			return a.AddPolicies("p", "p", [][]string{{"carol", "data1", "read"}, {"carol", "data1", "read"}})
+		}},
+	}
+	for _, test := range tests {
+		if err = test.do(); !errors.Is(err, ErrDuplicateRule) {
+			t.Errorf("%s() = %v, supposed to be %v", test.name, err, ErrDuplicateRule)
+		}
+	}
+	// Nothing was deleted nor added.
+	testStoredCounts(t, a, 6, 0)
+
+	// Once deleted, a rule is restored by adding it, but not twice.
+	if err = a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); !errors.Is(err, ErrDuplicateRule) {
+		t.Errorf("AddPolicy() = %v, supposed to be %v", err, ErrDuplicateRule)
+	}
+	testStoredCounts(t, a, 6, 0)
+
+	// SavePolicy stores each rule once, removing the duplicate.
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testStoredCounts(t, a, 5, 0)
+	if err = a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	testStoredCounts(t, a, 5, 1)
+}
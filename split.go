@@ -0,0 +1,134 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+// LoadSectionPolicy loads the rules of the ptypes of section sec of the model,
+// e.g. "g" to reload the grouping rules only, like LoadFilteredPolicy with a
+// Filter of these ptypes. With Config.SplitSections and PTypeSetLayout, only the
+// keys of these ptypes are read.
+func (a *Adapter) LoadSectionPolicy(model model.Model, sec string) error {
+	ptypes := make([]string, 0, len(model[sec]))
+	for ptype := range model[sec] {
+		ptypes = append(ptypes, ptype)
+	}
+	if len(ptypes) == 0 {
+		return fmt.Errorf("section %s is not in the model", sec)
+	}
+	sort.Strings(ptypes)
+	return a.LoadFilteredPolicy(model, &Filter{PType: ptypes})
+}
+
+// MigrateToSplitSections moves the rules of the policy list of the key to the
+// lists of their ptype, e.g. before switching an existing deployment to
+// Config.SplitSections. Rules keep their order within their ptype, and the
+// version is left as is. A key that is not a list, or does not exist, is left
+// untouched. It is only supported with Config.SplitSections.
+func (a *Adapter) MigrateToSplitSections() error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if !a.splitSections {
+		return errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	// The list is watched, so rules written meanwhile by a client still using
+	// the single list are not lost.
+	for i := 0; i < maxRewriteAttempts; i++ {
+		if _, err = conn.Do("WATCH", a.key); err != nil {
+			return err
+		}
+		typ, err := redis.String(conn.Do("TYPE", a.key))
+		if err != nil || typ != "list" {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+		values, err := redis.ByteSlices(conn.Do("LRANGE", a.key, 0, -1))
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+		var ptypes []string
+		grouped := make(map[string][][]byte)
+		for _, value := range values {
+			if string(value) == tombstone {
+				continue
+			}
+			var line CasbinRule
+			if err = a.unmarshal(value, &line); err != nil {
+				_, _ = conn.Do("UNWATCH")
+				return err
+			}
+			if _, ok := grouped[line.PType]; !ok {
+				ptypes = append(ptypes, line.PType)
+			}
+			grouped[line.PType] = append(grouped[line.PType], value)
+		}
+
+		if err = conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if err = conn.Send("DEL", a.key); err != nil {
+			return err
+		}
+		for _, ptype := range ptypes {
+			if err = conn.Send("RPUSH", redis.Args{}.Add(a.ptypeKey(ptype)).AddFlat(grouped[ptype])...); err != nil {
+				return err
+			}
+		}
+		if len(ptypes) > 0 {
+			if err = conn.Send("SADD", redis.Args{}.Add(a.ptypesKey()).AddFlat(ptypes)...); err != nil {
+				return err
+			}
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return execError(reply)
+		}
+	}
+	return errors.New("policy kept changing while it was migrated")
+}
+
+// validateSplitSections checks the options SplitSections cannot be combined with.
+func (c *Config) validateSplitSections(v *configCheck) {
+	if !c.SplitSections {
+		return
+	}
+	v.exclude("SplitSections", configOption{"a Layout other than ListLayout", c.Layout != ListLayout},
+		configOption{"Backend", c.Backend != nil}, configOption{"Keys", len(c.Keys) > 0},
+		configOption{"SoftDelete", c.SoftDelete}, configOption{"TrackCreationOrder", c.TrackCreationOrder},
+		configOption{"DisableLua", c.DisableLua}, configOption{"Compression", c.Compression != NoCompression},
+		configOption{"EncryptionKey", len(c.EncryptionKey) > 0},
+		configOption{"CompatOfficialAdapter", c.CompatOfficialAdapter})
+}
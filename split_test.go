@@ -0,0 +1,140 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSplitSections(t *testing.T) {
+	server := &fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	a, err := NewAdapter(&Config{RestURL: ts.URL, RestToken: "secret", SplitSections: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	if _, ok := server.lists["casbin_rules"]; ok {
+		t.Error("the rules were stored under the key, supposed to be split by ptype")
+	}
+	if n := len(server.lists["casbin_rules:p"]); n != 4 {
+		t.Errorf("%d rules under casbin_rules:p, supposed to be 4", n)
+	}
+	if g := server.lists["casbin_rules:g"]; len(g) != 1 || g[0] != `{"PType":"g","V0":"alice","V1":"data2_admin","V2":"","V3":"","V4":"","V5":""}` {
+		t.Errorf("rules under casbin_rules:g = %v, supposed to be the grouping rule", g)
+	}
+	if ptypes := server.sets["casbin_rules:ptypes"]; len(ptypes) != 2 || !ptypes["p"] || !ptypes["g"] {
+		t.Errorf("stored ptypes = %v, supposed to be p and g", ptypes)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if _, err = e.AddGroupingPolicy("bob", "data2_admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.lists["casbin_rules:g"]); n != 2 {
+		t.Errorf("%d rules under casbin_rules:g after AddGroupingPolicy, supposed to be 2", n)
+	}
+
+	// Reloading the grouping rules only reads their list.
+	e.ClearPolicy()
+	server.commands = nil
+	if err = a.LoadSectionPolicy(e.GetModel(), "g"); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{})
+	if rules := e.GetGroupingPolicy(); len(rules) != 2 {
+		t.Errorf("GetGroupingPolicy() = %v, supposed to be the 2 grouping rules", rules)
+	}
+	if want := []string{"LLEN", "LRANGE"}; !reflect.DeepEqual(server.commands, want) {
+		t.Errorf("LoadSectionPolicy(g) ran %v, supposed to read the list of g only", server.commands)
+	}
+	if err = a.LoadSectionPolicy(e.GetModel(), "x"); err == nil {
+		t.Error("LoadSectionPolicy() of a missing section succeeded, supposed to fail")
+	}
+}
+
+func TestSplitSectionsRedis(t *testing.T) {
+	for _, encoding := range []Encoding{JSONEncoding, GobEncoding} {
+		a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_split", SplitSections: true, Encoding: encoding})
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.dropTable()
+
+		initPolicy(t, a)
+
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := redis.Int(conn.Do("EXISTS", a.key)); err != nil || n != 0 {
+			t.Errorf("EXISTS %s = %d, %v, supposed to be 0 with the rules split by ptype", a.key, n, err)
+		}
+		if typ, err := redis.String(conn.Do("TYPE", a.ptypeKey("p"))); err != nil || typ != "list" {
+			t.Errorf("TYPE %s = %s, %v, supposed to be a list", a.ptypeKey("p"), typ, err)
+		}
+		a.release(conn)
+	}
+}
+
+func TestMigrateToSplitSections(t *testing.T) {
+	list, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_migrate_split"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list.dropTable()
+	source, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err = list.SavePolicy(source.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_migrate_split", SplitSections: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	if err = a.MigrateToSplitSections(); err != nil {
+		t.Fatal(err)
+	}
+	// Migrating again leaves the split lists untouched.
+	if err = a.MigrateToSplitSections(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	if n, err := redis.Int(conn.Do("LLEN", a.ptypeKey("g"))); err != nil || n != 1 {
+		t.Errorf("LLEN %s after MigrateToSplitSections = %d, %v, supposed to be 1", a.ptypeKey("g"), n, err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	if err = list.MigrateToSplitSections(); err != errLayoutUnsupported {
+		t.Errorf("MigrateToSplitSections() without SplitSections = %v, supposed to be unsupported", err)
+	}
+}
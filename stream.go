@@ -0,0 +1,482 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+// defaultStreamCompactThreshold is the default value of Config.StreamCompactThreshold.
+const defaultStreamCompactThreshold = 1000
+
+// The operations recorded by the events of StreamLayout, in their "op" field.
+const (
+	streamAdd            = "add"            // "rule" is appended
+	streamRemove         = "remove"         // the first occurrence of "rule" is removed
+	streamUpdate         = "update"         // the first occurrence of "old" is replaced by "rule"
+	streamRemoveFiltered = "removeFiltered" // the rules of "ptype" matching "index" and "values" are removed
+)
+
+// streamKey returns the key of the stream of events of StreamLayout.
+func (a *Adapter) streamKey() string {
+	return a.key + ":stream"
+}
+
+// readStream returns the snapshot of StreamLayout and the events appended since.
+// Unless the keys are watched, they are read in a transaction, so a concurrent
+// compaction cannot fold events between the reads.
+func (a *Adapter) readStream(conn redis.Conn, watched bool) ([][]byte, []interface{}, error) {
+	if watched {
+		snapshot, err := redis.ByteSlices(conn.Do("LRANGE", a.key, 0, -1))
+		if err != nil {
+			return nil, nil, err
+		}
+		events, err := redis.Values(conn.Do("XRANGE", a.streamKey(), "-", "+"))
+		return snapshot, events, err
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, nil, err
+	}
+	if err := conn.Send("LRANGE", a.key, 0, -1); err != nil {
+		return nil, nil, err
+	}
+	if err := conn.Send("XRANGE", a.streamKey(), "-", "+"); err != nil {
+		return nil, nil, err
+	}
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = execError(replies); err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := redis.ByteSlices(replies[0], nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := redis.Values(replies[1], nil)
+	return snapshot, events, err
+}
+
+// streamRules returns the current rules of StreamLayout, the snapshot with the
+// events folded into it.
+func (a *Adapter) streamRules(conn redis.Conn, watched bool) ([]CasbinRule, error) {
+	snapshot, events, err := a.readStream(conn, watched)
+	if err != nil {
+		return nil, err
+	}
+	snapshot = withoutTombstones(snapshot)
+
+	rules := make([]CasbinRule, len(snapshot))
+	for i, text := range snapshot {
+		if err = a.unmarshal(text, &rules[i]); err != nil {
+			return nil, err
+		}
+	}
+	indexOf := func(line CasbinRule) int {
+		for i := range rules {
+			if rules[i] == line {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var line, old CasbinRule
+	for _, event := range events {
+		id, fields, err := parseStreamEvent(event)
+		if err != nil {
+			return nil, err
+		}
+		if text, ok := fields["rule"]; ok {
+			if err = a.unmarshal(text, &line); err != nil {
+				return nil, fmt.Errorf("stream event %s: %w", id, err)
+			}
+		}
+		switch op := string(fields["op"]); op {
+		case streamAdd:
+			rules = append(rules, line)
+		case streamRemove:
+			if i := indexOf(line); i >= 0 {
+				rules = append(rules[:i], rules[i+1:]...)
+			}
+		case streamUpdate:
+			if err = a.unmarshal(fields["old"], &old); err != nil {
+				return nil, fmt.Errorf("stream event %s: %w", id, err)
+			}
+			if i := indexOf(old); i >= 0 {
+				rules[i] = line
+			}
+		case streamRemoveFiltered:
+			var values []string
+			index, err := strconv.Atoi(string(fields["index"]))
+			if err == nil {
+				err = json.Unmarshal(fields["values"], &values)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("stream event %s: %w", id, err)
+			}
+			match := fieldValuesMatcher(string(fields["ptype"]), index, values...)
+			kept := rules[:0]
+			for i := range rules {
+				if !match(&rules[i]) {
+					kept = append(kept, rules[i])
+				}
+			}
+			rules = kept
+		default:
+			return nil, fmt.Errorf("stream event %s: unknown operation %q", id, op)
+		}
+	}
+	return rules, nil
+}
+
+// parseStreamEvent splits an entry of an XRANGE reply into its ID and fields.
+func parseStreamEvent(event interface{}) (string, map[string][]byte, error) {
+	entry, err := redis.Values(event, nil)
+	if err != nil || len(entry) != 2 {
+		return "", nil, fmt.Errorf("malformed stream entry: %v", event)
+	}
+	id, err := redis.String(entry[0], nil)
+	if err != nil {
+		return "", nil, err
+	}
+	values, err := redis.ByteSlices(entry[1], nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("stream event %s: %w", id, err)
+	}
+	fields := make(map[string][]byte, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		fields[string(values[i])] = values[i+1]
+	}
+	return id, fields, nil
+}
+
+// streamLoadPolicy is LoadPolicy and LoadFilteredPolicy for StreamLayout.
+func (a *Adapter) streamLoadPolicy(ctx context.Context, model model.Model, filter *Filter) error {
+	var rules []CasbinRule
+	err := a.read(ctx, func(conn redis.Conn) (err error) {
+		rules, err = a.streamRules(conn, false)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var set filterSet
+	if filter != nil {
+		set = newFilterSet(filter)
+	}
+	in := a.newInterner()
+	for i := range rules {
+		if filter != nil && !set.match(&rules[i]) {
+			continue
+		}
+		in.line(&rules[i])
+		if err = loadPolicyLine(rules[i], model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamGetAllGrouped is GetAllGrouped for StreamLayout.
+func (a *Adapter) streamGetAllGrouped() (map[string][][]string, error) {
+	conn, err := a.getConn()
+	if err != nil {
+		return nil, err
+	}
+	defer a.release(conn)
+
+	rules, err := a.streamRules(conn, false)
+	if err != nil {
+		return nil, err
+	}
+	grouped := make(map[string][][]string)
+	for _, line := range rules {
+		grouped[line.PType] = append(grouped[line.PType], line.toStringPolicy()[1:])
+	}
+	return grouped, nil
+}
+
+// streamEvent returns the arguments of XADD appending an event for op.
+func (a *Adapter) streamEvent(op string, fields ...interface{}) redis.Args {
+	return redis.Args{}.Add(a.streamKey(), "*", "op", op).Add(fields...)
+}
+
+// appendEvents appends events to the stream in a transaction, then compacts the
+// stream if it grew past Config.StreamCompactThreshold.
+func (a *Adapter) appendEvents(conn redis.Conn, events []redis.Args) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := conn.Send("XADD", event...); err != nil {
+			return err
+		}
+	}
+	reply, err := conn.Do("EXEC")
+	if err == nil {
+		err = execError(reply)
+	}
+	if err != nil {
+		return err
+	}
+
+	n, err := redis.Int(conn.Do("XLEN", a.streamKey()))
+	if err == nil && n >= a.streamCompactThreshold {
+		err = a.compactStream(conn)
+	}
+	if err != nil {
+		// The events are stored, compaction is retried after the next write.
+		a.logger.Printf("redis-adapter: cannot compact stream %s: %v", a.streamKey(), err)
+	}
+	return nil
+}
+
+// marshalRules serializes rules of ptype with the configured encoding.
+func (a *Adapter) marshalRules(ptype string, rules [][]string) ([][]byte, error) {
+	texts := make([][]byte, 0, len(rules))
+	for _, rule := range rules {
+		text, err := a.marshal(savePolicyLine(ptype, rule))
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
+
+// streamAppendRules appends an event for op with each of the rules.
+func (a *Adapter) streamAppendRules(op, ptype string, rules [][]string) error {
+	texts, err := a.marshalRules(ptype, rules)
+	if err != nil || len(texts) == 0 {
+		return err
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	events := make([]redis.Args, 0, len(texts))
+	for _, text := range texts {
+		events = append(events, a.streamEvent(op, "rule", text))
+	}
+	return a.appendEvents(conn, events)
+}
+
+// streamAddPolicies is AddPolicy and AddPolicies for StreamLayout.
+func (a *Adapter) streamAddPolicies(ptype string, rules [][]string) error {
+	return a.streamAppendRules(streamAdd, ptype, rules)
+}
+
+// streamRemovePolicies is RemovePolicy and RemovePolicies for StreamLayout.
+func (a *Adapter) streamRemovePolicies(ptype string, rules [][]string) error {
+	return a.streamAppendRules(streamRemove, ptype, rules)
+}
+
+// removeFilteredEvent returns the event of RemoveFilteredPolicy.
+func (a *Adapter) removeFilteredEvent(ptype string, fieldIndex int, fieldValues []string) (redis.Args, error) {
+	values, err := json.Marshal(fieldValues)
+	if err != nil {
+		return nil, err
+	}
+	return a.streamEvent(streamRemoveFiltered, "ptype", ptype, "index", fieldIndex, "values", values), nil
+}
+
+// streamRemoveFilteredPolicy is RemoveFilteredPolicy for StreamLayout.
+func (a *Adapter) streamRemoveFilteredPolicy(ptype string, fieldIndex int, fieldValues ...string) error {
+	event, err := a.removeFilteredEvent(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return err
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	return a.appendEvents(conn, []redis.Args{event})
+}
+
+// streamUpdatePolicies is UpdatePolicy and UpdatePolicies for StreamLayout.
+func (a *Adapter) streamUpdatePolicies(ptype string, oldRules, newRules [][]string) error {
+	oldTexts, err := a.marshalRules(ptype, oldRules)
+	if err != nil {
+		return err
+	}
+	newTexts, err := a.marshalRules(ptype, newRules)
+	if err != nil {
+		return err
+	}
+	if len(oldTexts) != len(newTexts) {
+		return errors.New("the number of old and new rules differs")
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	events := make([]redis.Args, 0, len(oldTexts))
+	for i := range oldTexts {
+		events = append(events, a.streamEvent(streamUpdate, "old", oldTexts[i], "rule", newTexts[i]))
+	}
+	return a.appendEvents(conn, events)
+}
+
+// streamUpdateFilteredPolicies is UpdateFilteredPolicies for StreamLayout. It
+// returns the replaced rules, which it reads with the stream watched so they are
+// the ones the events remove.
+func (a *Adapter) streamUpdateFilteredPolicies(ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	newTexts, err := a.marshalRules(ptype, newRules)
+	if err != nil {
+		return nil, err
+	}
+	event, err := a.removeFilteredEvent(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
+	}
+	events := []redis.Args{event}
+	for _, text := range newTexts {
+		events = append(events, a.streamEvent(streamAdd, "rule", text))
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return nil, err
+	}
+	defer a.release(conn)
+
+	for i := 0; i < maxRewriteAttempts; i++ {
+		if _, err = conn.Do("WATCH", a.key, a.streamKey()); err != nil {
+			return nil, err
+		}
+		rules, err := a.streamRules(conn, true)
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return nil, err
+		}
+		match := fieldValuesMatcher(ptype, fieldIndex, fieldValues...)
+		var ret [][]string
+		for j := range rules {
+			if match(&rules[j]) {
+				ret = append(ret, rules[j].toStringPolicy())
+			}
+		}
+
+		if err = conn.Send("MULTI"); err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if err = conn.Send("XADD", event...); err != nil {
+				return nil, err
+			}
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return nil, err
+		}
+		if reply != nil {
+			return ret, execError(reply)
+		}
+	}
+	return nil, errors.New("policy kept changing while it was updated")
+}
+
+// Compact folds the events of StreamLayout into its snapshot and empties the
+// stream, so loading the policy no longer replays them. Writes compact the
+// stream once it holds Config.StreamCompactThreshold events, Compact does it
+// right away, e.g. before a burst of loads.
+func (a *Adapter) Compact() error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if a.layout != StreamLayout {
+		return errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+	return a.compactStream(conn)
+}
+
+// compactStream replaces the snapshot by the current rules and deletes the
+// stream. Both keys are watched, so events appended meanwhile are not lost.
+func (a *Adapter) compactStream(conn redis.Conn) error {
+	for i := 0; i < maxRewriteAttempts; i++ {
+		if _, err := conn.Do("WATCH", a.key, a.streamKey()); err != nil {
+			return err
+		}
+		rules, err := a.streamRules(conn, true)
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+		texts := make([][]byte, 0, len(rules))
+		for _, line := range rules {
+			text, err := a.marshal(line)
+			if err != nil {
+				_, _ = conn.Do("UNWATCH")
+				return err
+			}
+			texts = append(texts, text)
+		}
+
+		if err = conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if err = conn.Send("DEL", a.key, a.streamKey()); err != nil {
+			return err
+		}
+		for start := 0; start < len(texts); start += a.saveBatchSize {
+			end := start + a.saveBatchSize
+			if end > len(texts) {
+				end = len(texts)
+			}
+			if err = conn.Send("RPUSH", redis.Args{}.Add(a.key).AddFlat(texts[start:end])...); err != nil {
+				return err
+			}
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return execError(reply)
+		}
+	}
+	return errors.New("policy kept changing while the stream was compacted")
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// streamLen returns the number of events in the stream of a.
+func streamLen(t *testing.T, a *Adapter) int {
+	t.Helper()
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	n, err := redis.Int(conn.Do("XLEN", a.streamKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestStreamLayout(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_stream", Layout: StreamLayout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	initPolicy(t, a)
+	if n := streamLen(t, a); n != 0 {
+		t.Fatalf("stream holds %d events after SavePolicy, supposed to be empty", n)
+	}
+
+	// Writes append events, which loading replays over the snapshot.
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemoveFilteredPolicy("p", "p", 0, "data2_admin", "", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if n := streamLen(t, a); n != 4 {
+		t.Errorf("stream holds %d events, supposed to hold 4", n)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "read"}, {"data2_admin", "data2", "read"}, {"carol", "data3", "read"}})
+	if rules, err := a.GetAllPolicies(); err != nil || len(rules) != 4 || rules[0][1] != "bob" || rules[3][1] != "carol" {
+		t.Errorf("GetAllPolicies() = %v, %v, supposed to keep the order of the rules", rules, err)
+	}
+
+	if err = e.LoadFilteredPolicy(&Filter{PType: []string{"p"}, V0: []string{"carol"}}); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}})
+}
+
+func TestStreamCompaction(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_stream_compact", Layout: StreamLayout, StreamCompactThreshold: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	initPolicy(t, a)
+
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := streamLen(t, a); n != 2 {
+		t.Fatalf("stream holds %d events, supposed to hold 2 below the threshold", n)
+	}
+	// The third event reaches the threshold, and the stream is folded into the snapshot.
+	if err = a.AddPolicy("p", "p", []string{"dave", "data4", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := streamLen(t, a); n != 0 {
+		t.Errorf("stream holds %d events after compaction, supposed to be empty", n)
+	}
+	want := [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}, {"dave", "data4", "write"}}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, want)
+
+	// Compact folds the events right away.
+	if err = a.RemovePolicy("p", "p", []string{"dave", "data4", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := redis.Int(conn.Do("LLEN", a.key))
+	a.release(conn)
+	if err != nil || snapshot != 5 || streamLen(t, a) != 0 {
+		t.Errorf("snapshot holds %d rules (%v), stream %d events after Compact, supposed to be 5 and 0", snapshot, err, streamLen(t, a))
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, want[:4])
+}
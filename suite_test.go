@@ -0,0 +1,156 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter_test
+
+import (
+	"os"
+	"testing"
+
+	redisadapter "github.com/casbin/redis-adapter/v3"
+	"github.com/casbin/redis-adapter/v3/adaptertest"
+	"github.com/gomodule/redigo/redis"
+)
+
+// withConfig returns the factory of adapters of config, with the key of the
+// test and, without a pool or REST endpoint, connected to the local server.
+func withConfig(config redisadapter.Config) adaptertest.Factory {
+	return func(t *testing.T, key string) *redisadapter.Adapter {
+		config := config
+		config.Key = key
+		if config.Pool == nil && config.RestURL == "" {
+			config.Network, config.Address = "tcp", "127.0.0.1:6379"
+		}
+		a, err := redisadapter.NewAdapter(&config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+}
+
+// withAdapter returns the factory of the adapters of newAdapter, which the
+// tests skip if they cannot connect, e.g. for lack of credentials.
+func withAdapter(newAdapter func(t *testing.T, key string) (*redisadapter.Adapter, error)) adaptertest.Factory {
+	return func(t *testing.T, key string) *redisadapter.Adapter {
+		a, err := newAdapter(t, key)
+		if err != nil {
+			t.Skipf("cannot connect: %v", err)
+		}
+		return a
+	}
+}
+
+// newPool returns a pool of connections to the local server, closed at the end
+// of the test.
+func newPool(t *testing.T) *redis.Pool {
+	pool := &redis.Pool{
+		MaxIdle:   3,
+		MaxActive: 5,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", "127.0.0.1:6379")
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+// TestSuite runs the adaptertest suite against the constructors and the
+// configurations changing how rules are stored or matched.
+func TestSuite(t *testing.T) {
+	tests := []struct {
+		name       string
+		newAdapter adaptertest.Factory
+	}{
+		{"Config", withConfig(redisadapter.Config{})},
+		{"ConfigPool", func(t *testing.T, key string) *redisadapter.Adapter {
+			return withConfig(redisadapter.Config{Pool: newPool(t)})(t, key)
+		}},
+		{"Basic", withAdapter(func(t *testing.T, key string) (*redisadapter.Adapter, error) {
+			return redisadapter.NewAdapterBasic("tcp", "127.0.0.1:6379")
+		})},
+		{"Password", withAdapter(func(t *testing.T, key string) (*redisadapter.Adapter, error) {
+			return redisadapter.NewAdapterWithPassword("tcp", "127.0.0.1:6379", "testpass")
+		})},
+		{"User", withAdapter(func(t *testing.T, key string) (*redisadapter.Adapter, error) {
+			return redisadapter.NewAdapterWithUser("tcp", "127.0.0.1:6379", "testuser", "testpass")
+		})},
+		{"Key", withAdapter(func(t *testing.T, key string) (*redisadapter.Adapter, error) {
+			return redisadapter.NewAdapterWithKey("tcp", "127.0.0.1:6379", key)
+		})},
+		{"Option", withAdapter(func(t *testing.T, key string) (*redisadapter.Adapter, error) {
+			return redisadapter.NewAdapterWithOption(redisadapter.WithNetwork("tcp"), redisadapter.WithAddress("127.0.0.1:6379"), redisadapter.WithKey(key))
+		})},
+		{"Pool", withAdapter(func(t *testing.T, key string) (*redisadapter.Adapter, error) {
+			return redisadapter.NewAdapterWithPool(newPool(t))
+		})},
+		{"PoolAndOptions", withAdapter(func(t *testing.T, key string) (*redisadapter.Adapter, error) {
+			return redisadapter.NewAdapterWithPoolAndOptions(newPool(t), redisadapter.WithKey(key))
+		})},
+		{"PoolOption", withAdapter(func(t *testing.T, key string) (*redisadapter.Adapter, error) {
+			return redisadapter.NewAdapterWithOption(redisadapter.WithPool(newPool(t)), redisadapter.WithKey(key))
+		})},
+		{"SingleScanRemoval", withConfig(redisadapter.Config{SingleScanRemoval: true})},
+		// A low limit forces even small filters through the set-membership path.
+		{"FilterRegexLimit", withConfig(redisadapter.Config{FilterRegexLimit: 1})},
+		{"CSVEncoding", withConfig(redisadapter.Config{Encoding: redisadapter.CSVEncoding})},
+		{"GobEncoding", withConfig(redisadapter.Config{Encoding: redisadapter.GobEncoding})},
+		{"LowercaseJSONKeys", withConfig(redisadapter.Config{JSONKeys: redisadapter.LowercaseJSONKeys})},
+		{"SplitSections", withConfig(redisadapter.Config{SplitSections: true})},
+		{"SplitSectionsGob", withConfig(redisadapter.Config{SplitSections: true, Encoding: redisadapter.GobEncoding})},
+		// The fake REST server does not know EVAL, so the rules are removed and
+		// updated by rewriting the list.
+		{"RestCodec", func(t *testing.T, key string) *redisadapter.Adapter {
+			return withConfig(redisadapter.Config{RestURL: redisadapter.NewFakeRestServer(t), RestToken: "secret", DisableLua: true, Codec: redisadapter.NewTenantCodec(t, "acme")})(t, key)
+		}},
+		// A real REST endpoint, e.g. an Upstash database.
+		{"RestEndpoint", func(t *testing.T, key string) *redisadapter.Adapter {
+			url, token := os.Getenv("UPSTASH_REDIS_REST_URL"), os.Getenv("UPSTASH_REDIS_REST_TOKEN")
+			if url == "" {
+				t.Skip("UPSTASH_REDIS_REST_URL is not set")
+			}
+			return withConfig(redisadapter.Config{RestURL: url, RestToken: token})(t, key)
+		}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			adaptertest.TestAdapter(t, test.newAdapter)
+		})
+	}
+}
+
+// TestLayouts runs the adaptertest suite in each layout, set by Config.Layout or
+// WithLayout, with the default codec and a custom one.
+func TestLayouts(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		adaptertest.Run(t, func(t *testing.T, key string, layout redisadapter.Layout) *redisadapter.Adapter {
+			return withConfig(redisadapter.Config{Layout: layout})(t, key)
+		})
+	})
+	t.Run("Option", func(t *testing.T) {
+		adaptertest.Run(t, func(t *testing.T, key string, layout redisadapter.Layout) *redisadapter.Adapter {
+			a, err := redisadapter.NewAdapterWithOption(redisadapter.WithNetwork("tcp"), redisadapter.WithAddress("127.0.0.1:6379"), redisadapter.WithKey(key), redisadapter.WithLayout(layout))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return a
+		})
+	})
+	t.Run("Codec", func(t *testing.T) {
+		adaptertest.Run(t, func(t *testing.T, key string, layout redisadapter.Layout) *redisadapter.Adapter {
+			return withConfig(redisadapter.Config{Layout: layout, Codec: redisadapter.NewTenantCodec(t, "acme")})(t, key)
+		})
+	})
+}
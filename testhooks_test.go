@@ -0,0 +1,44 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// The suite of suite_test.go runs in package redisadapter_test, as adaptertest
+// imports this package, and reaches the test doubles through the functions
+// below.
+
+// NewFakeRestServer starts a fake REST server for the test and returns its URL.
+func NewFakeRestServer(t *testing.T) string {
+	ts := httptest.NewServer(&fakeRestServer{lists: map[string][]string{}, strings: map[string]string{}})
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+// NewTenantCodec returns a tenantCodec of tenant, checking at the end of the
+// test that it encoded and decoded rules.
+func NewTenantCodec(t *testing.T, tenant string) Codec {
+	codec := newTenantCodec(tenant)
+	t.Cleanup(func() {
+		if atomic.LoadInt64(codec.encodes) == 0 || atomic.LoadInt64(codec.decodes) == 0 {
+			t.Errorf("codec encoded %d and decoded %d rules, supposed to be used", *codec.encodes, *codec.decodes)
+		}
+	})
+	return codec
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrOperationTimeout is returned when an operation runs longer than
+// Config.OperationTimeout. Timeouts of single commands, such as read timeouts of
+// the connection, are returned as they are.
+var ErrOperationTimeout = errors.New("redis adapter operation timed out")
+
+// timeoutConn runs the commands of an operation with the deadline of the
+// operation. getConn only wraps connections in it when Config.OperationTimeout
+// is set.
+type timeoutConn struct {
+	redis.Conn
+	ctx      context.Context
+	deadline time.Time
+	timeout  time.Duration
+}
+
+func (c timeoutConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return c.DoContext(c.ctx, commandName, args...)
+}
+
+func (c timeoutConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	ctx, cancel := context.WithDeadline(ctx, c.deadline)
+	defer cancel()
+	reply, err := redis.DoContext(c.Conn, ctx, commandName, args...)
+	return reply, c.timedOut(err)
+}
+
+func (c timeoutConn) Receive() (interface{}, error) {
+	return c.ReceiveContext(c.ctx)
+}
+
+func (c timeoutConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	ctx, cancel := context.WithDeadline(ctx, c.deadline)
+	defer cancel()
+	reply, err := redis.ReceiveContext(c.Conn, ctx)
+	return reply, c.timedOut(err)
+}
+
+// timedOut reports an error of a command past the deadline as ErrOperationTimeout.
+func (c timeoutConn) timedOut(err error) error {
+	if err != nil && !time.Now().Before(c.deadline) {
+		return fmt.Errorf("%w after %v: %v", ErrOperationTimeout, c.timeout, err)
+	}
+	return err
+}
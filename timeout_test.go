@@ -0,0 +1,87 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// slowConn is a connection to a server taking delay to answer every command.
+type slowConn struct {
+	noScriptConn
+	delay time.Duration
+}
+
+func (c slowConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return c.DoContext(context.Background(), commandName, args...)
+}
+
+func (c slowConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	select {
+	case <-time.After(c.delay):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c slowConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return nil, nil
+}
+
+func TestOperationTimeout(t *testing.T) {
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return slowConn{delay: 30 * time.Millisecond}, nil }}
+	a, err := NewAdapter(&Config{Pool: pool, OperationTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The timeout bounds the operation as a whole, not each command: the
+	// second command fails although it alone would not take too long.
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("GET", "a"); err != nil {
+		t.Fatalf("first command = %v", err)
+	}
+	start := time.Now()
+	if _, err = conn.Do("GET", "b"); !errors.Is(err, ErrOperationTimeout) {
+		t.Errorf("second command = %v, supposed to be ErrOperationTimeout", err)
+	}
+	if waited := time.Since(start); waited > 25*time.Millisecond {
+		t.Errorf("second command failed after %v, supposed to be cancelled at the deadline", waited)
+	}
+	a.release(conn)
+
+	// A new operation gets a new deadline.
+	if _, err = a.Version(); err != nil {
+		t.Errorf("Version() = %v", err)
+	}
+
+	a, err = NewAdapter(&Config{Pool: pool, OperationTimeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Version(); !errors.Is(err, ErrOperationTimeout) {
+		t.Errorf("Version() = %v, supposed to be ErrOperationTimeout", err)
+	}
+
+}
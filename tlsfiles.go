@@ -0,0 +1,89 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// tlsFiles are the PEM files of Config.TLSCertFile, Config.TLSKeyFile and
+// Config.TLSCAFile.
+type tlsFiles struct {
+	cert, key, ca string
+}
+
+func (f tlsFiles) set() bool {
+	return f.cert != "" || f.key != "" || f.ca != ""
+}
+
+// load builds the TLS configuration of the files.
+func (f tlsFiles) load() (*tls.Config, error) {
+	config := &tls.Config{}
+	if f.cert != "" || f.key != "" {
+		if f.cert == "" || f.key == "" {
+			return nil, fmt.Errorf("TLS client certificate needs both TLSCertFile and TLSKeyFile")
+		}
+		pair, err := tls.LoadX509KeyPair(f.cert, f.key)
+		if err != nil {
+			return nil, fmt.Errorf("TLS client certificate %s with key %s: %w", f.cert, f.key, err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	if f.ca != "" {
+		pem, err := ioutil.ReadFile(f.ca)
+		if err != nil {
+			return nil, fmt.Errorf("TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("TLS CA file %s holds no PEM certificate", f.ca)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// effectiveTLSConfig returns the TLS configuration to dial with, nil without
+// TLS: Config.TLSConfig or the one of the files, with Config.TLSServerName and
+// Config.TLSInsecureSkipVerify filled in where unset.
+func (a *Adapter) effectiveTLSConfig() (*tls.Config, error) {
+	config := a.tlsConfig
+	if config == nil && a.tlsFiles.set() {
+		var err error
+		if config, err = a.tlsFiles.load(); err != nil {
+			return nil, err
+		}
+	}
+	if a.tlsServerName == "" && !a.tlsInsecure {
+		return config, nil
+	}
+
+	// The TLSConfig of the user is left untouched.
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = a.tlsServerName
+	}
+	if a.tlsInsecure {
+		config.InsecureSkipVerify = true
+	}
+	return config, nil
+}
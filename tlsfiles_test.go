@@ -0,0 +1,288 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate with its key, signed by the CA of the test.
+type testCert struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate for 127.0.0.1, or for the host names if
+// any, signed by ca, or self-signed if ca is nil.
+func newTestCert(t *testing.T, ca *testCert, isCA bool, names ...string) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "casbin test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if len(names) > 0 {
+		template.IPAddresses, template.DNSNames = nil, names
+	}
+	parent, signer := template, key
+	if ca != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, der: der, key: key}
+}
+
+// writeFiles writes the certificate and its key as PEM files in dir.
+func (c *testCert) writeFiles(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// serveNil accepts connections on l and replies nil to every command. It
+// passes the commands to record if not nil.
+func serveNil(l net.Listener, record func(args []string)) {
+	serve(l, func(args []string) string {
+		if record != nil {
+			record(args)
+		}
+		return "$-1\r\n"
+	})
+}
+
+// serve accepts connections on l and answers every command with the RESP
+// encoded reply returned by reply.
+func serve(l net.Listener, reply func(args []string) string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				args, err := readCommand(r)
+				if err != nil {
+					return
+				}
+				if _, err = conn.Write([]byte(reply(args))); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(r, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
+
+func TestTLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, nil, true)
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	serverCert := newTestCert(t, ca, false)
+	client := newTestCert(t, ca, false)
+	certFile, keyFile := client.writeFiles(t, dir, "client")
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca.cert)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.der}, PrivateKey: serverCert.key}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    caPool,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveNil(l, nil)
+
+	// The server requires the client certificate and is authenticated by the CA.
+	a, err := NewAdapter(&Config{Network: "tcp", Address: l.Addr().String(), TLSCertFile: certFile, TLSKeyFile: keyFile, TLSCAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Version(); err != nil {
+		t.Errorf("Version() over TLS with the files = %v", err)
+	}
+
+	// An explicit TLSConfig takes precedence over the files.
+	a, err = NewAdapter(&Config{Network: "tcp", Address: l.Addr().String(),
+		TLSConfig:   &tls.Config{RootCAs: caPool, Certificates: []tls.Certificate{{Certificate: [][]byte{client.der}, PrivateKey: client.key}}},
+		TLSCertFile: filepath.Join(dir, "missing.pem"), TLSKeyFile: filepath.Join(dir, "missing.pem"), TLSCAFile: filepath.Join(dir, "missing.pem"),
+	})
+	if err != nil {
+		t.Fatalf("NewAdapter() with TLSConfig and missing files = %v, supposed to ignore the files", err)
+	}
+	if _, err = a.Version(); err != nil {
+		t.Errorf("Version() over TLS with TLSConfig = %v", err)
+	}
+
+	notPEM := filepath.Join(dir, "not.pem")
+	if err = ioutil.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, serverKeyFile := serverCert.writeFiles(t, dir, "server")
+	tests := []struct {
+		name  string
+		files tlsFiles
+		err   string
+	}{
+		{"missing certificate", tlsFiles{cert: filepath.Join(dir, "missing.pem"), key: keyFile}, "missing.pem"},
+		{"mismatched key pair", tlsFiles{cert: certFile, key: serverKeyFile}, "does not match"},
+		{"certificate without key", tlsFiles{cert: certFile}, "both"},
+		{"missing CA file", tlsFiles{ca: filepath.Join(dir, "missing.pem")}, "missing.pem"},
+		{"CA file without certificate", tlsFiles{ca: notPEM}, "no PEM certificate"},
+	}
+	for _, test := range tests {
+		if _, err = test.files.load(); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("load() of %s = %v, supposed to fail with %q", test.name, err, test.err)
+		}
+	}
+	if _, err = NewAdapter(&Config{Network: "tcp", Address: l.Addr().String(), TLSCertFile: certFile, TLSKeyFile: serverKeyFile}); err == nil {
+		t.Error("NewAdapter() with a mismatched key pair succeeded")
+	}
+}
+
+// TestTLSFilesRedis connects to a TLS-enabled Redis with the files named by
+// REDIS_TLS_ADDRESS, REDIS_TLS_CERT, REDIS_TLS_KEY and REDIS_TLS_CA.
+func TestTLSFilesRedis(t *testing.T) {
+	address := os.Getenv("REDIS_TLS_ADDRESS")
+	if address == "" {
+		t.Skip("REDIS_TLS_ADDRESS is not set")
+	}
+	a, err := NewAdapter(&Config{Network: "tcp", Address: address, Key: "casbin_rules_tls",
+		TLSCertFile: os.Getenv("REDIS_TLS_CERT"), TLSKeyFile: os.Getenv("REDIS_TLS_KEY"), TLSCAFile: os.Getenv("REDIS_TLS_CA")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+}
+
+func TestTLSServerName(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, nil, true)
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	serverCert := newTestCert(t, ca, false, "redis.internal")
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.der}, PrivateKey: serverCert.key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveNil(l, nil)
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca.cert)
+	tests := []struct {
+		name   string
+		config Config
+		ok     bool
+	}{
+		{"address as server name", Config{TLSCAFile: caFile}, false},
+		{"TLSServerName", Config{TLSCAFile: caFile, TLSServerName: "redis.internal"}, true},
+		{"TLSInsecureSkipVerify", Config{TLSInsecureSkipVerify: true}, true},
+		{"TLSServerName filling TLSConfig", Config{TLSConfig: &tls.Config{RootCAs: caPool}, TLSServerName: "redis.internal"}, true},
+		{"ServerName of TLSConfig", Config{TLSConfig: &tls.Config{RootCAs: caPool, ServerName: "other.internal"}, TLSServerName: "redis.internal"}, false},
+	}
+	for _, test := range tests {
+		config := test.config
+		config.Network, config.Address = "tcp", l.Addr().String()
+		// The handshake happens when NewAdapter dials.
+		a, err := NewAdapter(&config)
+		if err == nil {
+			_, err = a.Version()
+		}
+		if (err == nil) != test.ok {
+			t.Errorf("connecting with %s = %v, supposed to succeed: %v", test.name, err, test.ok)
+		}
+	}
+	if tests[4].config.TLSConfig.ServerName != "other.internal" {
+		t.Error("TLSServerName changed the ServerName of TLSConfig")
+	}
+
+	a, err := NewAdapterWithOption(WithNetwork("tcp"), WithAddress(l.Addr().String()), WithTLSServerName("redis.internal"), WithTLSInsecureSkipVerify(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Version(); err != nil {
+		t.Errorf("Version() with WithTLSServerName and WithTLSInsecureSkipVerify = %v", err)
+	}
+}
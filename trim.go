@@ -0,0 +1,72 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// trimScript keeps the last ARGV[1] entries of the list and returns the number of
+// entries it dropped.
+var trimScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local max = tonumber(ARGV[1])
+
+	local n = redis.call('llen', key)
+	if n <= max then
+		return 0
+	end
+	if max == 0 then
+		redis.call('del', key)
+	else
+		redis.call('ltrim', key, -max, -1)
+	end
+	return n - max
+`)
+
+// TrimTo atomically drops the oldest rules so that at most maxLen entries remain
+// in the policy list, and returns the number of entries it dropped. It is meant
+// as a safety valve against runaway writers, not for regular policy management.
+// It is only supported by ListLayout without Config.Backend.
+func (a *Adapter) TrimTo(maxLen int) (dropped int, err error) {
+	if err := a.begin(); err != nil {
+		return 0, err
+	}
+	defer a.end()
+
+	if maxLen < 0 {
+		return 0, errors.New("maxLen must not be negative")
+	}
+	if !a.plainList() {
+		return 0, errLayoutUnsupported
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return 0, err
+	}
+	defer a.release(conn)
+
+	dropped, err = redis.Int(a.doScript(trimScript, conn, a.key, maxLen))
+	if err != nil {
+		return 0, err
+	}
+	if dropped > 0 {
+		a.bumpVersion(&err)
+	}
+	return dropped, err
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestTrimTo(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_trim"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initPolicy(t, a)
+
+	if err = a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"dave", "data4", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, err := a.TrimTo(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 4 {
+		t.Errorf("TrimTo(3) dropped %d rules, supposed to drop 4", dropped)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}, {"dave", "data4", "write"}})
+	if g := e.GetGroupingPolicy(); !util.Array2DEquals([][]string{{"alice", "data2_admin"}}, g) {
+		t.Errorf("grouping policy after TrimTo(3) = %v, supposed to keep the newest rules", g)
+	}
+
+	dropped, err = a.TrimTo(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 0 {
+		t.Errorf("TrimTo(3) of a short list dropped %d rules, supposed to drop none", dropped)
+	}
+}
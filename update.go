@@ -0,0 +1,83 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrUpdateTooLarge is returned by UpdatePolicies when a rule and its
+// replacement are together too large to be passed to a script.
+var ErrUpdateTooLarge = errors.New("rule and replacement too large for a script")
+
+// defaultUpdateBatchSize is the default value of Config.UpdateBatchSize.
+const defaultUpdateBatchSize = 1000
+
+// defaultUpdateBatchBytes bounds the size of the rules passed to one script by
+// UpdatePolicies, half of the default client-query-buffer-limit of Redis.
+const defaultUpdateBatchBytes = 512 << 20
+
+// updateBatch is a range of the rules replaced by UpdatePolicies passed to one
+// script.
+type updateBatch struct {
+	start, end int
+}
+
+// updateBatches splits the rules replaced by UpdatePolicies into batches of at
+// most a.updateBatchSize rules and a.updateBatchBytes bytes.
+func (a *Adapter) updateBatches(oldTexts, newTexts []string) ([]updateBatch, error) {
+	var batches []updateBatch
+	batch, size := updateBatch{}, 0
+	for i := range oldTexts {
+		pair := len(oldTexts[i]) + len(newTexts[i])
+		if pair > a.updateBatchBytes {
+			return nil, fmt.Errorf("rule %d: %w: %d bytes, at most %d", i, ErrUpdateTooLarge, pair, a.updateBatchBytes)
+		}
+		if batch.end > batch.start && (batch.end-batch.start == a.updateBatchSize || size+pair > a.updateBatchBytes) {
+			batches = append(batches, batch)
+			batch, size = updateBatch{start: i, end: i}, 0
+		}
+		batch.end++
+		size += pair
+	}
+	if batch.end > batch.start {
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+// updateInBatches runs script, which takes the old rules followed by the new
+// ones, once per batch in a transaction.
+func (a *Adapter) updateInBatches(conn redis.Conn, script *redis.Script, batches []updateBatch, oldTexts, newTexts []string) error {
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	// EVAL rather than EVALSHA, since the scripts of a transaction could not be
+	// retried when the script is not cached.
+	for _, batch := range batches {
+		args := redis.Args{}.Add(a.key).AddFlat(oldTexts[batch.start:batch.end]).AddFlat(newTexts[batch.start:batch.end])
+		if err := script.Send(conn, args...); err != nil {
+			return err
+		}
+	}
+	reply, err := conn.Do("EXEC")
+	if err != nil {
+		return scriptingError(err)
+	}
+	return scriptingError(execError(reply))
+}
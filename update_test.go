@@ -0,0 +1,72 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestUpdateBatchSize(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_update_batch", UpdateBatchSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+
+	var oldRules, newRules [][]string
+	for i := 0; i < 10; i++ {
+		oldRules = append(oldRules, []string{fmt.Sprintf("user%d", i), "data", "read"})
+		newRules = append(newRules, []string{fmt.Sprintf("user%d", i), "data", "write"})
+	}
+	if err = a.AddPolicies("p", "p", oldRules); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.UpdatePolicies("p", "p", oldRules, newRules); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.LoadPolicy(m); err != nil {
+		t.Fatal(err)
+	}
+	if policy := m.GetPolicy("p", "p"); !reflect.DeepEqual(policy, newRules) {
+		t.Errorf("policy after UpdatePolicies() in batches = %v, supposed to be %v", policy, newRules)
+	}
+}
+
+func TestUpdateBatches(t *testing.T) {
+	a := &Adapter{updateBatchSize: 2, updateBatchBytes: 10}
+	batches, err := a.updateBatches([]string{"a", "b", "c", "ddddd", "e"}, []string{"a", "b", "c", "ddddd", "e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The batches hold at most 2 rules and 10 bytes.
+	want := []updateBatch{{0, 2}, {2, 3}, {3, 4}, {4, 5}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("updateBatches() = %v, supposed to be %v", batches, want)
+	}
+
+	if _, err = a.updateBatches([]string{"a", "bbbbbb"}, []string{"a", "bbbbbb"}); !errors.Is(err, ErrUpdateTooLarge) {
+		t.Errorf("updateBatches() of a rule too large = %v, supposed to be ErrUpdateTooLarge", err)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors reported by Config.Validate, wrapped in a *ConfigError.
+var (
+	// ErrMissingNetwork is reported when neither Pool, RestURL nor Network is set.
+	ErrMissingNetwork = errors.New("network is required when not using a pool")
+	// ErrMissingAddress is reported when neither Pool, RestURL, Address,
+	// Addresses nor SentinelAddrs is set, or SentinelMasterName is missing.
+	ErrMissingAddress = errors.New("address is required when not using a pool")
+	// ErrMissingPassword is reported for a Username without Password.
+	ErrMissingPassword = errors.New("username requires a password")
+	// ErrInvalidKey is reported for keys made of white space only.
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrInvalidDB is reported for a negative DB.
+	ErrInvalidDB = errors.New("database out of range")
+	// ErrIgnoredOption is reported for connection options that Pool or RestURL
+	// would ignore, e.g. TLSConfig with Pool.
+	ErrIgnoredOption = errors.New("option ignored")
+	// ErrIncompatibleOptions is reported for features that cannot be combined,
+	// e.g. DisableLua and Fencing.
+	ErrIncompatibleOptions = errors.New("incompatible options")
+	// ErrInvalidValue is reported for values out of their domain, e.g. an
+	// unknown Encoding or a negative timeout.
+	ErrInvalidValue = errors.New("invalid value")
+)
+
+// ConfigError lists the problems found by Config.Validate. errors.Is and
+// errors.As match any of them.
+type ConfigError struct {
+	Errs []error
+}
+
+func (e *ConfigError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+func (e *ConfigError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *ConfigError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// configOption is an option of Config and whether it is set.
+type configOption struct {
+	name string
+	set  bool
+}
+
+// configCheck collects the problems found by Validate.
+type configCheck struct {
+	errs []error
+}
+
+// report adds a problem wrapping sentinel.
+func (v *configCheck) report(sentinel error, format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), sentinel))
+}
+
+// exclude reports the options set among others, which feature cannot be
+// combined with.
+func (v *configCheck) exclude(feature string, others ...configOption) {
+	var names []string
+	for _, other := range others {
+		if other.set {
+			names = append(names, other.name)
+		}
+	}
+	if len(names) > 0 {
+		v.report(ErrIncompatibleOptions, "%s cannot be combined with %s", feature, strings.Join(names, ", "))
+	}
+}
+
+// Validate checks the fields of the config and their combinations, and returns
+// a *ConfigError listing every problem, or nil. NewAdapter calls it.
+func (c *Config) Validate() error {
+	v := &configCheck{}
+	report := v.report
+
+	// Connection
+	switch {
+	case c.Pool != nil && c.RestURL != "":
+		report(ErrIgnoredOption, "RestURL cannot be combined with Pool")
+	case c.client != nil && (c.Pool != nil || c.RestURL != ""):
+		report(ErrIgnoredOption, "Pool and RestURL are ignored with NewAdapterWithClient")
+	case c.Pool != nil || c.RestURL != "" || c.client != nil:
+		using := "Pool"
+		if c.client != nil {
+			using = "NewAdapterWithClient"
+		} else if c.Pool == nil {
+			using = "RestURL"
+		}
+		for _, option := range c.dialOptions() {
+			report(ErrIgnoredOption, "%s is ignored with %s", option, using)
+		}
+	case c.DialFunc != nil:
+		for _, option := range c.dialOptions() {
+			if option != "DialFunc" && !isPoolOption(option) {
+				report(ErrIgnoredOption, "%s is ignored with DialFunc", option)
+			}
+		}
+	default:
+		if c.Network == "" {
+			v.errs = append(v.errs, ErrMissingNetwork)
+		}
+		switch {
+		case len(c.Addresses) > 0 && (c.Address != "" || len(c.SentinelAddrs) > 0):
+			report(ErrIncompatibleOptions, "Addresses cannot be combined with Address or SentinelAddrs")
+		case len(c.Addresses) > 0:
+			for _, address := range c.Addresses {
+				if address == "" {
+					report(ErrInvalidValue, "Addresses has an empty entry")
+					break
+				}
+			}
+		case len(c.SentinelAddrs) > 0 && c.Address != "":
+			report(ErrIncompatibleOptions, "Address cannot be combined with SentinelAddrs")
+		case len(c.SentinelAddrs) > 0 && c.SentinelMasterName == "":
+			report(ErrMissingAddress, "SentinelMasterName is required with SentinelAddrs")
+		case len(c.SentinelAddrs) == 0 && (c.SentinelMasterName != "" || c.SentinelPassword != ""):
+			report(ErrIgnoredOption, "SentinelMasterName and SentinelPassword are ignored without SentinelAddrs")
+		case len(c.SentinelAddrs) == 0 && c.Address == "":
+			v.errs = append(v.errs, ErrMissingAddress)
+		}
+		if c.CredentialsProvider != nil && (c.Username != "" || c.Password != "") {
+			report(ErrIgnoredOption, "Username and Password are ignored with CredentialsProvider")
+		} else if c.Username != "" && c.Password == "" {
+			report(ErrMissingPassword, "Username %q", c.Username)
+		}
+		if c.TLSConfig == nil && (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+			report(ErrInvalidValue, "TLSCertFile and TLSKeyFile go together")
+		}
+		if c.NetDialer != nil && (c.ConnectTimeout != 0 || c.KeepAlive != 0) {
+			report(ErrIgnoredOption, "ConnectTimeout and KeepAlive are ignored with NetDialer")
+		}
+	}
+	if c.ClosePoolOnShutdown && c.Pool == nil {
+		report(ErrIgnoredOption, "ClosePoolOnShutdown is ignored without Pool")
+	}
+	if c.DB < 0 {
+		report(ErrInvalidDB, "DB %d", c.DB)
+	}
+	for _, value := range []struct {
+		name string
+		n    int64
+	}{
+		{"ConnectTimeout", int64(c.ConnectTimeout)}, {"ReadTimeout", int64(c.ReadTimeout)}, {"WriteTimeout", int64(c.WriteTimeout)},
+		{"MaxIdle", int64(c.MaxIdle)}, {"MaxActive", int64(c.MaxActive)}, {"IdleTimeout", int64(c.IdleTimeout)},
+		{"MaxConnLifetime", int64(c.MaxConnLifetime)}, {"KeepAlive", int64(c.KeepAlive)},
+	} {
+		if value.n < 0 {
+			report(ErrInvalidValue, "%s cannot be negative", value.name)
+		}
+	}
+
+	// Keys
+	if c.Key != "" && strings.TrimSpace(c.Key) == "" {
+		report(ErrInvalidKey, "Key %q is white space", c.Key)
+	}
+	for _, key := range c.Keys {
+		if strings.TrimSpace(key) == "" {
+			report(ErrInvalidKey, "Keys entry %q is empty or white space", key)
+		}
+	}
+
+	// Storage
+	if c.Encoding != JSONEncoding && c.Encoding != GobEncoding && c.Encoding != CSVEncoding {
+		report(ErrInvalidValue, "unknown encoding %d", c.Encoding)
+	}
+	if c.JSONKeys != (JSONKeys{}) {
+		if err := c.JSONKeys.validate(); err != nil {
+			report(ErrInvalidValue, "%v", err)
+		}
+	}
+	if err := checkFieldNames(c.FieldNames); err != nil {
+		report(ErrInvalidValue, "%v", err)
+	}
+
+	// Features, each checking the options it cannot be combined with.
+	c.validateRest(v)
+	c.validateLayout(v)
+	c.validateBackend(v)
+	c.validateSplitSections(v)
+	c.validateLua(v)
+	c.validateCreationOrder(v)
+	c.validateCompat(v)
+	c.validateCompression(v)
+	c.validateEncryption(v)
+	c.validateCodec(v)
+	c.validateMatching(v)
+
+	if len(v.errs) > 0 {
+		return &ConfigError{Errs: v.errs}
+	}
+	return nil
+}
+
+// dialOptions returns the names of the set options used to dial the server,
+// which Pool and RestURL ignore.
+func (c *Config) dialOptions() []string {
+	var options []string
+	for _, option := range []configOption{
+		{"Network", c.Network != ""}, {"Address", c.Address != ""}, {"DB", c.DB != 0},
+		{"Username", c.Username != ""}, {"Password", c.Password != ""}, {"CredentialsProvider", c.CredentialsProvider != nil},
+		{"TLSConfig", c.TLSConfig != nil},
+		{"TLSCertFile", c.TLSCertFile != ""}, {"TLSKeyFile", c.TLSKeyFile != ""}, {"TLSCAFile", c.TLSCAFile != ""},
+		{"TLSServerName", c.TLSServerName != ""}, {"TLSInsecureSkipVerify", c.TLSInsecureSkipVerify},
+		{"ClientName", c.ClientName != ""}, {"StrictClientName", c.StrictClientName},
+		{"SentinelAddrs", len(c.SentinelAddrs) > 0}, {"SentinelMasterName", c.SentinelMasterName != ""},
+		{"SentinelPassword", c.SentinelPassword != ""}, {"Addresses", len(c.Addresses) > 0},
+		{"ConnectTimeout", c.ConnectTimeout != 0}, {"ReadTimeout", c.ReadTimeout != 0}, {"WriteTimeout", c.WriteTimeout != 0},
+		{"KeepAlive", c.KeepAlive != 0},
+		{"MaxIdle", c.MaxIdle != 0}, {"MaxActive", c.MaxActive != 0}, {"IdleTimeout", c.IdleTimeout != 0},
+		{"MaxConnLifetime", c.MaxConnLifetime != 0}, {"PoolWait", c.PoolWait}, {"DialFunc", c.DialFunc != nil},
+		{"NetDialer", c.NetDialer != nil},
+	} {
+		if option.set {
+			options = append(options, option.name)
+		}
+	}
+	return options
+}
+
+// isPoolOption reports whether the dial option named name tunes the pool of
+// the adapter rather than dialing, so that it applies with DialFunc.
+func isPoolOption(name string) bool {
+	switch name {
+	case "MaxIdle", "MaxActive", "IdleTimeout", "MaxConnLifetime", "PoolWait":
+		return true
+	}
+	return false
+}
+
+// clearDialOptions drops the options used to dial the server when a pool is
+// set, for the constructors that ignored them before Validate reported them.
+func (c *Config) clearDialOptions() {
+	if c.Pool == nil && c.client == nil {
+		return
+	}
+	c.Network, c.Address, c.DB, c.Username, c.Password, c.TLSConfig = "", "", 0, "", "", nil
+	c.CredentialsProvider = nil
+	c.TLSCertFile, c.TLSKeyFile, c.TLSCAFile = "", "", ""
+	c.TLSServerName, c.TLSInsecureSkipVerify = "", false
+	c.ClientName, c.StrictClientName = "", false
+	c.SentinelAddrs, c.SentinelMasterName, c.SentinelPassword = nil, "", ""
+	c.Addresses, c.DialFunc, c.NetDialer = nil, nil, nil
+	c.ConnectTimeout, c.ReadTimeout, c.WriteTimeout = 0, 0, 0
+	c.KeepAlive = 0
+}
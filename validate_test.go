@@ -0,0 +1,103 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestConfigValidate(t *testing.T) {
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return noScriptConn{}, nil }}
+	if err := (&Config{Pool: pool, Key: "casbin_rules"}).Validate(); err != nil {
+		t.Errorf("Validate() of a pool-only config = %v, supposed to be nil", err)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   error
+	}{
+		{"no network", Config{Address: "127.0.0.1:6379"}, ErrMissingNetwork},
+		{"no address", Config{Network: "tcp"}, ErrMissingAddress},
+		{"sentinel without master name", Config{Network: "tcp", SentinelAddrs: []string{"127.0.0.1:26379"}}, ErrMissingAddress},
+		{"sentinel and address", Config{Network: "tcp", Address: "127.0.0.1:6379", SentinelAddrs: []string{"127.0.0.1:26379"},
+			SentinelMasterName: "mymaster"}, ErrIncompatibleOptions},
+		{"username without password", Config{Network: "tcp", Address: "127.0.0.1:6379", Username: "casbin"}, ErrMissingPassword},
+		{"password and credentials provider", Config{Network: "tcp", Address: "127.0.0.1:6379", Password: "secret",
+			CredentialsProvider: func() (string, string, error) { return "", "secret", nil }}, ErrIgnoredOption},
+		{"net dialer and keep-alive", Config{Network: "tcp", Address: "127.0.0.1:6379", KeepAlive: time.Minute,
+			NetDialer: net.Dial}, ErrIgnoredOption},
+		{"white space key", Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "  "}, ErrInvalidKey},
+		{"empty merged key", Config{Network: "tcp", Address: "127.0.0.1:6379", Keys: []string{""}}, ErrInvalidKey},
+		{"negative DB", Config{Network: "tcp", Address: "127.0.0.1:6379", DB: -1}, ErrInvalidDB},
+		{"pool and TLS", Config{Pool: pool, TLSConfig: &tls.Config{}}, ErrIgnoredOption},
+		{"pool and address", Config{Pool: pool, Address: "127.0.0.1:6379"}, ErrIgnoredOption},
+		{"pool and REST", Config{Pool: pool, RestURL: "https://example.com"}, ErrIgnoredOption},
+		{"pool and PoolWait", Config{Pool: pool, PoolWait: true}, ErrIgnoredOption},
+		{"gob over REST", Config{RestURL: "https://example.com", Encoding: GobEncoding}, ErrIncompatibleOptions},
+		{"repair version over REST", Config{RestURL: "https://example.com", RepairVersionOnStart: true}, ErrIncompatibleOptions},
+		{"HTTP client without REST", Config{Pool: pool, HTTPClient: http.DefaultClient}, ErrIgnoredOption},
+		{"DisableLua and Fencing", Config{Pool: pool, DisableLua: true, Fencing: true}, ErrIncompatibleOptions},
+		{"unknown layout", Config{Pool: pool, Layout: Layout(42)}, ErrInvalidValue},
+		{"hash layout and DisableLua", Config{Pool: pool, Layout: HashLayout, DisableLua: true}, ErrIncompatibleOptions},
+		{"zset layout and soft delete", Config{Pool: pool, Layout: ZSetLayout, SoftDelete: true}, ErrIncompatibleOptions},
+		{"backend and stream layout", Config{Pool: pool, Backend: ListBackend{}, Layout: StreamLayout}, ErrIncompatibleOptions},
+		{"compat and gob encoding", Config{Pool: pool, CompatOfficialAdapter: true, Encoding: GobEncoding}, ErrIncompatibleOptions},
+		{"backend and single scan removal", Config{Pool: pool, Backend: ListBackend{}, SingleScanRemoval: true}, ErrIgnoredOption},
+		{"compat and raw pattern matching", Config{Pool: pool, CompatOfficialAdapter: true, RawPatternMatching: true}, ErrIncompatibleOptions},
+		{"strict fields without raw pattern matching", Config{Pool: pool, StrictFieldValidation: true}, ErrIgnoredOption},
+		{"unknown compression", Config{Pool: pool, Compression: Compression(9)}, ErrInvalidValue},
+		{"compression over rest", Config{RestURL: "http://127.0.0.1:1", Compression: GzipCompression}, ErrIncompatibleOptions},
+		{"short encryption key", Config{Pool: pool, EncryptionKey: []byte("short")}, ErrInvalidValue},
+		{"encryption and track creation order", Config{Pool: pool, EncryptionKey: testEncryptionKey, TrackCreationOrder: true}, ErrIncompatibleOptions},
+		{"codec and compat", Config{Pool: pool, Codec: JSONCodec{}, CompatOfficialAdapter: true}, ErrIncompatibleOptions},
+		{"codec and encoding", Config{Pool: pool, Codec: JSONCodec{}, Encoding: GobEncoding}, ErrIgnoredOption},
+		{"split sections and set layout", Config{Pool: pool, SplitSections: true, Layout: PTypeSetLayout}, ErrIncompatibleOptions},
+		{"split sections and disable lua", Config{Pool: pool, SplitSections: true, DisableLua: true}, ErrIncompatibleOptions},
+		{"negative timeout", Config{Network: "tcp", Address: "127.0.0.1:6379", ReadTimeout: -time.Second}, ErrInvalidValue},
+	}
+	for _, test := range tests {
+		err := test.config.Validate()
+		if !errors.Is(err, test.want) {
+			t.Errorf("Validate() of %s = %v, supposed to be %v", test.name, err, test.want)
+		}
+		config := test.config
+		if _, err = NewAdapter(&config); !errors.Is(err, test.want) {
+			t.Errorf("NewAdapter() of %s = %v, supposed to be %v", test.name, err, test.want)
+		}
+	}
+
+	// Every problem is reported at once.
+	err := (&Config{Username: "casbin", Key: " ", DB: -1}).Validate()
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Validate() = %v, supposed to be a *ConfigError", err)
+	}
+	if len(configErr.Errs) != 5 {
+		t.Errorf("Validate() = %v, supposed to report 5 problems", err)
+	}
+	for _, want := range []error{ErrMissingNetwork, ErrMissingAddress, ErrMissingPassword, ErrInvalidKey, ErrInvalidDB} {
+		if !errors.Is(err, want) {
+			t.Errorf("Validate() = %v, supposed to include %v", err, want)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrVersionConflict is returned by SavePolicyIfVersion when the policy version is
+// not the expected one, i.e. the policy was written since it was read.
+var ErrVersionConflict = errors.New("policy version conflict")
+
+// ErrWatchUnavailable is returned over REST by SavePolicyIfVersion and
+// RepairVersion, which need the optimistic locking of WATCH that the REST API
+// lacks, so they could not be atomic.
+var ErrWatchUnavailable = errors.New("WATCH is not available over REST")
+
+// maxRepairAttempts bounds how many times RepairVersion retries when the policy
+// changes while it computes the content hash.
+const maxRepairAttempts = 3
+
+// repairVersionScript bumps the version, resetting it if it is not an integer,
+// and records the content hash ARGV[1] for the new version in the meta hash.
+// Unless ARGV[2] is "1", nothing is done if the version is valid and the hash
+// was already recorded for it.
+var repairVersionScript = redis.NewScript(2, `
+	local versionKey = KEYS[1]
+	local metaKey = KEYS[2]
+
+	local v = tonumber(redis.call('get', versionKey) or '0')
+	if v == nil or v ~= math.floor(v) then
+		v = 0
+	elseif ARGV[2] ~= '1' then
+		local meta = redis.call('hmget', metaKey, 'hash', 'version')
+		if meta[1] == ARGV[1] and tonumber(meta[2]) == v then
+			return v
+		end
+	end
+	v = v + 1
+	redis.call('set', versionKey, v)
+	redis.call('hset', metaKey, 'hash', ARGV[1])
+	redis.call('hset', metaKey, 'version', v)
+	return v
+`)
+
+// versionKey returns the key of the version counter, incremented by every write.
+func (a *Adapter) versionKey() string {
+	return a.key + ":version"
+}
+
+// metaKey returns the key of the hash holding the content hash recorded by RepairVersion.
+func (a *Adapter) metaKey() string {
+	return a.key + ":meta"
+}
+
+// bumpVersion increments the version after a successful write that is not
+// audited, see changed.
+func (a *Adapter) bumpVersion(err *error) {
+	if *err != nil {
+		return
+	}
+	conn, e := a.getConn()
+	if e != nil {
+		*err = e
+		return
+	}
+	defer a.release(conn)
+
+	if _, e = conn.Do("INCR", a.versionKey()); e != nil {
+		*err = fmt.Errorf("version counter: %w", e)
+	}
+}
+
+// Version returns the version of the policy, which every write through an adapter
+// increments. Clients can poll it and reload the policy when it changes. It is 0
+// if the policy was never written.
+func (a *Adapter) Version() (int64, error) {
+	if err := a.begin(); err != nil {
+		return 0, err
+	}
+	defer a.end()
+
+	conn, err := a.getConn()
+	if err != nil {
+		return 0, err
+	}
+	defer a.release(conn)
+	return a.readVersion(conn)
+}
+
+// readVersion is Version on conn.
+func (a *Adapter) readVersion(conn redis.Conn) (int64, error) {
+	v, err := redis.Int64(conn.Do("GET", a.versionKey()))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("version counter: %w", err)
+	}
+	return v, nil
+}
+
+// ensureKeyScript creates KEYS[1] holding a tombstone, as a value of the type
+// ARGV[1], unless it exists or ARGV[1] is empty, and the version counter KEYS[2]
+// at 0 unless it exists.
+var ensureKeyScript = newWriteScript(2, `
+	if ARGV[1] ~= '' and redis.call('exists', KEYS[1]) == 0 then
+		if ARGV[1] == 'list' then
+			redis.call('rpush', KEYS[1], ARGV[2])
+		elseif ARGV[1] == 'set' then
+			redis.call('sadd', KEYS[1], ARGV[2])
+		elseif ARGV[1] == 'hash' then
+			redis.call('hset', KEYS[1], ARGV[2], ARGV[2])
+		else
+			redis.call('zadd', KEYS[1], 0, ARGV[2])
+		end
+	end
+	redis.call('set', KEYS[2], 0, 'NX')
+	return
+`)
+
+// EnsureKey makes the policy key exist before any rule is saved, e.g. for
+// monitoring alerting on missing keys. Redis does not store empty values, so
+// EnsureKey stores a tombstone in the key, which loads skip like those left by
+// TrimTo, and creates the version counter "<key>:version" at 0, atomically.
+// PTypeSetLayout and Config.SplitSections store it in the set of the ptypes.
+// With Config.Backend, whose keys the adapter does not know the type of, only
+// the version counter is created. It leaves an existing key and counter
+// untouched, and fails if the key holds a value of another type.
+func (a *Adapter) EnsureKey() error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	key, want := a.key, "list"
+	switch a.layout {
+	case HashLayout:
+		want = "hash"
+	case ZSetLayout:
+		want = "zset"
+	}
+	if a.layout != StreamLayout && !a.ownBackend() {
+		// Config.Backend chooses the type.
+		want = ""
+	}
+	if a.perPType() {
+		key, want = a.ptypesKey(), "set"
+	}
+	typ, err := redis.String(conn.Do("TYPE", key))
+	if err != nil {
+		return err
+	}
+	if typ != "none" && want != "" && typ != want {
+		return fmt.Errorf("key %s holds a %s, not a %s", key, typ, want)
+	}
+	if a.disableLua {
+		return a.ensureListWatched(conn, key)
+	}
+	_, err = runScript(ensureKeyScript, conn, key, a.versionKey(), want, tombstone)
+	return err
+}
+
+// ensureListWatched is ensureKeyScript for Config.DisableLua, which only lists
+// support. The list is watched, so the tombstone is not pushed after rules
+// written meanwhile.
+func (a *Adapter) ensureListWatched(conn redis.Conn, key string) error {
+	for i := 0; i < maxRewriteAttempts; i++ {
+		if _, err := conn.Do("WATCH", key); err != nil {
+			return err
+		}
+		exists, err := redis.Bool(conn.Do("EXISTS", key))
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+
+		if err = conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if !exists {
+			if err = conn.Send("RPUSH", key, tombstone); err != nil {
+				return err
+			}
+		}
+		if err = conn.Send("SET", a.versionKey(), 0, "NX"); err != nil {
+			return err
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return execError(reply)
+		}
+	}
+	return errors.New("policy kept changing while its key was created")
+}
+
+// SavePolicyIfVersion is SavePolicy that only saves if the policy version is still
+// expectedVersion, as returned by Version, and otherwise returns ErrVersionConflict.
+// The check, the save and the version increment are atomic, so a writer holding a
+// stale policy cannot overwrite a newer one. It returns ErrWatchUnavailable over REST.
+func (a *Adapter) SavePolicyIfVersion(model model.Model, expectedVersion int64) (err error) {
+	defer a.observe("SavePolicyIfVersion", time.Now(), &err)
+	defer a.observeRules(model, &err)
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+	if a.overREST() {
+		return ErrWatchUnavailable
+	}
+	ctx := context.Background()
+	defer func() { a.audit(ctx, &err, AuditEntry{Op: AuditSave, Count: countRules(model)}) }()
+
+	if err := a.waitWrite(ctx); err != nil {
+		return err
+	}
+
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	// The transaction fails if another write bumps the version after it is checked.
+	if _, err = conn.Do("WATCH", a.versionKey()); err != nil {
+		return err
+	}
+	v, err := redis.Int64(conn.Do("GET", a.versionKey()))
+	if err == redis.ErrNil {
+		v, err = 0, nil
+	}
+	if err != nil {
+		_, _ = conn.Do("UNWATCH")
+		return fmt.Errorf("version counter: %w", err)
+	}
+	if v != expectedVersion {
+		_, _ = conn.Do("UNWATCH")
+		return ErrVersionConflict
+	}
+
+	tmps, err := a.sendSavePolicy(conn, model)
+	if err != nil {
+		return err
+	}
+	if err = conn.Send("INCR", a.versionKey()); err != nil {
+		a.dropTempKeys(conn, tmps)
+		return err
+	}
+	reply, err := conn.Do("EXEC")
+	if err == nil && reply == nil {
+		err = ErrVersionConflict
+	}
+	if err == nil {
+		err = execError(reply)
+	}
+	if err != nil {
+		a.dropTempKeys(conn, tmps)
+		return err
+	}
+	return a.syncCreated(conn)
+}
+
+// RepairVersion recomputes the content hash of the policy and bumps the version,
+// so that clients reload the policy. It repairs a version that is out of sync with
+// the data, e.g. after a crash between a write and the version increment, and
+// resets a version that is not an integer. It returns ErrWatchUnavailable over REST.
+func (a *Adapter) RepairVersion() error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	return a.repairVersion(true)
+}
+
+// repairVersion is RepairVersion. Unless force is set, the version is only bumped
+// if the content hash differs from the one recorded for the current version.
+func (a *Adapter) repairVersion(force bool) error {
+	if a.disableLua {
+		return errLuaDisabled
+	}
+	if a.overREST() {
+		return ErrWatchUnavailable
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		return err
+	}
+	defer a.release(conn)
+
+	forceArg := "0"
+	if force {
+		forceArg = "1"
+	}
+	for i := 0; i < maxRepairAttempts; i++ {
+		keys, err := a.contentKeys(conn)
+		if err != nil {
+			return err
+		}
+		// The transaction fails if the policy changes while the hash is computed.
+		if _, err = conn.Do("WATCH", keys...); err != nil {
+			return err
+		}
+		hash, err := a.contentHash(conn)
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return err
+		}
+
+		if err = conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if err = repairVersionScript.Send(conn, a.versionKey(), a.metaKey(), hash, forceArg); err != nil {
+			return err
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return scriptingError(err)
+		}
+		if reply != nil {
+			return nil
+		}
+	}
+	return errors.New("policy kept changing while repairing the version")
+}
+
+// contentKeys returns the keys holding the policy and its version.
+func (a *Adapter) contentKeys(conn redis.Conn) ([]interface{}, error) {
+	keys := a.policyKeys()
+	if a.perPType() {
+		var err error
+		if keys, err = a.setPolicyKeys(conn); err != nil {
+			return nil, err
+		}
+	}
+	return append(keys, a.versionKey()), nil
+}
+
+// contentHash returns a hash of the stored rules. Sets and hashes have no order,
+// so their rules are sorted first.
+func (a *Adapter) contentHash(conn redis.Conn) (string, error) {
+	var texts [][]byte
+	if a.layout == StreamLayout {
+		rules, err := a.streamRules(conn, true)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range rules {
+			text, err := a.marshal(line)
+			if err != nil {
+				return "", err
+			}
+			texts = append(texts, text)
+		}
+	} else if a.perPType() {
+		lines, err := a.loadMembers(conn, nil)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range lines {
+			text, err := json.Marshal(line.toStringPolicy())
+			if err != nil {
+				return "", err
+			}
+			texts = append(texts, text)
+		}
+		sort.Slice(texts, func(i, j int) bool { return string(texts[i]) < string(texts[j]) })
+	} else {
+		var err error
+		if texts, err = a.loadValues(conn); err != nil {
+			return "", err
+		}
+		if a.layout == HashLayout {
+			sort.Slice(texts, func(i, j int) bool { return string(texts[i]) < string(texts[j]) })
+		}
+	}
+
+	h := sha256.New()
+	var size [8]byte
+	for _, text := range texts {
+		binary.BigEndian.PutUint64(size[:], uint64(len(text)))
+		h.Write(size[:])
+		h.Write(text)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestRepairVersion(t *testing.T) {
+	config := &Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_version"}
+	a, err := NewAdapter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+
+	initPolicy(t, a)
+	v1, err := a.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1 <= 0 {
+		t.Fatalf("Version() = %d after SavePolicy, supposed to be positive", v1)
+	}
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	v2, err := a.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2 <= v1 {
+		t.Fatalf("Version() = %d after AddPolicy, supposed to be greater than %d", v2, v1)
+	}
+
+	// A writer crashed before bumping the version: clients see no change.
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := a.marshal(CasbinRule{PType: "p", V0: "eve", V1: "data1", V2: "read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("RPUSH", a.key, text); err != nil {
+		t.Fatal(err)
+	}
+	a.release(conn)
+	if v, _ := a.Version(); v != v2 {
+		t.Fatalf("Version() = %d, supposed to be unchanged", v)
+	}
+
+	if err = a.RepairVersion(); err != nil {
+		t.Fatal(err)
+	}
+	v3, err := a.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v3 == v2 {
+		t.Error("RepairVersion() did not change the version")
+	}
+
+	// Startup repair only bumps the version when the content changed.
+	config.RepairVersionOnStart = true
+	if _, err = NewAdapter(config); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := a.Version(); v != v3 {
+		t.Errorf("Version() = %d after a startup repair of an unchanged policy, supposed to be %d", v, v3)
+	}
+	conn, err = a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("LPOP", a.key); err != nil {
+		t.Fatal(err)
+	}
+	a.release(conn)
+	if _, err = NewAdapter(config); err != nil {
+		t.Fatal(err)
+	}
+	v4, err := a.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v4 == v3 {
+		t.Error("startup repair did not change the version of a changed policy")
+	}
+
+	// A corrupted version is reset.
+	conn, err = a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Do("SET", a.versionKey(), "garbage"); err != nil {
+		t.Fatal(err)
+	}
+	a.release(conn)
+	if _, err = a.Version(); err == nil {
+		t.Error("Version() of a corrupted counter succeeded, supposed to fail")
+	}
+	if err = a.RepairVersion(); err != nil {
+		t.Fatal(err)
+	}
+	v5, err := a.Version()
+	if err != nil {
+		t.Fatalf("Version() after RepairVersion() = %v", err)
+	}
+	if v5 == v4 {
+		t.Errorf("Version() = %d after repairing a corrupted counter, supposed to differ from %d", v5, v4)
+	}
+}
+
+func TestSavePolicyIfVersion(t *testing.T) {
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_cas"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dropTable()
+	initPolicy(t, a)
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := a.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another writer advances the version between the read and the save.
+	if err = a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	e.GetModel().AddPolicy("p", "p", []string{"dave", "data4", "write"})
+	if err = a.SavePolicyIfVersion(e.GetModel(), v); err != ErrVersionConflict {
+		t.Fatalf("SavePolicyIfVersion() with a stale version = %v, supposed to be ErrVersionConflict", err)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+
+	v, err = a.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.GetModel().AddPolicy("p", "p", []string{"dave", "data4", "write"})
+	if err = a.SavePolicyIfVersion(e.GetModel(), v); err != nil {
+		t.Fatalf("SavePolicyIfVersion() with the current version = %v", err)
+	}
+	if after, _ := a.Version(); after != v+1 {
+		t.Errorf("Version() = %d after SavePolicyIfVersion(), supposed to be %d", after, v+1)
+	}
+	if err = e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}, {"dave", "data4", "write"}})
+}
+
+func TestEnsureKey(t *testing.T) {
+	configs := []struct {
+		config *Config
+		key    func(a *Adapter) string
+		typ    string
+	}{
+		{&Config{}, func(a *Adapter) string { return a.key }, "list"},
+		{&Config{DisableLua: true}, func(a *Adapter) string { return a.key }, "list"},
+		{&Config{Layout: PTypeSetLayout}, (*Adapter).ptypesKey, "set"},
+		{&Config{SplitSections: true}, (*Adapter).ptypesKey, "set"},
+		{&Config{Layout: HashLayout}, func(a *Adapter) string { return a.key }, "hash"},
+		{&Config{Layout: ZSetLayout}, func(a *Adapter) string { return a.key }, "zset"},
+		{&Config{Layout: StreamLayout}, func(a *Adapter) string { return a.key }, "list"},
+	}
+
+	for _, c := range configs {
+		config := *c.config
+		config.Network, config.Address, config.Key = "tcp", "127.0.0.1:6379", "casbin_rules_ensure"
+		a, err := NewAdapter(&config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.dropTable()
+
+		if err = a.EnsureKey(); err != nil {
+			t.Fatalf("EnsureKey() with %+v = %v", c.config, err)
+		}
+		conn, err := a.getConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ, _ := redis.String(conn.Do("TYPE", c.key(a))); typ != c.typ {
+			t.Errorf("type of %s after EnsureKey() with %+v = %s, supposed to be %s", c.key(a), c.config, typ, c.typ)
+		}
+		if n, _ := redis.Int(conn.Do("EXISTS", a.versionKey())); n != 1 {
+			t.Errorf("EnsureKey() with %+v did not create the version counter", c.config)
+		}
+		a.release(conn)
+		if v, _ := a.Version(); v != 0 {
+			t.Errorf("Version() after EnsureKey() with %+v = %d, supposed to be 0", c.config, v)
+		}
+		e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicy(t, e, [][]string{})
+
+		// With rules stored, EnsureKey changes nothing.
+		if _, err = e.AddPolicy("alice", "data1", "read"); err != nil {
+			t.Fatal(err)
+		}
+		before, _ := a.Version()
+		if err = a.EnsureKey(); err != nil {
+			t.Fatalf("EnsureKey() with %+v = %v", c.config, err)
+		}
+		if v, _ := a.Version(); v != before {
+			t.Errorf("Version() after EnsureKey() with %+v = %d, supposed to stay %d", c.config, v, before)
+		}
+		if err = e.LoadPolicy(); err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+		a.dropTable()
+		a.Close()
+	}
+
+	// A key of another type is reported.
+	a, err := NewAdapter(&Config{Network: "tcp", Address: "127.0.0.1:6379", Key: "casbin_rules_ensure"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := a.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.release(conn)
+	if _, err = conn.Do("SET", a.key, "x"); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Do("DEL", a.key)
+	if err = a.EnsureKey(); err == nil {
+		t.Error("EnsureKey() with a string key succeeded, supposed to fail")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// WarmPool dials connections until n of them are idle in the pool, and in
+// ReadPool if set, so the first requests after startup do not pay the dial
+// latency. It opens no more than the pools can hold, MaxIdle idle connections
+// and MaxActive connections in all. It fails over REST and with NewAdapterWithClient, which have no pool.
+func (a *Adapter) WarmPool(n int) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if a._pool == nil {
+		return errors.New("WarmPool needs a connection pool, which REST and NewAdapterWithClient do not use")
+	}
+	if err := warmPool(a._pool, n); err != nil {
+		return err
+	}
+	if a.readPool != nil {
+		if err := warmPool(a.readPool, n); err != nil {
+			return fmt.Errorf("read pool: %w", err)
+		}
+	}
+	return nil
+}
+
+// warmPool dials connections until n of them are idle in pool.
+func warmPool(pool *redis.Pool, n int) error {
+	// Taking n connections at once reuses the idle ones and dials the rest.
+	// ActiveCount includes the idle connections.
+	stats := pool.Stats()
+	if pool.MaxIdle < n {
+		n = pool.MaxIdle
+	}
+	if inUse := stats.ActiveCount - stats.IdleCount; pool.MaxActive > 0 && n > pool.MaxActive-inUse {
+		n = pool.MaxActive - inUse
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	// The connections are all taken before any is returned, otherwise the pool
+	// would hand out the same idle connection again.
+	conns := make([]redis.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		conn, err := pool.GetContext(context.Background())
+		if err == nil {
+			err = conn.Err()
+		}
+		if err != nil {
+			if conn != nil {
+				conn.Close()
+			}
+			return err
+		}
+		conns = append(conns, conn)
+	}
+	return nil
+}
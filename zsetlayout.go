@@ -0,0 +1,184 @@
+// Copyright 2025 The casbin Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redisadapter
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// lastScoreLua sets last to the highest score of the sorted set key, or 0 if it
+// is empty, for the scripts adding rules after it.
+const lastScoreLua = `
+	local last = 0
+	local top = redis.call('zrange', key, -1, -1, 'WITHSCORES')
+	if #top > 0 then
+		last = tonumber(top[2])
+	end
+`
+
+// addScoredScript adds each rule in ARGV after the highest score, unless it is
+// stored already, so that it comes last in the order.
+var addScoredScript = newWriteScript(1, `
+	local key = KEYS[1]
+`+lastScoreLua+`
+	for i=1, #ARGV do
+		if not redis.call('zscore', key, ARGV[i]) then
+			last = last + 1
+			redis.call('zadd', key, last, ARGV[i])
+		end
+	end
+	return
+`)
+
+// updateScoredScript replaces each of the first ARGV[1] rules following it with
+// the rule at the same position after them, if it is stored. The new rule takes
+// the score of the old one, so it keeps its position.
+var updateScoredScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local n = tonumber(ARGV[1])
+
+	for i=2, n+1 do
+		local score = redis.call('zscore', key, ARGV[i])
+		if score then
+			redis.call('zrem', key, ARGV[i])
+			redis.call('zadd', key, score, ARGV[i+n])
+		end
+	end
+	return
+`)
+
+// replaceScoredScript replaces the ARGV[1] rules following it by the rules after
+// them, in order: each new rule takes the score of a replaced rule, the replaced
+// rules left over are removed and the new rules left over are added last.
+var replaceScoredScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local n = tonumber(ARGV[1])
+	local m = #ARGV - 1 - n
+`+lastScoreLua+`
+	local freed = {}
+	for i=2, n+1 do
+		local score = redis.call('zscore', key, ARGV[i])
+		if score then
+			redis.call('zrem', key, ARGV[i])
+			table.insert(freed, tonumber(score))
+		end
+	end
+	table.sort(freed)
+	for i=1, m do
+		local score = freed[i]
+		if not score then
+			last = last + 1
+			score = last
+		end
+		redis.call('zadd', key, score, ARGV[n+1+i])
+	end
+	return
+`)
+
+// removeFilteredScoredScript removes the rules matching the pattern ARGV[1].
+var removeFilteredScoredScript = newWriteScript(1, `
+	local key = KEYS[1]
+	local pattern = ARGV[1]
+
+	local r = redis.call('zrange', key, 0, -1)
+	for i=1, #r do
+		if string.find(r[i], pattern) then
+			redis.call('zrem', key, r[i])
+		end
+	end
+	return
+`)
+
+// ZSetBackend is the StorageBackend of ZSetLayout, which stores the rules as
+// members of a sorted set, scored in the order they are added. It keeps their
+// order but not duplicates. The filtered operations match the rules by their
+// Pattern within a script, or decode them in the adapter without one.
+type ZSetBackend struct{}
+
+// LoadAll returns the rules of the sorted set in score order.
+func (b ZSetBackend) LoadAll(conn redis.Conn, key string) ([][]byte, error) {
+	values, err := redis.ByteSlices(conn.Do("ZRANGE", key, 0, -1))
+	return withoutTombstones(values), err
+}
+
+// LoadMatching returns the rules of the sorted set that match, decoding every
+// rule.
+func (b ZSetBackend) LoadMatching(conn redis.Conn, key string, match RuleMatch) ([][]byte, error) {
+	rules, err := b.LoadAll(conn, key)
+	if err != nil {
+		return nil, err
+	}
+	return matchRules(rules, match)
+}
+
+// Append adds the rules not stored yet after the highest score.
+func (b ZSetBackend) Append(conn redis.Conn, key string, rules [][]byte) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err := runScript(addScoredScript, conn, redis.Args{}.Add(key).AddFlat(rules)...)
+	return err
+}
+
+// Remove removes the rules from the sorted set.
+func (b ZSetBackend) Remove(conn redis.Conn, key string, rules [][]byte) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err := conn.Do("ZREM", redis.Args{}.Add(key).AddFlat(rules)...)
+	return err
+}
+
+// RemoveMatching removes the rules of the sorted set that match.
+func (b ZSetBackend) RemoveMatching(conn redis.Conn, key string, match RuleMatch) error {
+	if match.Pattern != "" {
+		_, err := runScript(removeFilteredScoredScript, conn, key, match.Pattern)
+		return err
+	}
+	matched, err := b.LoadMatching(conn, key, match)
+	if err != nil {
+		return err
+	}
+	return b.Remove(conn, key, matched)
+}
+
+// Replace replaces the stored rules of the sorted set, keeping their scores.
+func (b ZSetBackend) Replace(conn redis.Conn, key string, oldRules, newRules [][]byte) error {
+	if len(oldRules) == 0 {
+		return nil
+	}
+	_, err := runScript(updateScoredScript, conn, redis.Args{}.Add(key, len(oldRules)).AddFlat(oldRules).AddFlat(newRules)...)
+	return err
+}
+
+// ReplaceMatching replaces the rules of the sorted set that match, the new
+// rules taking their scores in order.
+func (b ZSetBackend) ReplaceMatching(conn redis.Conn, key string, match RuleMatch, newRules [][]byte) ([][]byte, error) {
+	matched, err := b.LoadMatching(conn, key, match)
+	if err != nil {
+		return nil, err
+	}
+	args := redis.Args{}.Add(key, len(matched)).AddFlat(matched).AddFlat(newRules)
+	if _, err = runScript(replaceScoredScript, conn, args...); err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// Clear deletes the sorted set.
+func (b ZSetBackend) Clear(conn redis.Conn, key string) error {
+	_, err := conn.Do("DEL", key)
+	return err
+}